@@ -0,0 +1,135 @@
+// Package adjust calculates the split and dividend
+// adjusted prices and dividends of a symbol.
+package adjust
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"szakszon.com/divyield"
+)
+
+// Dividends sets the split adjusted amounts of the dividends.
+// A split adjusts the dividends paid before its ex-date.
+func Dividends(
+	dividends []*divyield.Dividend,
+	splits []*divyield.Split,
+) {
+	f := newFactors(splitEvents(splits))
+
+	for _, d := range dividends {
+		factor := f.after(d.ExDate)
+		d.FactorAdj = round(factor)
+		d.AmountAdj = round(d.Amount * factor)
+	}
+}
+
+// Prices sets the adjusted closes of the prices.
+// The splits adjust both CloseAdjSplits and CloseAdj,
+// the cash dividends adjust CloseAdj only. A dividend
+// without a price on its ex-date is ignored.
+func Prices(
+	prices []*divyield.Price,
+	dividends []*divyield.Dividend,
+	splits []*divyield.Split,
+) {
+	closes := make(map[string]float64, len(prices))
+	for _, p := range prices {
+		closes[p.Date.Format(divyield.DateFormat)] = p.Close
+	}
+
+	events := splitEvents(splits)
+	fSplits := newFactors(splitEvents(splits))
+
+	for _, d := range dividends {
+		if !isCash(d) {
+			continue
+		}
+
+		close := closes[d.ExDate.Format(divyield.DateFormat)]
+		if close <= 0 {
+			continue
+		}
+		events = append(events, &event{
+			Date:   d.ExDate,
+			Factor: close / (close + d.Amount),
+		})
+	}
+	f := newFactors(events)
+
+	for _, p := range prices {
+		factor := f.after(p.Date)
+		factorSplits := fSplits.after(p.Date)
+
+		p.FactorAdj = round(factor)
+		p.CloseAdj = round(p.Close * factor)
+		p.FactorAdjSplits = round(factorSplits)
+		p.CloseAdjSplits = round(p.Close * factorSplits)
+	}
+}
+
+func isCash(d *divyield.Dividend) bool {
+	return d.PaymentType == "Cash" || d.PaymentType == "Cash&Stock"
+}
+
+// round rounds to 4 decimal places
+// like the numeric columns of the database.
+func round(v float64) float64 {
+	return math.Round(v*10000) / 10000
+}
+
+type event struct {
+	Date   time.Time
+	Factor float64
+}
+
+// factors multiplies the factors of the events
+// that happen after a date.
+type factors struct {
+	dates []time.Time
+
+	// products[i] is the product of the factors
+	// of the events from the i-th date on
+	products []float64
+}
+
+func newFactors(events []*event) *factors {
+	sort.SliceStable(events, func(i, j int) bool {
+		return events[i].Date.Before(events[j].Date)
+	})
+
+	f := &factors{
+		dates:    make([]time.Time, len(events)),
+		products: make([]float64, len(events)+1),
+	}
+	f.products[len(events)] = 1
+	for i := len(events) - 1; i >= 0; i-- {
+		f.dates[i] = events[i].Date
+		f.products[i] = f.products[i+1] * events[i].Factor
+	}
+	return f
+}
+
+// after returns the product of the factors
+// of the events after the date.
+func (f *factors) after(date time.Time) float64 {
+	i := sort.Search(len(f.dates), func(i int) bool {
+		return date.Before(f.dates[i])
+	})
+	return f.products[i]
+}
+
+func splitEvents(splits []*divyield.Split) []*event {
+	events := make([]*event, 0, len(splits))
+	for _, s := range splits {
+		if s.ToFactor == 0 || s.FromFactor == 0 {
+			continue
+		}
+		events = append(events, &event{
+			Date:   s.ExDate,
+			Factor: s.FromFactor / s.ToFactor,
+		})
+	}
+	return events
+}
@@ -0,0 +1,303 @@
+package adjust
+
+import (
+	"testing"
+	"time"
+
+	"szakszon.com/divyield"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(divyield.DateFormat, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func cash(exDate string, amount float64) *divyield.Dividend {
+	return &divyield.Dividend{
+		ExDate:      date(exDate),
+		Amount:      amount,
+		PaymentType: "Cash",
+	}
+}
+
+func split(exDate string, to, from float64) *divyield.Split {
+	return &divyield.Split{
+		ExDate:     date(exDate),
+		ToFactor:   to,
+		FromFactor: from,
+	}
+}
+
+func TestPrices(t *testing.T) {
+	type price struct {
+		date           string
+		close          float64
+		closeAdj       float64
+		closeAdjSplits float64
+	}
+
+	tests := []struct {
+		name      string
+		prices    []price
+		dividends []*divyield.Dividend
+		splits    []*divyield.Split
+	}{
+		{
+			name: "no events",
+			prices: []price{
+				{"2021-01-04", 100, 100, 100},
+				{"2021-01-05", 101, 101, 101},
+			},
+		},
+		{
+			name: "split only",
+			prices: []price{
+				{"2021-01-04", 100, 50, 50},
+				{"2021-01-05", 50, 50, 50},
+				{"2021-01-06", 51, 51, 51},
+			},
+			splits: []*divyield.Split{
+				split("2021-01-05", 2, 1),
+			},
+		},
+		{
+			name: "splits",
+			prices: []price{
+				{"2021-01-04", 120, 20, 20},
+				{"2021-01-05", 60, 20, 20},
+				{"2021-01-06", 20, 20, 20},
+			},
+			splits: []*divyield.Split{
+				split("2021-01-06", 3, 1),
+				split("2021-01-05", 2, 1),
+			},
+		},
+		{
+			name: "cash dividend",
+			prices: []price{
+				{"2021-01-04", 100, 99, 100},
+				{"2021-01-05", 99, 99, 99},
+			},
+			dividends: []*divyield.Dividend{
+				cash("2021-01-05", 1),
+			},
+		},
+		{
+			name: "cash and stock dividend",
+			prices: []price{
+				{"2021-01-04", 100, 98, 100},
+				{"2021-01-05", 98, 98, 98},
+			},
+			dividends: []*divyield.Dividend{
+				{
+					ExDate:      date("2021-01-05"),
+					Amount:      2,
+					PaymentType: "Cash&Stock",
+				},
+			},
+		},
+		{
+			name: "dividend without price on ex-date",
+			prices: []price{
+				{"2021-01-04", 100, 100, 100},
+				{"2021-01-06", 99, 99, 99},
+			},
+			dividends: []*divyield.Dividend{
+				cash("2021-01-05", 1),
+			},
+		},
+		{
+			name: "non-cash dividend",
+			prices: []price{
+				{"2021-01-04", 100, 100, 100},
+				{"2021-01-05", 99, 99, 99},
+			},
+			dividends: []*divyield.Dividend{
+				{
+					ExDate:      date("2021-01-05"),
+					Amount:      1,
+					PaymentType: "Stock",
+				},
+			},
+		},
+		{
+			name: "split and dividend on the same date",
+			prices: []price{
+				{"2021-01-04", 100, 49, 50},
+				{"2021-01-05", 49, 49, 49},
+			},
+			dividends: []*divyield.Dividend{
+				cash("2021-01-05", 1),
+			},
+			splits: []*divyield.Split{
+				split("2021-01-05", 2, 1),
+			},
+		},
+		{
+			name: "dividends on the same date",
+			prices: []price{
+				{"2021-01-04", 100, 97.99, 100},
+				{"2021-01-05", 98, 98, 98},
+			},
+			dividends: []*divyield.Dividend{
+				cash("2021-01-05", 1),
+				cash("2021-01-05", 1),
+			},
+		},
+		{
+			name: "invalid split",
+			prices: []price{
+				{"2021-01-04", 100, 100, 100},
+				{"2021-01-05", 50, 50, 50},
+			},
+			splits: []*divyield.Split{
+				split("2021-01-05", 0, 1),
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prices := make([]*divyield.Price, 0, len(tt.prices))
+			for _, p := range tt.prices {
+				prices = append(prices, &divyield.Price{
+					Date:  date(p.date),
+					Close: p.close,
+				})
+			}
+
+			Prices(prices, tt.dividends, tt.splits)
+
+			for i, p := range prices {
+				want := tt.prices[i]
+				if p.CloseAdj != want.closeAdj {
+					t.Errorf(
+						"%v: close adj: got %v, want %v",
+						want.date,
+						p.CloseAdj,
+						want.closeAdj,
+					)
+				}
+				if p.CloseAdjSplits != want.closeAdjSplits {
+					t.Errorf(
+						"%v: close adj splits: got %v, want %v",
+						want.date,
+						p.CloseAdjSplits,
+						want.closeAdjSplits,
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestDividends(t *testing.T) {
+	tests := []struct {
+		name      string
+		dividends []*divyield.Dividend
+		splits    []*divyield.Split
+		factors   []float64
+		amounts   []float64
+	}{
+		{
+			name: "no splits",
+			dividends: []*divyield.Dividend{
+				cash("2021-01-04", 1),
+			},
+			factors: []float64{1},
+			amounts: []float64{1},
+		},
+		{
+			name: "split",
+			dividends: []*divyield.Dividend{
+				cash("2021-01-04", 1),
+				cash("2021-04-05", 0.5),
+				cash("2021-07-05", 0.55),
+			},
+			splits: []*divyield.Split{
+				split("2021-04-05", 2, 1),
+			},
+			factors: []float64{0.5, 1, 1},
+			amounts: []float64{0.5, 0.5, 0.55},
+		},
+		{
+			name: "splits on the same date",
+			dividends: []*divyield.Dividend{
+				cash("2021-01-04", 1.2),
+			},
+			splits: []*divyield.Split{
+				split("2021-04-05", 2, 1),
+				split("2021-04-05", 3, 1),
+			},
+			factors: []float64{0.1667},
+			amounts: []float64{0.2},
+		},
+		{
+			name: "non-cash dividend",
+			dividends: []*divyield.Dividend{
+				{
+					ExDate:      date("2021-01-04"),
+					Amount:      1,
+					PaymentType: "Stock",
+				},
+			},
+			splits: []*divyield.Split{
+				split("2021-04-05", 2, 1),
+			},
+			factors: []float64{0.5},
+			amounts: []float64{0.5},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			Dividends(tt.dividends, tt.splits)
+
+			for i, d := range tt.dividends {
+				if d.FactorAdj != tt.factors[i] {
+					t.Errorf(
+						"%v: factor adj: got %v, want %v",
+						i,
+						d.FactorAdj,
+						tt.factors[i],
+					)
+				}
+				if d.AmountAdj != tt.amounts[i] {
+					t.Errorf(
+						"%v: amount adj: got %v, want %v",
+						i,
+						d.AmountAdj,
+						tt.amounts[i],
+					)
+				}
+			}
+		})
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		v    float64
+		want float64
+	}{
+		{0, 0},
+		{1, 1},
+		{1.23454, 1.2345},
+		{1.23455, 1.2346},
+		{1.0 / 3, 0.3333},
+		{2.0 / 3, 0.6667},
+		{0.00004, 0},
+		{0.00005, 0.0001},
+		{-1.23456, -1.2346},
+	}
+
+	for _, tt := range tests {
+		got := round(tt.v)
+		if got != tt.want {
+			t.Errorf("round(%v): got %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,454 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"szakszon.com/divyield"
+)
+
+const (
+	auditPriceGap        = "price gap"
+	auditPriceJump       = "unrecorded split?"
+	auditMissingExDate   = "no price on ex-date"
+	auditDuplicateExDate = "duplicate ex-date"
+	auditCurrency        = "currency mismatch"
+	auditStaleProfile    = "stale profile"
+)
+
+type auditIssue struct {
+	Symbol  string
+	Check   string
+	Date    time.Time
+	Details string
+}
+
+func (c *Command) audit(ctx context.Context) error {
+	symbols, err := c.resolveSymbols(ctx, c.args)
+	if err != nil {
+		return err
+	}
+	if len(symbols) == 0 {
+		return fmt.Errorf("Symbol not found")
+	}
+
+	proout, err := c.opts.db.Profiles(
+		ctx,
+		&divyield.DBProfilesInput{
+			Symbols: symbols,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	profiles := make(map[string]*divyield.Profile)
+	for _, p := range proout.Profiles {
+		profiles[p.Symbol] = p
+	}
+
+	issues := make([]*auditIssue, 0)
+	audited := 0
+
+LOOP:
+	for _, symbol := range symbols {
+		select {
+		case <-ctx.Done():
+			break LOOP
+		default:
+			// noop
+		}
+
+		a := &auditor{
+			symbol:    symbol,
+			gapDays:   c.opts.auditGapDays,
+			jump:      c.opts.auditJump,
+			staleDays: c.opts.auditStaleDays,
+		}
+		found, err := a.audit(ctx, c.opts.db, profiles[symbol])
+		if err != nil {
+			return fmt.Errorf("%v: %v", symbol, err)
+		}
+		issues = append(issues, found...)
+		audited++
+	}
+
+	c.writeAuditIssues(issues)
+	c.writeAuditFooter(audited, issues)
+	return nil
+}
+
+type auditor struct {
+	symbol    string
+	gapDays   int
+	jump      float64
+	staleDays int
+	issues    []*auditIssue
+}
+
+func (a *auditor) audit(
+	ctx context.Context,
+	db divyield.DB,
+	profile *divyield.Profile,
+) ([]*auditIssue, error) {
+	prices, err := db.Prices(
+		ctx,
+		a.symbol,
+		&divyield.PriceFilter{},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get prices: %v", err)
+	}
+
+	dividends, err := db.Dividends(
+		ctx,
+		a.symbol,
+		&divyield.DividendFilter{},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get dividends: %v", err)
+	}
+
+	splits, err := db.Splits(
+		ctx,
+		a.symbol,
+		&divyield.SplitFilter{},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get splits: %v", err)
+	}
+
+	// the database returns the latest first
+	sort.SliceStable(prices, func(i, j int) bool {
+		return prices[i].Date.Before(prices[j].Date)
+	})
+	sort.SliceStable(dividends, func(i, j int) bool {
+		return dividends[i].ExDate.Before(dividends[j].ExDate)
+	})
+
+	a.auditPriceGaps(prices)
+	a.auditPriceJumps(prices, splits)
+	a.auditExDatePrices(prices, dividends)
+	a.auditDuplicateExDates(dividends)
+	a.auditCurrencies(prices, dividends)
+	a.auditProfile(profile)
+	return a.issues, nil
+}
+
+func (a *auditor) report(
+	check string,
+	date time.Time,
+	format string,
+	v ...interface{},
+) {
+	a.issues = append(a.issues, &auditIssue{
+		Symbol:  a.symbol,
+		Check:   check,
+		Date:    date,
+		Details: fmt.Sprintf(format, v...),
+	})
+}
+
+func (a *auditor) auditPriceGaps(prices []*divyield.Price) {
+	if a.gapDays <= 0 {
+		return
+	}
+
+	for i := 1; i < len(prices); i++ {
+		p0 := prices[i-1]
+		p1 := prices[i]
+		n := tradingDaysBetween(p0.Date, p1.Date)
+		if n > a.gapDays {
+			a.report(
+				auditPriceGap,
+				p0.Date,
+				"%v trading days missing until %v",
+				n,
+				p1.Date.Format(divyield.DateFormat),
+			)
+		}
+	}
+}
+
+func (a *auditor) auditPriceJumps(
+	prices []*divyield.Price,
+	splits []*divyield.Split,
+) {
+	if a.jump <= 0 {
+		return
+	}
+
+	splitDates := make(map[string]struct{})
+	for _, s := range splits {
+		splitDates[s.ExDate.Format(divyield.DateFormat)] = struct{}{}
+	}
+
+	up := 1 + a.jump/100
+	down := 1 / up
+
+	for i := 1; i < len(prices); i++ {
+		p0 := prices[i-1]
+		p1 := prices[i]
+		if p0.Close <= 0 || p1.Close <= 0 {
+			continue
+		}
+
+		ratio := p1.Close / p0.Close
+		if down < ratio && ratio < up {
+			continue
+		}
+
+		d := p1.Date.Format(divyield.DateFormat)
+		if _, ok := splitDates[d]; ok {
+			continue
+		}
+
+		a.report(
+			auditPriceJump,
+			p1.Date,
+			"close %.2f -> %.2f (%+.2f%%)",
+			p0.Close,
+			p1.Close,
+			(ratio-1)*100,
+		)
+	}
+}
+
+func (a *auditor) auditExDatePrices(
+	prices []*divyield.Price,
+	dividends []*divyield.Dividend,
+) {
+	if len(prices) == 0 {
+		return
+	}
+
+	priceDates := make(map[string]struct{})
+	for _, p := range prices {
+		priceDates[p.Date.Format(divyield.DateFormat)] = struct{}{}
+	}
+
+	first := prices[0].Date
+	for _, d := range dividends {
+		// the price adjustment only uses cash dividends
+		if d.PaymentType != "Cash" && d.PaymentType != "Cash&Stock" {
+			continue
+		}
+		if d.ExDate.Before(first) {
+			continue
+		}
+
+		if _, ok := priceDates[d.ExDate.Format(divyield.DateFormat)]; !ok {
+			a.report(
+				auditMissingExDate,
+				d.ExDate,
+				"%v %v not used in price adjustment",
+				d.Amount,
+				d.Currency,
+			)
+		}
+	}
+}
+
+func (a *auditor) auditDuplicateExDates(
+	dividends []*divyield.Dividend,
+) {
+	byExDate := make(map[string][]*divyield.Dividend)
+	exDates := make([]string, 0)
+	for _, d := range dividends {
+		k := d.ExDate.Format(divyield.DateFormat)
+		if _, ok := byExDate[k]; !ok {
+			exDates = append(exDates, k)
+		}
+		byExDate[k] = append(byExDate[k], d)
+	}
+
+	for _, k := range exDates {
+		a0 := byExDate[k]
+		if len(a0) < 2 {
+			continue
+		}
+
+		details := make([]string, 0, len(a0))
+		for _, d := range a0 {
+			details = append(details, fmt.Sprintf(
+				"%v %v %v (freq %v)",
+				d.Amount,
+				d.Currency,
+				d.PaymentType,
+				d.Frequency,
+			))
+		}
+		a.report(
+			auditDuplicateExDate,
+			a0[0].ExDate,
+			"%s",
+			strings.Join(details, ", "),
+		)
+	}
+}
+
+func (a *auditor) auditCurrencies(
+	prices []*divyield.Price,
+	dividends []*divyield.Dividend,
+) {
+	if len(prices) == 0 {
+		return
+	}
+
+	// the latest price defines the currency of the symbol
+	currency := prices[len(prices)-1].Currency
+
+	for _, p := range prices {
+		if p.Currency != currency {
+			a.report(
+				auditCurrency,
+				p.Date,
+				"price in %v, latest price in %v",
+				p.Currency,
+				currency,
+			)
+		}
+	}
+
+	for _, d := range dividends {
+		if d.Currency != currency {
+			a.report(
+				auditCurrency,
+				d.ExDate,
+				"dividend %v %v, prices in %v",
+				d.Amount,
+				d.Currency,
+				currency,
+			)
+		}
+	}
+}
+
+func (a *auditor) auditProfile(profile *divyield.Profile) {
+	if profile == nil {
+		a.report(auditStaleProfile, time.Time{}, "profile not found")
+		return
+	}
+
+	if profile.Pulled.IsZero() {
+		a.report(auditStaleProfile, time.Time{}, "never pulled")
+		return
+	}
+
+	if a.staleDays <= 0 {
+		return
+	}
+
+	age := int(date(time.Now()).Sub(date(profile.Pulled)).Hours() / 24)
+	if age > a.staleDays {
+		a.report(
+			auditStaleProfile,
+			profile.Pulled,
+			"pulled %v days ago",
+			age,
+		)
+	}
+}
+
+// tradingDaysBetween returns the number of weekdays
+// strictly between the two dates.
+func tradingDaysBetween(from, to time.Time) int {
+	n := 0
+	for d := date(from).AddDate(0, 0, 1); d.Before(date(to)); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *Command) writeAuditIssues(issues []*auditIssue) {
+	out := &bytes.Buffer{}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	b := &bytes.Buffer{}
+	b.WriteString("Symbol")
+	b.WriteByte('\t')
+	b.WriteString("Check")
+	b.WriteByte('\t')
+	b.WriteString("Date")
+	b.WriteByte('\t')
+	b.WriteString("Details")
+	b.WriteByte('\t')
+	fmt.Fprintln(w, b.String())
+
+	for _, v := range issues {
+		d := ""
+		if !v.Date.IsZero() {
+			d = v.Date.Format(divyield.DateFormat)
+		}
+
+		b.Reset()
+		b.WriteString(v.Symbol)
+		b.WriteByte('\t')
+		b.WriteString(v.Check)
+		b.WriteByte('\t')
+		b.WriteString(d)
+		b.WriteByte('\t')
+		b.WriteString(v.Details)
+		b.WriteByte('\t')
+		fmt.Fprintln(w, b.String())
+	}
+
+	w.Flush()
+	c.writef("%s", out.String())
+}
+
+func (c *Command) writeAuditFooter(
+	audited int,
+	issues []*auditIssue,
+) {
+	out := &bytes.Buffer{}
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+
+	counts := make(map[string]int)
+	symbols := make(map[string]struct{})
+	for _, v := range issues {
+		counts[v.Check]++
+		symbols[v.Symbol] = struct{}{}
+	}
+
+	b := &bytes.Buffer{}
+	b.WriteString("Number of symbols:")
+	b.WriteByte('\t')
+	b.WriteString(strconv.Itoa(audited))
+	b.WriteByte('\t')
+	fmt.Fprintln(w, b.String())
+
+	b.Reset()
+	b.WriteString("Symbols with issues:")
+	b.WriteByte('\t')
+	b.WriteString(strconv.Itoa(len(symbols)))
+	b.WriteByte('\t')
+	fmt.Fprintln(w, b.String())
+
+	for _, check := range []string{
+		auditPriceGap,
+		auditPriceJump,
+		auditMissingExDate,
+		auditDuplicateExDate,
+		auditCurrency,
+		auditStaleProfile,
+	} {
+		b.Reset()
+		b.WriteString(strings.ToUpper(check[:1]) + check[1:] + ":")
+		b.WriteByte('\t')
+		b.WriteString(strconv.Itoa(counts[check]))
+		b.WriteByte('\t')
+		fmt.Fprintln(w, b.String())
+	}
+
+	w.Flush()
+	c.writef("%s", out.String())
+}
@@ -5,10 +5,9 @@ import (
 	"bytes"
 	"context"
 	"encoding/csv"
-	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
-	"io/ioutil"
 	"math"
 	"os"
 	"os/exec"
@@ -26,6 +25,8 @@ import (
 	"golang.org/x/text/language"
 	"golang.org/x/text/message"
 	"szakszon.com/divyield"
+	"szakszon.com/divyield/fundamentals"
+	"szakszon.com/divyield/intrinsic"
 )
 
 type Command struct {
@@ -61,12 +62,26 @@ func (c *Command) Execute(ctx context.Context) error {
 		return c.bargain(ctx)
 	case "pull-valuation":
 		return c.pullValuation(ctx)
+	case "statements":
+		return c.statements(ctx)
 	case "profile":
 		return c.profile(ctx)
 	case "symbols":
 		return c.symbols(ctx)
 	case "exchanges":
 		return c.exchanges(ctx)
+	case "audit":
+		return c.audit(ctx)
+	case "migrate":
+		return c.migrate(ctx)
+	case "consolidate":
+		return c.consolidate(ctx)
+	case "fx":
+		return c.fx(ctx)
+	case "cpi":
+		return c.cpi(ctx)
+	case "sp500":
+		return c.sp500(ctx)
 	default:
 		return fmt.Errorf("invalid command: %v", c.name)
 	}
@@ -83,6 +98,12 @@ func (c *Command) stats(ctx context.Context) error {
 		return fmt.Errorf("Symbol not found")
 	}
 
+	// the CPI and S&P 500 series tables
+	_, err = c.opts.db.Migrate(ctx, &divyield.DBMigrateInput{})
+	if err != nil {
+		return fmt.Errorf("migrate: %v", err)
+	}
+
 	infout, err := c.opts.inflationService.Fetch(
 		ctx,
 		&divyield.InflationFetchInput{},
@@ -93,7 +114,9 @@ func (c *Command) stats(ctx context.Context) error {
 
 	spout, err := c.opts.sp500Service.DividendYield(
 		ctx,
-		&divyield.SP500DividendYieldInput{},
+		&divyield.SP500DividendYieldInput{
+			Date: c.opts.asOf,
+		},
 	)
 	if err != nil {
 		return err
@@ -101,7 +124,9 @@ func (c *Command) stats(ctx context.Context) error {
 
 	sg := &statsGenerator{
 		db:                  c.opts.db,
+		workers:             c.opts.workers,
 		startDate:           c.opts.startDate,
+		asOf:                c.opts.asOf,
 		inflation:           &infout.Inflation,
 		sp500DividendYield:  &spout.SP500DividendYield,
 		divYieldFwdSP500Min: c.opts.divYieldFwdSP500Min,
@@ -110,10 +135,16 @@ func (c *Command) stats(ctx context.Context) error {
 		ggrROI:              c.opts.ggrROI,
 		ggrMin:              c.opts.ggrMin,
 		ggrMax:              c.opts.ggrMax,
+		intrinsic:           c.opts.intrinsic,
 		noCutDividend:       c.opts.noCutDividend,
 		noDecliningDGR:      c.opts.noDecliningDGR,
 		dgrAvgMin:           c.opts.dgrAvgMin,
 		dgrYearly:           c.opts.dgrYearly,
+		currencyService:     c.opts.currencyService,
+		reportCurrency:      c.opts.reportCurrency,
+		cpiService:          c.opts.cpiService,
+		cpiCountry:          c.opts.cpiCountry,
+		realDGRMin:          c.opts.realDGRMin,
 	}
 
 	stats, err := sg.Generate(ctx, symbols)
@@ -123,10 +154,13 @@ func (c *Command) stats(ctx context.Context) error {
 
 	if c.opts.chart {
 		cg := &chartGenerator{
-			db:        c.opts.db,
-			writer:    c.opts.writer,
-			dir:       c.opts.dir,
-			startDate: c.opts.startDate,
+			db:                  c.opts.db,
+			writer:              c.opts.writer,
+			dir:                 c.opts.dir,
+			startDate:           c.opts.startDate,
+			sp500Service:        c.opts.sp500Service,
+			divYieldFwdSP500Min: c.opts.divYieldFwdSP500Min,
+			divYieldFwdSP500Max: c.opts.divYieldFwdSP500Max,
 		}
 		err = cg.Generate(ctx, stats)
 		if err != nil {
@@ -151,10 +185,18 @@ func (c *Command) writeStats(s *divyield.Stats) {
 	b.WriteByte('\t')
 	b.WriteString("Dividend fwd")
 	b.WriteByte('\t')
+	if s.ReportCurrency != "" {
+		b.WriteString("Dividend fwd " + s.ReportCurrency)
+		b.WriteByte('\t')
+	}
 	b.WriteString("Yield fwd")
 	b.WriteByte('\t')
 	b.WriteString("GGR")
 	b.WriteByte('\t')
+	b.WriteString("DDM")
+	b.WriteByte('\t')
+	b.WriteString("DDM MoS")
+	b.WriteByte('\t')
 	b.WriteString("MR% date")
 	b.WriteByte('\t')
 	b.WriteString("MR%")
@@ -169,6 +211,22 @@ func (c *Command) writeStats(s *divyield.Stats) {
 	b.WriteByte('\t')
 	//b.WriteString("DGR-5y")
 	//b.WriteByte('\t')
+	if s.ReportCurrency != "" {
+		for _, n := range []int{1, 2, 3, 4} {
+			b.WriteString(fmt.Sprintf(
+				"DGR-%dy %v",
+				n,
+				s.ReportCurrency,
+			))
+			b.WriteByte('\t')
+		}
+	}
+	if s.InflationCountry != "" {
+		for _, n := range []int{1, 2, 3, 4} {
+			b.WriteString(fmt.Sprintf("RDGR-%dy", n))
+			b.WriteByte('\t')
+		}
+	}
 
 	fmt.Fprintln(w, b.String())
 
@@ -186,10 +244,18 @@ func (c *Command) writeStats(s *divyield.Stats) {
 		b.WriteByte('\t')
 		b.WriteString(fmt.Sprintf("%.2f", row.DivFwd))
 		b.WriteByte('\t')
+		if s.ReportCurrency != "" {
+			b.WriteString(fmt.Sprintf("%.2f", row.DivFwdHome))
+			b.WriteByte('\t')
+		}
 		b.WriteString(fmt.Sprintf("%.2f%%", row.DivYieldFwd))
 		b.WriteByte('\t')
 		b.WriteString(fmt.Sprintf("%.2f%%", row.GordonGrowthRate))
 		b.WriteByte('\t')
+		b.WriteString(fmt.Sprintf("%.2f", row.FairPriceDDM))
+		b.WriteByte('\t')
+		b.WriteString(fmt.Sprintf("%.2f%%", row.MarginOfSafetyDDM))
+		b.WriteByte('\t')
 		b.WriteString(fmt.Sprintf(
 			"%s",
 			row.DividendChangeMRDate.Format("2006-01-02")))
@@ -208,6 +274,18 @@ func (c *Command) writeStats(s *divyield.Stats) {
 		//b.WriteByte('\t')
 		//b.WriteString(fmt.Sprintf("%.2f%%", row.DGRs[5]))
 		b.WriteByte('\t')
+		if s.ReportCurrency != "" {
+			for _, n := range []int{1, 2, 3, 4} {
+				b.WriteString(fmt.Sprintf("%.2f%%", row.DGRsHome[n]))
+				b.WriteByte('\t')
+			}
+		}
+		if s.InflationCountry != "" {
+			for _, n := range []int{1, 2, 3, 4} {
+				b.WriteString(fmt.Sprintf("%.2f%%", row.RealDGRs[n]))
+				b.WriteByte('\t')
+			}
+		}
 
 		fmt.Fprintln(w, b.String())
 	}
@@ -233,6 +311,15 @@ func (c *Command) writeStatsFooter(
 	b.WriteByte('\t')
 	fmt.Fprintln(w, b.String())
 
+	if stats.ReportCurrency != "" {
+		b.Reset()
+		b.WriteString("Report currency:")
+		b.WriteByte('\t')
+		b.WriteString(stats.ReportCurrency)
+		b.WriteByte('\t')
+		fmt.Fprintln(w, b.String())
+	}
+
 	b.Reset()
 	b.WriteString("Start date:")
 	b.WriteByte('\t')
@@ -240,6 +327,15 @@ func (c *Command) writeStatsFooter(
 	b.WriteByte('\t')
 	fmt.Fprintln(w, b.String())
 
+	if !sg.asOf.IsZero() {
+		b.Reset()
+		b.WriteString("As of:")
+		b.WriteByte('\t')
+		b.WriteString(sg.asOf.Format(divyield.DateFormat))
+		b.WriteByte('\t')
+		fmt.Fprintln(w, b.String())
+	}
+
 	inf := fmt.Sprintf(
 		"%.2f%%, %v",
 		sg.inflation.Rate,
@@ -253,6 +349,26 @@ func (c *Command) writeStatsFooter(
 	b.WriteByte('\t')
 	fmt.Fprintln(w, b.String())
 
+	if stats.InflationCountry != "" {
+		ye := sg.now().Year() - 1
+		rates := make([]string, 0, 4)
+		for y := ye - 3; y <= ye; y++ {
+			if r, ok := sg.inflationRates[y]; ok {
+				rates = append(rates, fmt.Sprintf("%v: %.2f%%", y, r))
+			}
+		}
+
+		b.Reset()
+		b.WriteString(fmt.Sprintf(
+			"Inflation (%v CPI):",
+			stats.InflationCountry,
+		))
+		b.WriteByte('\t')
+		b.WriteString(strings.Join(rates, ", "))
+		b.WriteByte('\t')
+		fmt.Fprintln(w, b.String())
+	}
+
 	sp500DivYld := fmt.Sprintf(
 		"%.2f%%, %v",
 		sg.sp500DividendYield.Rate,
@@ -417,429 +533,186 @@ func (c *Command) writeCashFlow(
 	c.writef("%s", out.String())
 }
 
-const (
-	lastTTM = "TTM"
-	last1   = "2020"
-	last2   = "2019"
-	last3   = "2018"
-	last4   = "2017"
-	last5   = "2016"
-)
-
 func (c *Command) bargain(ctx context.Context) error {
-	baseDir := c.opts.dir
-	if baseDir == "" {
-		return fmt.Errorf("dir must be specified")
+	if len(c.opts.filters) == 1 && c.opts.filters[0] == "list" {
+		c.printFilters()
+		return nil
 	}
 
-	exist, err := exists(baseDir)
+	profile, err := fundamentals.LookupProfile(c.opts.profile)
 	if err != nil {
 		return err
 	}
-	if !exist {
-		return fmt.Errorf("dir not found: %v", baseDir)
+	metricNames := c.opts.metrics
+	if len(metricNames) == 0 {
+		metricNames = profile.Metrics
+	}
+	filterSpecs := c.opts.filters
+	if len(filterSpecs) == 0 {
+		filterSpecs = profile.Filters
+	}
+	filterSpecs = append([]string{}, filterSpecs...)
+	if c.opts.fscoreMin > 0 {
+		filterSpecs = append(
+			filterSpecs,
+			"fscore-min="+strconv.Itoa(c.opts.fscoreMin),
+		)
+	}
+	if c.opts.zscoreMin > 0 {
+		filterSpecs = append(
+			filterSpecs,
+			"zscore-min="+strconv.FormatFloat(c.opts.zscoreMin, 'f', -1, 64),
+		)
+	}
+	sortSpec := c.opts.sortKey
+	if sortSpec == "" {
+		sortSpec = profile.SortKey
 	}
 
-	urlsFile := c.args[0]
-	uf, err := os.Open(urlsFile)
+	metrics, err := fundamentals.LookupMetrics(metricNames)
+	if err != nil {
+		return err
+	}
+	if c.opts.periods < 1 {
+		return fmt.Errorf("invalid periods: %v", c.opts.periods)
+	}
+	criteria, err := fundamentals.ParseFilters(filterSpecs)
+	if err != nil {
+		return err
+	}
+	sortKey, err := fundamentals.ParseSortKey(sortSpec)
 	if err != nil {
 		return err
 	}
-	defer uf.Close()
 
-	financials := make([]*financials, 0)
-	scanner := bufio.NewScanner(uf)
-	for scanner.Scan() {
-		select {
-		case <-ctx.Done():
-			break
-		default:
-			// noop
+	// the filters may check more years than printed
+	n := c.opts.periods
+	for _, v := range criteria {
+		if v.FiscalYears() > n {
+			n = v.FiscalYears()
 		}
+	}
 
-		u := scanner.Text()
-		u = strings.TrimSpace(u)
-		if u == "" || strings.HasPrefix(u, "#") {
-			continue
-		}
+	symbols, err := readStatementSymbols(ctx, c.args[0])
+	if err != nil {
+		return err
+	}
 
-		_, symbol, exch := morningstarURLValuation(u)
+	_, err = c.opts.db.Migrate(ctx, &divyield.DBMigrateInput{})
+	if err != nil {
+		return fmt.Errorf("migrate: %v", err)
+	}
 
-		dir := filepath.Join(baseDir, exch, symbol)
+	out, err := c.opts.db.StatementItems(
+		ctx,
+		&divyield.DBStatementItemsInput{
+			Symbols: symbols,
+			AsOf:    c.opts.asOf,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("get statements: %v", err)
+	}
 
-		/*
-					missingFile := filepath.Join(dir, "missing")
-					exist, err = exists(missingFile)
-					if err != nil {
-						return err
-					}
-					if exist {
-			            fmt.Println("missing")
-						continue
-					}
-		*/
+	items := make(map[divyield.StatementSymbol][]*divyield.StatementItem)
+	for _, v := range out.Items {
+		k := divyield.StatementSymbol{
+			Exchange: v.Exchange,
+			Symbol:   v.Symbol,
+		}
+		items[k] = append(items[k], v)
+	}
 
-		fin, err := c.financials(ctx, dir)
+	companies := make([]*fundamentals.Company, 0)
+	skipped := make([]error, 0)
+SYMBOLS:
+	for _, v := range symbols {
+		if len(items[*v]) == 0 {
+			continue
+		}
+		fin := fundamentals.FromItems(items[*v])
+		err := fin.Validate(v.Exchange, v.Symbol)
 		if err != nil {
-			return fmt.Errorf("%v: %v", symbol, err)
+			skipped = append(skipped, err)
+			continue
 		}
-		if fin != nil {
-			fin.Exchange = exch
-			fin.Symbol = symbol
-
-			//fin.PToFCFTTM = fin.PriceToFreeCashFlow(lastTTM)
-
-			fin.FCFTTM = fin.CashFlow.FreeCashFlow(lastTTM)
-			fin.FCF1 = fin.CashFlow.FreeCashFlow(last1)
-			fin.FCF2 = fin.CashFlow.FreeCashFlow(last2)
-			fin.FCF3 = fin.CashFlow.FreeCashFlow(last3)
-			fin.FCF4 = fin.CashFlow.FreeCashFlow(last4)
-			fin.FCF5 = fin.CashFlow.FreeCashFlow(last5)
-
-			fin.FCFPSTTM = fin.FreeCashFlowPerShare(lastTTM)
-			fin.FCFPS1 = fin.FreeCashFlowPerShare(last1)
-			fin.FCFPS2 = fin.FreeCashFlowPerShare(last2)
-			fin.FCFPS3 = fin.FreeCashFlowPerShare(last3)
-			fin.FCFPS4 = fin.FreeCashFlowPerShare(last4)
-			fin.FCFPS5 = fin.FreeCashFlowPerShare(last5)
-
-
-			fin.ETTM = fin.IncomeStatement.NetIncome(lastTTM)
-			fin.E1 = fin.IncomeStatement.NetIncome(last1)
-			fin.E2 = fin.IncomeStatement.NetIncome(last2)
-			fin.E3 = fin.IncomeStatement.NetIncome(last3)
-			fin.E4 = fin.IncomeStatement.NetIncome(last4)
-			fin.E5 = fin.IncomeStatement.NetIncome(last5)
-
-			fin.EPSTTM = fin.IncomeStatement.EarningsPerShare(lastTTM)
-			fin.EPS1 = fin.IncomeStatement.EarningsPerShare(last1)
-			fin.EPS2 = fin.IncomeStatement.EarningsPerShare(last2)
-			fin.EPS3 = fin.IncomeStatement.EarningsPerShare(last3)
-			fin.EPS4 = fin.IncomeStatement.EarningsPerShare(last4)
-			fin.EPS5 = fin.IncomeStatement.EarningsPerShare(last5)
-
-			fin.RevTTM = fin.IncomeStatement.Revenue(lastTTM)
-			fin.Rev1 = fin.IncomeStatement.Revenue(last1)
-			fin.Rev2 = fin.IncomeStatement.Revenue(last2)
-			fin.Rev3 = fin.IncomeStatement.Revenue(last3)
-			fin.Rev4 = fin.IncomeStatement.Revenue(last4)
-			fin.Rev5 = fin.IncomeStatement.Revenue(last5)
-
-			fin.RPSTTM = fin.IncomeStatement.RevenuePerShare(lastTTM)
-			fin.RPS1 = fin.IncomeStatement.RevenuePerShare(last1)
-			fin.RPS2 = fin.IncomeStatement.RevenuePerShare(last2)
-			fin.RPS3 = fin.IncomeStatement.RevenuePerShare(last3)
-			fin.RPS4 = fin.IncomeStatement.RevenuePerShare(last4)
-			fin.RPS5 = fin.IncomeStatement.RevenuePerShare(last5)
-
-			fin.BV1 = fin.BalanceSheet.Equity(last1)
-			fin.BV2 = fin.BalanceSheet.Equity(last2)
-			fin.BV3 = fin.BalanceSheet.Equity(last3)
-			fin.BV4 = fin.BalanceSheet.Equity(last4)
-			fin.BV5 = fin.BalanceSheet.Equity(last5)
-
-			fin.BVPS1 = fin.BookValuePerShare(last1)
-			fin.BVPS2 = fin.BookValuePerShare(last2)
-			fin.BVPS3 = fin.BookValuePerShare(last3)
-			fin.BVPS4 = fin.BookValuePerShare(last4)
-			fin.BVPS5 = fin.BookValuePerShare(last5)
-
-			fin.DivPS1 = fin.DividendPerShare(last1)
-			fin.DivPS2 = fin.DividendPerShare(last2)
-			fin.DivPS3 = fin.DividendPerShare(last3)
-			fin.DivPS4 = fin.DividendPerShare(last4)
-			fin.DivPS5 = fin.DividendPerShare(last5)
-
-//          fin.ROIC1 = fin.ReturnOnInvestedCapital(last1)
-//			fin.ROIC2 = fin.ReturnOnInvestedCapital(last2)
-//			fin.ROIC3 = fin.ReturnOnInvestedCapital(last3)
-//			fin.ROIC4 = fin.ReturnOnInvestedCapital(last4)
-//			fin.ROIC5 = fin.ReturnOnInvestedCapital(last5)
-
-			fin.RONTA1 = fin.ReturnOnNetTangibleAssets(last1)
-			fin.RONTA2 = fin.ReturnOnNetTangibleAssets(last2)
-			fin.RONTA3 = fin.ReturnOnNetTangibleAssets(last3)
-			fin.RONTA4 = fin.ReturnOnNetTangibleAssets(last4)
-			fin.RONTA5 = fin.ReturnOnNetTangibleAssets(last5)
-
-			fin.ROE1 = fin.ReturnOnEquity(last1)
-			fin.ROE2 = fin.ReturnOnEquity(last2)
-			fin.ROE3 = fin.ReturnOnEquity(last3)
-			fin.ROE4 = fin.ReturnOnEquity(last4)
-			fin.ROE5 = fin.ReturnOnEquity(last5)
-
-			fin.DebtToFCF1 = fin.DebtToFreeCashFlow(last1)
-			fin.DebtToFCF2 = fin.DebtToFreeCashFlow(last2)
-			fin.DebtToFCF3 = fin.DebtToFreeCashFlow(last3)
-			fin.DebtToFCF4 = fin.DebtToFreeCashFlow(last4)
-			fin.DebtToFCF5 = fin.DebtToFreeCashFlow(last5)
-
-			fin.DebtToEqu1 = fin.DebtToEquity(last1)
-			fin.DebtToEqu2 = fin.DebtToEquity(last2)
-			fin.DebtToEqu3 = fin.DebtToEquity(last3)
-			fin.DebtToEqu4 = fin.DebtToEquity(last4)
-			fin.DebtToEqu5 = fin.DebtToEquity(last5)
-
-			fin.CorToRevTTM = fin.IncomeStatement.
-				CostOfRevenueToRevenue(lastTTM)
-			fin.CorToRev1 = fin.IncomeStatement.
-				CostOfRevenueToRevenue(last1)
-			fin.CorToRev2 = fin.IncomeStatement.
-				CostOfRevenueToRevenue(last2)
-			fin.CorToRev3 = fin.IncomeStatement.
-				CostOfRevenueToRevenue(last3)
-			fin.CorToRev4 = fin.IncomeStatement.
-				CostOfRevenueToRevenue(last4)
-			fin.CorToRev5 = fin.IncomeStatement.
-				CostOfRevenueToRevenue(last5)
-
-			fin.EffTTM = fin.IncomeStatement.OperatingEfficiency(lastTTM)
-			fin.Eff1 = fin.IncomeStatement.OperatingEfficiency(last1)
-			fin.Eff2 = fin.IncomeStatement.OperatingEfficiency(last2)
-			fin.Eff3 = fin.IncomeStatement.OperatingEfficiency(last3)
-			fin.Eff4 = fin.IncomeStatement.OperatingEfficiency(last4)
-			fin.Eff5 = fin.IncomeStatement.OperatingEfficiency(last5)
-
-            financials = append(financials, fin)
-		}
-	}
-
-	sort.SliceStable(
-		financials,
-		func(i, j int) bool {
-			v0 := financials[i].RONTA1
-            if math.IsNaN(v0) {
-                v0 = 0;
-            }
-			v1 := financials[j].RONTA1
-            if math.IsNaN(v1) {
-                v1 = 0;
-            }
-			return v0 > v1
-
-			/*
-				pe0 := financials[i].Valuation.
-					PriceToEarnings("Current")
-				pe1 := financials[j].Valuation.
-					PriceToEarnings("Current")
-				return pe0 < pe1
-			*/
-		},
-	)
 
-	c.printFinancials(financials)
+		company := fundamentals.NewCompany(
+			v.Exchange,
+			v.Symbol,
+			fin,
+			n,
+		)
+		company.Intrinsic = c.opts.intrinsic
+		for _, cr := range criteria {
+			if !cr.Match(company) {
+				continue SYMBOLS
+			}
+		}
+		companies = append(companies, company)
+	}
+
+	fundamentals.Sort(companies, sortKey)
+
+	c.printFinancials(companies, metrics)
+	if c.opts.scores {
+		c.printScores(companies)
+	}
+	if len(skipped) > 0 {
+		c.writef("Skipped: %v", len(skipped))
+		for _, err := range skipped {
+			c.writef("  %v", err)
+		}
+	}
 	return nil
 }
 
 func (c *Command) printFinancials(
-	financials []*financials,
+	companies []*fundamentals.Company,
+	metrics []*fundamentals.Metric,
 ) {
 	buf := &bytes.Buffer{}
 	w := tabwriter.NewWriter(
 		buf, 0, 0, 2, ' ', tabwriter.AlignRight)
 
 	p := message.NewPrinter(language.English)
+	years := fundamentals.CommonYears(companies)
+	periods := c.opts.periods
 
 	b := &bytes.Buffer{}
 	b.WriteString(fmt.Sprintf("%-10v", "Symbol"))
 	b.WriteByte('\t')
+	b.WriteString("FY end")
+	b.WriteByte('\t')
 
 	b.WriteString("MCap")
 	b.WriteByte('\t')
-
-//	b.WriteString("MyPE")
-//	b.WriteByte('\t')
 	b.WriteString("PE")
 	b.WriteByte('\t')
-//	b.WriteString("PB")
-//	b.WriteByte('\t')
-
-//	b.WriteString("ROIC1%")
-//	b.WriteByte('\t')
-//	b.WriteString("ROIC2%")
-//	b.WriteByte('\t')
-//	b.WriteString("ROIC3%")
-//	b.WriteByte('\t')
-//	b.WriteString("ROIC4%")
-//	b.WriteByte('\t')
-//	b.WriteString("ROIC5%")
-//	b.WriteByte('\t')
-
-//	b.WriteString("ROE1%")
-//	b.WriteByte('\t')
-//	b.WriteString("ROE2%")
-//	b.WriteByte('\t')
-//	b.WriteString("ROE3%")
-//	b.WriteByte('\t')
-//	b.WriteString("ROE4%")
-//	b.WriteByte('\t')
-//	b.WriteString("ROE5%")
-//	b.WriteByte('\t')
-
-	b.WriteString("RONTA1%")
-	b.WriteByte('\t')
-	b.WriteString("RONTA2%")
-	b.WriteByte('\t')
-	b.WriteString("RONTA3%")
-	b.WriteByte('\t')
-	b.WriteString("RONTA4%")
-	b.WriteByte('\t')
-	b.WriteString("RONTA5%")
-	b.WriteByte('\t')
-
-	b.WriteString("Debt/Equ1")
-	b.WriteByte('\t')
-	b.WriteString("Debt/Equ2")
-	b.WriteByte('\t')
-	b.WriteString("Debt/Equ3")
-	b.WriteByte('\t')
-	b.WriteString("Debt/Equ4")
-	b.WriteByte('\t')
-	b.WriteString("Debt/Equ5")
-	b.WriteByte('\t')
-
-	b.WriteString("Debt/FCF1")
-	b.WriteByte('\t')
-	b.WriteString("Debt/FCF2")
-	b.WriteByte('\t')
-	b.WriteString("Debt/FCF3")
-	b.WriteByte('\t')
-	b.WriteString("Debt/FCF4")
-	b.WriteByte('\t')
-	b.WriteString("Debt/FCF5")
-	b.WriteByte('\t')
-
-	b.WriteString("Cap rate%")
-	b.WriteByte('\t')
-	b.WriteString("FCFPS CAGR%")
-	b.WriteByte('\t')
-	b.WriteString("FCFPSTTM")
-	b.WriteByte('\t')
-	b.WriteString("FCFPS1")
-	b.WriteByte('\t')
-	b.WriteString("FCFPS2")
-	b.WriteByte('\t')
-	b.WriteString("FCFPS3")
-	b.WriteByte('\t')
-	b.WriteString("FCFPS4")
-	b.WriteByte('\t')
-	b.WriteString("FCFPS5")
-	b.WriteByte('\t')
-
-	b.WriteString("EPS CAGR%")
-	b.WriteByte('\t')
-	b.WriteString("EPSTTM")
-	b.WriteByte('\t')
-	b.WriteString("EPS1")
-	b.WriteByte('\t')
-	b.WriteString("EPS2")
-	b.WriteByte('\t')
-	b.WriteString("EPS3")
-	b.WriteByte('\t')
-	b.WriteString("EPS4")
-	b.WriteByte('\t')
-	b.WriteString("EPS5")
-	b.WriteByte('\t')
-
-	b.WriteString("BVDivPS CAGR%")
-	b.WriteByte('\t')
-	b.WriteString("BVPS1")
-	b.WriteByte('\t')
-	b.WriteString("BVPS2")
-	b.WriteByte('\t')
-	b.WriteString("BVPS3")
-	b.WriteByte('\t')
-	b.WriteString("BVPS4")
-	b.WriteByte('\t')
-	b.WriteString("BVPS5")
-	b.WriteByte('\t')
-
-	b.WriteString("DivPS1")
-	b.WriteByte('\t')
-	b.WriteString("DivPS2")
-	b.WriteByte('\t')
-	b.WriteString("DivPS3")
-	b.WriteByte('\t')
-	b.WriteString("DivPS4")
-	b.WriteByte('\t')
-	b.WriteString("DivPS5")
-	b.WriteByte('\t')
-
-	b.WriteString("RPS CAGR%")
-	b.WriteByte('\t')
-	b.WriteString("RPSTTM")
-	b.WriteByte('\t')
-	b.WriteString("RPS1")
-	b.WriteByte('\t')
-	b.WriteString("RPS2")
-	b.WriteByte('\t')
-	b.WriteString("RPS3")
-	b.WriteByte('\t')
-	b.WriteString("RPS4")
-	b.WriteByte('\t')
-	b.WriteString("RPS5")
-	b.WriteByte('\t')
-
-	b.WriteString("COR/RevTTM")
-	b.WriteByte('\t')
-	b.WriteString("COR/Rev1")
-	b.WriteByte('\t')
-	b.WriteString("COR/Rev2")
-	b.WriteByte('\t')
-	b.WriteString("COR/Rev3")
-	b.WriteByte('\t')
-	b.WriteString("COR/Rev4")
-	b.WriteByte('\t')
-	b.WriteString("COR/Rev5")
-	b.WriteByte('\t')
-
-	b.WriteString("EffTTM%")
-	b.WriteByte('\t')
-	b.WriteString("Eff1%")
-	b.WriteByte('\t')
-	b.WriteString("Eff2%")
-	b.WriteByte('\t')
-	b.WriteString("Eff3%")
-	b.WriteByte('\t')
-	b.WriteString("Eff4%")
-	b.WriteByte('\t')
-	b.WriteString("Eff5%")
-	b.WriteByte('\t')
-
-	fmt.Fprintln(w, b.String())
 
-	for _, v := range financials {
-        if !filterEPSGrowing(v) {
-		    //continue
-		}
-        if !filterFCFGrowing(v) {
-		    continue
-		}
-        if !filterRONTAPositive(v) {
-		    continue
-		}
-        if !filterRevGrowing(v) {
-		    //continue
-		}
-        if !filterBVGrowing(v) {
-		    //continue
-		}
-        if !(v.RONTA1>0) {
-		    //continue
+	for _, m := range metrics {
+		suffix := ""
+		if m.Percent {
+			suffix = "%"
 		}
-        if !filterDebtToEquity1Low(v) {
-		    //continue
-		}
-        if !filterRPSGrowing(v) {
-		    //continue
+		if m.Period == nil {
+			b.WriteString(m.Header + suffix)
+			b.WriteByte('\t')
+			continue
 		}
-        if !filterEPSGrowing(v) {
-		    //continue
+		if m.TTM {
+			b.WriteString(m.Header + "TTM" + suffix)
+			b.WriteByte('\t')
 		}
-        if !filterBVPSPositive(v) {
-		    //continue
+		for i := 1; i <= periods; i++ {
+			b.WriteString(fiscalYearColumn(years, m.Header, i, suffix))
+			b.WriteByte('\t')
 		}
+	}
+
+	fmt.Fprintln(w, b.String())
 
+	for _, v := range companies {
 		b.Reset()
 		b.WriteString(fmt.Sprintf(
 			"%-10v",
@@ -847,172 +720,118 @@ func (c *Command) printFinancials(
 		))
 		b.WriteByte('\t')
 
-		b.WriteString(p.Sprintf("%s", v.Realtime.MarketCapStr()))
+		b.WriteString(fiscalYearEndStr(v))
 		b.WriteByte('\t')
 
-//		b.WriteString(p.Sprintf("%.2f", v.PToFCFTTM))
-//		b.WriteByte('\t')
-//
-        pe := v.Valuation.PriceToEarnings("Current")
-		b.WriteString(p.Sprintf("%.2f", pe))
-		b.WriteByte('\t')
-//
-//		pb := v.Valuation.PriceToBook("Current")
-//		b.WriteString(p.Sprintf("%.2f", pb))
-//		b.WriteByte('\t')
-
-//		b.WriteString(p.Sprintf("%.2f", v.ROIC1))
-//		b.WriteByte('\t')
-//		b.WriteString(p.Sprintf("%.2f", v.ROIC2))
-//		b.WriteByte('\t')
-//		b.WriteString(p.Sprintf("%.2f", v.ROIC3))
-//		b.WriteByte('\t')
-//		b.WriteString(p.Sprintf("%.2f", v.ROIC4))
-//		b.WriteByte('\t')
-//		b.WriteString(p.Sprintf("%.2f", v.ROIC5))
-//		b.WriteByte('\t')
-
-//		b.WriteString(p.Sprintf("%.2f", v.ROE1))
-//		b.WriteByte('\t')
-//		b.WriteString(p.Sprintf("%.2f", v.ROE2))
-//		b.WriteByte('\t')
-//		b.WriteString(p.Sprintf("%.2f", v.ROE3))
-//		b.WriteByte('\t')
-//		b.WriteString(p.Sprintf("%.2f", v.ROE4))
-//		b.WriteByte('\t')
-//		b.WriteString(p.Sprintf("%.2f", v.ROE5))
-//		b.WriteByte('\t')
-
-		b.WriteString(p.Sprintf("%.2f", v.RONTA1))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.RONTA2))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.RONTA3))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.RONTA4))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.RONTA5))
+		b.WriteString(p.Sprintf("%s", v.Realtime.MarketCapStr()))
 		b.WriteByte('\t')
 
-		b.WriteString(p.Sprintf("%.2f", v.DebtToEqu1))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DebtToEqu2))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DebtToEqu3))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DebtToEqu4))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DebtToEqu5))
+		pe := v.Valuation.PriceToEarnings("Current")
+		b.WriteString(p.Sprintf("%.2f", pe))
 		b.WriteByte('\t')
 
+		for _, m := range metrics {
+			if m.Period == nil {
+				b.WriteString(p.Sprintf("%.2f", m.Company(v)))
+				b.WriteByte('\t')
+				continue
+			}
+			s := v.Series(m.Name)
+			if m.TTM {
+				b.WriteString(p.Sprintf("%.2f", s.TTM))
+				b.WriteByte('\t')
+			}
+			for i := 0; i < periods; i++ {
+				b.WriteString(p.Sprintf("%.2f", s.At(i)))
+				b.WriteByte('\t')
+			}
+		}
 
-		b.WriteString(p.Sprintf("%.2f", v.DebtToFCF1))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DebtToFCF2))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DebtToFCF3))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DebtToFCF4))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DebtToFCF5))
-		b.WriteByte('\t')
+		fmt.Fprintln(w, b.String())
+	}
 
-		b.WriteString(p.Sprintf("%.2f", v.CapRate()))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.FreeCashFlowPerShareCAGR()))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.FCFPSTTM))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.FCFPS1))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.FCFPS2))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.FCFPS3))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.FCFPS4))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.FCFPS5))
-		b.WriteByte('\t')
+	w.Flush()
+	c.writef("%s", buf.String())
+}
 
-		b.WriteString(p.Sprintf("%.2f", v.EarningsPerShareCAGR()))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.EPSTTM))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.EPS1))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.EPS2))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.EPS3))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.EPS4))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.EPS5))
-		b.WriteByte('\t')
+// printScores writes the signals of the Piotroski F-score
+// and the ratios of the Altman Z-score of the companies.
+func (c *Command) printScores(companies []*fundamentals.Company) {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(
+		buf, 0, 0, 2, ' ', tabwriter.AlignRight)
 
-		b.WriteString(p.Sprintf("%.2f", v.BookValueDividendPerShareCAGR()))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.BVPS1))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.BVPS2))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.BVPS3))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.BVPS4))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.BVPS5))
+	b := &bytes.Buffer{}
+	for _, h := range []string{
+		fmt.Sprintf("%-10v", "Symbol"),
+		"F",
+		"ROA",
+		"CFO",
+		"dROA",
+		"Accrual",
+		"dLever",
+		"dCurr",
+		"NoDilut",
+		"dGM",
+		"dATO",
+		"Z",
+		"WC/TA",
+		"RE/TA",
+		"EBIT/TA",
+		"MV/TL",
+		"S/TA",
+	} {
+		b.WriteString(h)
 		b.WriteByte('\t')
+	}
+	fmt.Fprintln(w, b.String())
 
-		b.WriteString(p.Sprintf("%.2f", v.DivPS1))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DivPS2))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DivPS3))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DivPS4))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.DivPS5))
+	for _, v := range companies {
+		b.Reset()
+		b.WriteString(fmt.Sprintf(
+			"%-10v",
+			v.Exchange+"/"+v.Symbol,
+		))
 		b.WriteByte('\t')
 
-		b.WriteString(p.Sprintf("%.2f", v.RevenuePerShareCAGR()))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.RPSTTM))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.RPS1))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.RPS2))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.RPS3))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.RPS4))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.RPS5))
-		b.WriteByte('\t')
+		fs := v.FScore()
+		if fs == nil {
+			for i := 0; i < 10; i++ {
+				b.WriteString("-")
+				b.WriteByte('\t')
+			}
+		} else {
+			b.WriteString(strconv.Itoa(fs.Score()))
+			b.WriteByte('\t')
+			for _, s := range fs.Signals() {
+				if s {
+					b.WriteString("1")
+				} else {
+					b.WriteString("0")
+				}
+				b.WriteByte('\t')
+			}
+		}
 
-		b.WriteString(p.Sprintf("%.2f", v.CorToRevTTM))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.CorToRev1))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.CorToRev2))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.CorToRev3))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.CorToRev4))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.CorToRev5))
-		b.WriteByte('\t')
-
-		b.WriteString(p.Sprintf("%.2f", v.EffTTM))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.Eff1))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.Eff2))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.Eff3))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.Eff4))
-		b.WriteByte('\t')
-		b.WriteString(p.Sprintf("%.2f", v.Eff5))
-		b.WriteByte('\t')
+		zs := v.ZScore()
+		if zs == nil {
+			for i := 0; i < 6; i++ {
+				b.WriteString("-")
+				b.WriteByte('\t')
+			}
+		} else {
+			for _, r := range []float64{
+				zs.Score(),
+				zs.WorkingCapital,
+				zs.RetainedEarnings,
+				zs.EBIT,
+				zs.MarketValue,
+				zs.Sales,
+			} {
+				b.WriteString(fmt.Sprintf("%.2f", r))
+				b.WriteByte('\t')
+			}
+		}
 
 		fmt.Fprintln(w, b.String())
 	}
@@ -1021,977 +840,36 @@ func (c *Command) printFinancials(
 	c.writef("%s", buf.String())
 }
 
-func filterRONTAPositive(v *financials) bool {
-	return v.RONTA1 > 0 &&
-		v.RONTA2 > 0 &&
-		v.RONTA3 > 0
-}
-
-func filterRPSGrowing(v *financials) bool {
-	return v.RPSTTM > v.RPS1 &&
-		v.RPS1 > v.RPS2 &&
-		v.RPS2 > v.RPS3 &&
-		v.RPS3 > 0
-}
-
-func filterRevGrowing(v *financials) bool {
-	return v.RevTTM > v.Rev3 &&
-		v.Rev1 > 0 &&
-		v.Rev2 > 0 &&
-		v.Rev3 > 0
-}
-
-func filterEGrowing(v *financials) bool {
-	return v.ETTM > v.E1 &&
-		v.E1 > v.E2 &&
-		v.E2 > v.E3 &&
-		v.E3 > 0
-}
-
-func filterEPSGrowing(v *financials) bool {
-	return v.EPSTTM > v.EPS1 &&
-		v.EPS1 > v.EPS2 &&
-		v.EPS2 > v.EPS3 &&
-		v.EPS3 > 0
-}
-
-func filterFCFGrowing(v *financials) bool {
-	return v.FCFTTM > 0 &&
-		v.FCF1 > 0 &&
-		v.FCF2 > 0 &&
-		v.FCF3 > 0
-}
-
-func filterFCFPSGrowing(v *financials) bool {
-	return v.FCFPSTTM > v.FCFPS3 &&
-        v.FCFPSTTM > 0 &&
-		v.FCFPS1 > 0 &&
-		v.FCFPS2 > 0 &&
-		v.FCFPS3 > 0
-}
-
-func filterBVGrowing(v *financials) bool {
-	return v.BV1 > v.BV3 &&
-		v.BV2 > 0 &&
-		v.BV3 > 0
-}
-
-func filterBVPSPositive(v *financials) bool {
-	return v.BVPS1 > 0 &&
-		v.BVPS2 > 0 &&
-		v.BVPS3 > 0
-}
-
-func filterDebtToEquity1Low(v *financials) bool {
-	return v.DebtToEqu1 <= 0.33
-}
-
-func filterCapRate10(v *financials) bool {
-	return v.CapRate() >= 10
-}
-
-func (c *Command) financials(
-	ctx context.Context,
-	dir string,
-) (*financials, error) {
-	file := filepath.Join(dir, "is.json")
-	exist, err := exists(file)
-	if err != nil {
-		return nil, err
-	}
-	if !exist {
-		return nil, nil
-	}
-	var incomeStatement statement
-	err = c.decodeJSON(file, &incomeStatement)
-	if err != nil {
-		return nil, err
-	}
-
-	file = filepath.Join(dir, "bs.json")
-	exist, err = exists(file)
-	if err != nil {
-		return nil, err
-	}
-	if !exist {
-		return nil, nil
-	}
-	var balanceSheet statement
-	err = c.decodeJSON(file, &balanceSheet)
-	if err != nil {
-		return nil, err
-	}
-
-	file = filepath.Join(dir, "cf.json")
-	exist, err = exists(file)
-	if err != nil {
-		return nil, err
-	}
-	if !exist {
-		return nil, nil
-	}
-	var cashFlow statement
-	err = c.decodeJSON(file, &cashFlow)
-	if err != nil {
-		return nil, err
-	}
-
-	file = filepath.Join(dir, "valuation.json")
-	exist, err = exists(file)
-	if err != nil {
-		return nil, err
-	}
-	if !exist {
-		return nil, nil
-	}
-	var valuation valuation
-	err = c.decodeJSON(file, &valuation)
-	if err != nil {
-		return nil, err
-	}
-
-	file = filepath.Join(dir, "rt.json")
-	exist, err = exists(file)
-	if err != nil {
-		return nil, err
-	}
-	if !exist {
-		return nil, nil
-	}
-	var realtime realtime
-	err = c.decodeJSON(file, &realtime)
-	if err != nil {
-		return nil, err
-	}
-
-	financials := &financials{
-		IncomeStatement: &incomeStatement,
-		BalanceSheet:    &balanceSheet,
-		CashFlow:        &cashFlow,
-		Valuation:       &valuation,
-		Realtime:        &realtime,
-	}
-
-	return financials, nil
-}
-
-func (c *Command) decodeJSON(
-	file string,
-	statement interface{},
-) error {
-	f, err := os.Open(file)
-	if err != nil {
-		return err
-	}
-	defer f.Close()
-	dec := json.NewDecoder(f)
-	err = dec.Decode(statement)
-	if err != nil && err != io.EOF {
-		return err
-	}
-	return nil
-}
-
-type financials struct {
-	Exchange        string
-	Symbol          string
-	IncomeStatement *statement
-	BalanceSheet    *statement
-	CashFlow        *statement
-	Valuation       *valuation
-	Realtime        *realtime
-
-    PToFCFTTM float64
-
-	NetCashToMCap float64
-
-    // Free cash flow per share
-	FCFPSTTM float64
-	FCFPS1 float64
-	FCFPS2 float64
-	FCFPS3 float64
-	FCFPS4 float64
-	FCFPS5 float64
-
-	FCFTTM float64
-	FCF1 float64
-	FCF2 float64
-	FCF3 float64
-	FCF4 float64
-	FCF5 float64
-
-    ETTM float64
-    E1 float64
-	E2 float64
-	E3 float64
-	E4 float64
-	E5 float64
-
-    EPSTTM float64
-    EPS1 float64
-	EPS2 float64
-	EPS3 float64
-	EPS4 float64
-	EPS5 float64
-
-	BV1 float64
-	BV2 float64
-	BV3 float64
-	BV4 float64
-	BV5 float64
-
-    // Book value (equity) per share
-	BVPS1 float64
-	BVPS2 float64
-	BVPS3 float64
-	BVPS4 float64
-	BVPS5 float64
-
-    // Dividend per share
-    DivPS1 float64
-	DivPS2 float64
-	DivPS3 float64
-	DivPS4 float64
-	DivPS5 float64
-
-    // Operating cash flow per share
-	OCFPS1 float64
-	OCFPS2 float64
-	OCFPS3 float64
-	OCFPS4 float64
-	OCFPS5 float64
-
-    ROIC1 float64
-	ROIC2 float64
-	ROIC3 float64
-	ROIC4 float64
-	ROIC5 float64
-
-	ROE1 float64
-	ROE2 float64
-	ROE3 float64
-	ROE4 float64
-	ROE5 float64
-
-	RONTA1 float64
-	RONTA2 float64
-	RONTA3 float64
-	RONTA4 float64
-	RONTA5 float64
-
-	DebtToFCF1 float64
-	DebtToFCF2 float64
-	DebtToFCF3 float64
-	DebtToFCF4 float64
-	DebtToFCF5 float64
-
-	DebtToEqu1 float64
-	DebtToEqu2 float64
-	DebtToEqu3 float64
-	DebtToEqu4 float64
-	DebtToEqu5 float64
-
-    // Sales per share
-    RPSTTM float64
-    RPS1 float64
-	RPS2 float64
-	RPS3 float64
-	RPS4 float64
-	RPS5 float64
-
-    RevTTM float64
-    Rev1 float64
-	Rev2 float64
-	Rev3 float64
-	Rev4 float64
-	Rev5 float64
-
-    CorToRevTTM float64
-    CorToRev1 float64
-	CorToRev2 float64
-	CorToRev3 float64
-	CorToRev4 float64
-	CorToRev5 float64
-
-    EffTTM float64
-    Eff1 float64
-	Eff2 float64
-	Eff3 float64
-	Eff4 float64
-	Eff5 float64
-
-}
-
-func (f *financials) ReturnOnInvestedCapital(
-	period string,
-) float64 {
-	//ear := f.CashFlow.FreeCashFlow(period)
-	ear := f.IncomeStatement.OperatingIncome(period)
-	if ear <= 0 {
-		return math.NaN()
-	}
-	cap := f.BalanceSheet.InvestedCapital(period)
-	if cap <= 0 {
-		return math.NaN()
-	}
-	return (ear / cap) * 100
-}
-
-func (f *financials) ReturnOnEquity(
-	period string,
-) float64 {
-	//ear := f.CashFlow.FreeCashFlow(period)
-	ear := f.IncomeStatement.OperatingIncome(period)
-	if ear <= 0 {
-		return math.NaN()
-	}
-	equ := f.BalanceSheet.Equity(period)
-    //fmt.Println(period, "equ", equ)
-	if equ <= 0 {
-		return math.NaN()
-	}
-	return (ear / equ) * 100
-}
-
-func (f *financials) ReturnOnNetTangibleAssets(
-	period string,
-) float64 {
-	ear := f.IncomeStatement.OperatingIncome(period)
-//	if ear <= 0 {
-//		return math.NaN()
-//	}
-	nta := f.BalanceSheet.NetTangibleAssets(period)
-    //fmt.Println(period, nta)
-	return (ear / nta) * 100
-}
-
-func (f *financials) DebtToFreeCashFlow(
-	period string,
-) float64 {
-	debt := f.BalanceSheet.Debt(period)
-	fcf := f.CashFlow.FreeCashFlow(period)
-	if fcf <= 0 {
-		return math.NaN()
-	}
-	return debt / fcf
-}
-
-func (f *financials) FreeCashFlowPerShareCAGR() float64 {
-    n := 3
-	to := f.FCFPSTTM
-    from := f.FCFPS3
-    if to <= 0 || from <= 0 {
-		return math.NaN()
-	}
-    return (math.Pow(to / from, float64(1) / float64(n)) - 1) * 100
-}
-
-func (f *financials) EarningsPerShareCAGR() float64 {
-    n := 3
-	to := f.EPSTTM
-    from := f.EPS3
-    if to <= 0 || from <= 0 {
-		return math.NaN()
-	}
-    return (math.Pow(to / from, float64(1) / float64(n)) - 1) * 100
-}
-
-func (f *financials) RevenuePerShareCAGR() float64 {
-    n := 3
-	to := f.RPSTTM
-    from := f.RPS3
-    if to <= 0 || from <= 0 {
-		return math.NaN()
-	}
-    return (math.Pow(to / from, float64(1) / float64(n)) - 1) * 100
-}
-
-func (f *financials) CapRate() float64 {
-    return (f.FCFPSTTM / f.Realtime.LastPrice) * 100
-}
-
-func (f *financials) FreeCashFlowPerShare(
-	period string,
-) float64 {
-	sha := f.IncomeStatement.SharesOutstanding(period)
-	fcf := f.CashFlow.FreeCashFlow(period)
-	if sha <= 0 {
-		return math.NaN()
-	}
-	return fcf / sha
-}
-
-func (f *financials) BookValueDividendPerShareCAGR() float64 {
-    n := 2
-	to := f.BVPS1 + math.Abs(f.DivPS1)
-    from := f.BVPS3 + math.Abs(f.DivPS3)
-	if to <= 0 || from <= 0 {
-		return math.NaN()
-	}
-    return (math.Pow(to / from, float64(1) / float64(n)) - 1) * 100
-}
-
-func (f *financials) BookValuePerShare(
-	period string,
-) float64 {
-	sha := f.IncomeStatement.SharesOutstanding(period)
-	equ := f.BalanceSheet.Equity(period)
-	if sha <= 0 {
-		return math.NaN()
-	}
-	return equ / sha
-}
-
-func (f *financials) DividendPerShare(
-	period string,
-) float64 {
-	sha := f.IncomeStatement.SharesOutstanding(period)
-	div := f.CashFlow.DividendPaid(period)
-	if sha <= 0 {
-		return math.NaN()
-	}
-	return div / sha
-}
-
-type statement struct {
-	ColumnDefs []string             `json:"columnDefs"`
-	Rows       []*statementSubLevel `json:"rows"`
-	Footer     *statementFooter     `json:"footer"`
-}
-
-func (s *statement) OrderOfMagnitude() float64 {
-	switch s.Footer.OrderOfMagnitude {
-	case "Billion":
-		return 1000000000
-	case "Million":
-		return 1000000
-	case "Thousand":
-		return 1000
-	default:
-		panic("unexpected order of magnitude: " + s.Footer.OrderOfMagnitude)
-	}
-}
-
-type statementSubLevel struct {
-	Label     string               `json:"label"`
-	SubLevels []*statementSubLevel `json:"subLevel"`
-	Datum     []interface{}        `json:"datum"`
-}
-
-type statementFooter struct {
-	Currency          string `json:"currency"`
-	CurrencySymbol    string `json:"currencySymbol"`
-	OrderOfMagnitude  string `json:"orderOfMagnitude"`
-	FiscalYearEndDate string `json:"fiscalYearEndDate"`
-}
-
-func (s *statement) SharesOutstanding(
-	period string,
-) float64 {
-	return s.value(
-		s.periodIndex(period),
-		"Diluted Weighted Average Shares Outstanding",
-		s.Rows,
-	)
-}
-
-func (s *statement) Revenue(
-	period string,
-) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-
-    return s.value(
-		s.periodIndex(period),
-		"Total Revenue",
-		s.Rows,
-	)
-}
-
-func (s *statement) CostOfRevenue(
-	period string,
-) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-	return s.value(
-		s.periodIndex(period),
-		"Cost of Revenue",
-		s.Rows,
-	)
-}
-
-func (s *statement) CostOfRevenueToRevenue(
-	period string,
-) float64 {
-    cor := s.CostOfRevenue(period)
-    rev := s.Revenue(period)
-    if rev <= 0 {
-        return math.NaN()
-    }
-    return math.Abs(cor) / rev
-}
-
-func (s *statement) GrossIncome(
-	period string,
-) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-    // banks, no cost of goods sold
-	netIntInc := s.NetInterestIncome(period)
-	if netIntInc > 0 {
-		return s.Revenue(period)
-	}
-
-	// other companies
-	return s.value(
-		s.periodIndex(period),
-		"Gross Profit",
-		s.Rows,
-	)
-}
-
-func (s *statement) OperatingIncome(
-	period string,
-) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-	// non bank companies
-    inc := s.value(
-		s.periodIndex(period),
-		"Total Operating Profit/Loss",
-		s.Rows,
-	)
-
-    if inc != 0 {
-        return inc
-    }
-
-	// banks
-	nonIntExp := s.NonInterestExpenses(period)
-	if nonIntExp < 0 {
-		gro := s.GrossIncome(period)
-		return gro + nonIntExp
-	}
-
-    return 0
-}
-
-func (s *statement) NetInterestIncome(
-	period string,
-) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-	return s.value(
-		s.periodIndex(period),
-		"Net Interest Income",
-		s.Rows,
-	)
-}
-
-func (s *statement) NonInterestIncome(
-	period string,
-) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-	return s.value(
-		s.periodIndex(period),
-		"Non-Interest Income",
-		s.Rows,
-	)
-}
-
-func (s *statement) NonInterestExpenses(
-	period string,
-) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-	return s.value(
-		s.periodIndex(period),
-		"Non-Interest Expenses",
-		s.Rows,
-	)
-}
-
-func (s *statement) OperatingEfficiency(
-	period string,
-) float64 {
-	exp := s.NonInterestExpenses(period)
-	netInc := s.NetInterestIncome(period)
-	nonInc := s.NonInterestIncome(period)
-	//fmt.Println(period, netInc, nonInc, exp)
-	return (math.Abs(exp) / (netInc + nonInc)) * 100
-}
-
-func (s *statement) NetIncome(period string) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-	return s.value(
-		s.periodIndex(period),
-		"Net Income Available to Common Stockholders",
-		s.Rows,
-	)
-}
-
-func (s *statement) DilutedSharesOutstanding(period string) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-    return s.value(
-		s.periodIndex(period),
-        "Diluted Weighted Average Shares Outstanding",
-		s.Rows,
-	)
-}
-
-func (s *statement) EarningsPerShare(period string) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-    return s.value(
-		s.periodIndex(period),
-        "Diluted EPS",
-		s.Rows,
-	)
-}
-
-func (s *statement) RevenuePerShare(period string) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-    sha := s.DilutedSharesOutstanding(period)
-    rev := s.Revenue(period)
-    if sha <= 0 {
-        return math.NaN()
-    }
-    return rev / sha
-}
-
-func (s *statement) InvestedCapital(
-	period string,
-) float64 {
-	equ := s.Equity(period)
-	debt := s.Debt(period)
-	return equ + debt
-}
-
-func (s *statement) Equity(
-	period string,
-) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-
-    return s.value(
-		s.periodIndex(period),
-		"Total Equity",
-		s.Rows,
-	)
-}
-
-func (s *statement) NetTangibleAssets(
-	period string,
-) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-
-    equ := s.Equity(period)
-
-    intan := s.value(
-		s.periodIndex(period),
-		"Net Intangible Assets",
-		s.Rows,
-	)
-
-    return equ - intan
-}
-
-func (s *statement) Debt(
-	period string,
-) float64 {
-    if len(s.Rows) == 0 {
-        return 0
-    }
-	curr := s.value(
-		s.periodIndex(period),
-		"Current Debt and Capital Lease Obligation",
-		s.Rows,
-	)
-
-	long := s.value(
-		s.periodIndex(period),
-		"Long Term Debt and Capital Lease Obligation",
-		s.Rows,
-	)
-
-	//fmt.Printf("Debt %v %f %f\n", period, curr, long)
-	// non banks
-	if curr > 0 || long > 0 {
-		return curr + long
-	}
-
-	// banks
-	return s.value(
-		s.periodIndex(period),
-		"Debt and Capital Lease Obligations",
-		s.Rows,
-	)
-}
-
-func (f *financials) DebtToEquity(
-	period string,
-) float64 {
-	debt := f.BalanceSheet.Debt(period)
-	equ  := f.BalanceSheet.Equity(period)
-    if equ <= 0 {
-        return math.NaN()
-    }
-	return debt / equ
-}
-
-func (s *statement) LiabilitiesNoDeposits(
-	period string,
-) float64 {
-	totLia := s.Liabilities(period)
-	totDep := s.Deposits(period)
-	return totLia - totDep
-}
-
-func (s *statement) Liabilities(
-	period string,
-) float64 {
-	return s.value(
-		s.periodIndex(period),
-		"Total Liabilities",
-		s.Rows,
-	)
-}
-
-func (s *statement) Deposits(
-	period string,
-) float64 {
-	return s.value(
-		s.periodIndex(period),
-		"Total Deposits",
-		s.Rows,
-	)
-}
-
-func (s *statement) CashAndCashEquivalents(
-	period string,
-) float64 {
-	return s.value(
-		s.periodIndex(period),
-		"Cash and Cash Equivalents",
-		s.Rows,
-	)
-}
-
-func (s *statement) OperatingCashFlow(
-	period string,
-) float64 {
-	if len(s.Rows) == 0 {
-		return 0
-	}
-
-	dir := s.value(
-		s.periodIndex(period),
-		"Net Cash Flow from Continuing Operating Activities, Direct",
-		s.Rows,
-	)
-
-	ind := s.value(
-		s.periodIndex(period),
-		"Net Cash Flow from Continuing Operating Activities, Indirect",
-		s.Rows,
-	)
-
-	return dir + ind
-}
-
-func (s *statement) FreeCashFlow(
-	period string,
-) float64 {
-	if len(s.Rows) == 0 {
-		return 0
-	}
-
-	opCash := s.OperatingCashFlow(period)
-
-	pppe := s.value(
-		s.periodIndex(period),
-		"Purchase of Property, Plant and Equipment",
-		s.Rows,
-	)
-
-	capEx := s.value(
-		s.periodIndex(period),
-		"Capital Expenditure, Reported",
-		s.Rows,
-	)
-
-	fcf := opCash + capEx
-	if pppe < 0 {
-		fcf += pppe
-	}
-	return fcf
-}
-
-func (s *statement) DividendPaid(
-	period string,
-) float64 {
-	if len(s.Rows) == 0 {
-		return 0
-	}
-
-	return s.value(
-		s.periodIndex(period),
-        "Common Stock Dividends Paid",
-		s.Rows,
-	)
-}
-
-func (s *statement) periodIndex(period string) int {
-	for i, v := range s.ColumnDefs {
-		if v == period {
-			return i
-		}
-	}
-	return -1
-}
-
-func (s *statement) value(
-	periodIndex int,
-	label string,
-	rows []*statementSubLevel,
-) float64 {
-	if periodIndex == -1 {
-		return 0
-	}
-
-	levels := make([]*statementSubLevel, 0)
-	levels = append(levels, rows...)
-
-	for len(levels) > 0 {
-		next := levels[0]
-		levels = levels[1:]
-
-		if next.Label == label {
-			v := next.Datum[periodIndex]
-			num, _ := v.(float64)
-            if label == "Diluted EPS" {
-                return num
-            } else {
-			    return num * s.OrderOfMagnitude()
-            }
-		}
-
-		levels = append(levels, next.SubLevels...)
-	}
-	return 0
-}
-
-type realtime struct {
-	MarketCap float64 `json:"marketCap"`
-    LastPrice float64 `json:"lastPrice"`
-
-}
-
-const trillion = float64(1000000000000);
-const billion = float64(1000000000);
-const million = float64(1000000);
-const thousand = float64(1000);
-
-func (r *realtime) MarketCapStr() string {
-    if r.MarketCap > trillion {
-        v := r.MarketCap / trillion
-        return strconv.FormatFloat(v, 'f', 3, 64) + "tr"
-    }
-    if r.MarketCap > billion {
-        v := r.MarketCap / billion
-        return strconv.FormatFloat(v, 'f', 3, 64) + "b"
-    }
-
-    if r.MarketCap > million {
-        v := r.MarketCap / million
-        return strconv.FormatFloat(v, 'f', 3, 64) + "m"
-    }
-
-    v := r.MarketCap / thousand
-    return strconv.FormatFloat(v, 'f', 3, 64) + "th"
-}
-
-type valuation struct {
-	Collapsed *valuationCollapsed `json:"Collapsed"`
-}
-
-type valuationCollapsed struct {
-	ColumnDefs []string        `json:"columnDefs"`
-	Rows       []*valuationRow `json:"rows"`
-}
-
-type valuationRow struct {
-	Label string        `json:"label"`
-	Datum []interface{} `json:"datum"`
-}
-
-func (s *valuation) periodIndex(period string) int {
-	for i, v := range s.Collapsed.ColumnDefs {
-		if v == period {
-			return i - 1
-		}
-	}
-	return -1
-}
+// printFilters writes the filters and the sort keys of bargain.
+func (c *Command) printFilters() {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', 0)
 
-func (s *valuation) value(
-	periodIndex int,
-	label string,
-) float64 {
-	if periodIndex == -1 {
-		return 0
-	}
+	b := &bytes.Buffer{}
+	b.WriteString("Filter")
+	b.WriteByte('\t')
+	b.WriteString("n")
+	b.WriteByte('\t')
+	b.WriteString("Description")
+	b.WriteByte('\t')
+	fmt.Fprintln(w, b.String())
 
-	for _, row := range s.Collapsed.Rows {
-		if row.Label == label {
-			d := row.Datum[periodIndex]
-			str, _ := d.(string)
-			if str == "" {
-				return 0
-			}
-			num, err := strconv.ParseFloat(str, 64)
-			if err != nil {
-				panic("parse float: " + err.Error())
-			}
-			return num
-		}
+	for _, f := range fundamentals.Filters() {
+		b.Reset()
+		b.WriteString(f.Name)
+		b.WriteByte('\t')
+		b.WriteString(strconv.FormatFloat(f.Default, 'f', -1, 64))
+		b.WriteByte('\t')
+		b.WriteString(f.Description)
+		b.WriteByte('\t')
+		fmt.Fprintln(w, b.String())
 	}
-	return 0
-}
 
-func (s *valuation) PriceToEarnings(
-	period string,
-) float64 {
-	return s.value(
-		s.periodIndex(period),
-		"Price/Earnings",
-	)
-}
-
-func (s *valuation) PriceToBook(
-	period string,
-) float64 {
-	return s.value(
-		s.periodIndex(period),
-		"Price/Book",
+	w.Flush()
+	c.writef("%s", buf.String())
+	c.writef(
+		"Sort keys: %v",
+		strings.Join(fundamentals.SortKeys(), ", "),
 	)
 }
 
@@ -2028,25 +906,56 @@ func exists(f string) (bool, error) {
 }
 
 func (c *Command) pullValuation(ctx context.Context) error {
-	baseDir := c.opts.dir
-	if baseDir == "" {
-		return fmt.Errorf("dir must be specified")
+	_, err := c.opts.db.Migrate(ctx, &divyield.DBMigrateInput{})
+	if err != nil {
+		return fmt.Errorf("migrate: %v", err)
 	}
 
-	exist, err := exists(baseDir)
+	urlsFile := c.args[0]
+	urls, err := readURLs(ctx, urlsFile)
 	if err != nil {
 		return err
 	}
-	if !exist {
-		return fmt.Errorf("dir not found: %v", baseDir)
+
+	// the URLs failed to pull are marked missing
+	missingFile := urlsFile + ".missing"
+	if c.opts.retryMissing {
+		retry, err := readMissing(missingFile)
+		if err != nil {
+			return err
+		}
+		retried := make([]string, 0)
+		for _, u := range urls {
+			if retry[u] {
+				retried = append(retried, u)
+			}
+		}
+		urls = retried
 	}
 
-	urlsFile := c.args[0]
-	uf, err := os.Open(urlsFile)
+	pulls, err := c.statementPulls(ctx, urls)
 	if err != nil {
 		return err
 	}
-	defer uf.Close()
+
+	// the statements pulled within the max age are fresh,
+	// the older ones are stale, the others are missing
+	fresh, stale, missing := 0, 0, 0
+	jobs := make([]string, 0, len(urls))
+	now := time.Now()
+	for _, u := range urls {
+		pulled, ok := pulls[u]
+		switch {
+		case !ok:
+			missing++
+		case c.opts.maxAge > 0 && now.Sub(pulled) <= c.opts.maxAge:
+			fresh++
+			continue
+		default:
+			stale++
+		}
+		jobs = append(jobs, u)
+	}
 
 	jobCh, resCh := c.opts.financialsService.PullValuation(
 		ctx,
@@ -2054,137 +963,163 @@ func (c *Command) pullValuation(ctx context.Context) error {
 	)
 
 	go func() {
-		scanner := bufio.NewScanner(uf)
-	SCANNER:
-		for scanner.Scan() {
+	JOBS:
+		for _, u := range jobs {
 			select {
 			case <-ctx.Done():
-				break SCANNER
+				break JOBS
 			default:
 				// noop
 			}
 
-			u := scanner.Text()
-			u = strings.TrimSpace(u)
-			if u == "" || strings.HasPrefix(u, "#") {
-				continue
-			}
-
 			_, symbol, _ := morningstarURLValuation(u)
-
-			/*
-				m := filepath.Join(baseDir, exch, symbol, "missing")
-				exist, err = exists(m)
-				if err != nil {
-					fmt.Println(err)
-					continue
-				}
-				if exist {
-					continue
-				}
-			*/
-
-			/*
-				dir := filepath.Join(baseDir, exch, symbol, "is.json")
-				exist, err = exists(dir)
-				if err != nil {
-					fmt.Println(err)
-					continue
-				}
-				if exist {
-					continue
-				}
-			*/
-
 			jobCh <- u
 			fmt.Printf("%v: %v\n", symbol, u)
 		}
-		if err := scanner.Err(); err != nil {
-			fmt.Println(err)
-		}
 		close(jobCh)
 	}()
 
+	failed := make([]string, 0)
 	for res := range resCh {
 		_, symbol, exch := morningstarURLValuation(res.URL)
-		err := os.MkdirAll(
-			filepath.Join(baseDir, exch, symbol),
-			0777,
-		)
-		if err != nil {
-			fmt.Printf("%v: %v\n", symbol, err)
+		if res.Err != nil {
+			fmt.Printf("%v: %v\n", symbol, res.Err)
+			failed = append(failed, res.URL)
 			continue
 		}
 
-		err = res.Err
+		fin, err := fundamentals.Parse(
+			res.Realtime,
+			res.Valuation,
+			res.IncomeStatement,
+			res.BalanceSheet,
+			res.CashFlow,
+		)
+		if err == nil {
+			err = fin.Validate(exch, symbol)
+		}
+		var perr *fundamentals.ParseError
+		if errors.As(err, &perr) {
+			perr.Exchange = exch
+			perr.Symbol = symbol
+		}
 		if err != nil {
-			fmt.Printf("%v: %v\n", symbol, err)
-			if strings.Contains(
-				err.Error(),
-				"deadline exceeded",
-			) {
-				err := ioutil.WriteFile(
-					missingFile(baseDir, res.URL),
-					[]byte(""),
-					0644,
-				)
-				if err != nil {
-					fmt.Printf("%v: %v\n", symbol, err)
-				}
-			}
+			fmt.Println(err)
+			failed = append(failed, res.URL)
 			continue
 		}
 
-		err = ioutil.WriteFile(
-			realtimeFile(baseDir, res.URL),
-			[]byte(res.Realtime),
-			0644,
+		items := fin.Items(exch, symbol, time.Now())
+		_, err = c.opts.db.SaveStatementItems(
+			ctx,
+			&divyield.DBSaveStatementItemsInput{
+				Items: items,
+			},
 		)
 		if err != nil {
-			fmt.Printf("%v: %v\n", symbol, err)
+			fmt.Printf("%v: save statements: %v\n", symbol, err)
+			failed = append(failed, res.URL)
 			continue
 		}
+		fmt.Printf("%v: %v items\n", symbol, len(items))
+	}
 
-		err = ioutil.WriteFile(
-			valuationFile(baseDir, res.URL),
-			[]byte(res.Valuation),
-			0644,
-		)
-		if err != nil {
-			fmt.Printf("%v: %v\n", symbol, err)
-			continue
+	err = writeMissing(missingFile, failed)
+	if err != nil {
+		return err
+	}
+	fmt.Printf(
+		"Fresh: %v, stale: %v, missing: %v, failed: %v (%v)\n",
+		fresh,
+		stale,
+		missing,
+		len(failed),
+		missingFile,
+	)
+	return nil
+}
+
+// statementPulls returns the time of the latest
+// snapshot of the statements of the URLs.
+func (c *Command) statementPulls(
+	ctx context.Context,
+	urls []string,
+) (map[string]time.Time, error) {
+	keys := make(map[divyield.StatementSymbol]string)
+	symbols := make([]*divyield.StatementSymbol, 0, len(urls))
+	for _, u := range urls {
+		_, symbol, exch := morningstarURLValuation(u)
+		k := divyield.StatementSymbol{
+			Exchange: exch,
+			Symbol:   symbol,
 		}
+		keys[k] = u
+		symbols = append(symbols, &k)
+	}
 
-		err = ioutil.WriteFile(
-			incomeStatementFile(baseDir, res.URL),
-			[]byte(res.IncomeStatement),
-			0644,
-		)
-		if err != nil {
-			fmt.Printf("%v: %v\n", symbol, err)
-			continue
+	pulls := make(map[string]time.Time)
+	if len(symbols) == 0 {
+		return pulls, nil
+	}
+
+	out, err := c.opts.db.StatementPulls(
+		ctx,
+		&divyield.DBStatementPullsInput{
+			Symbols: symbols,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get statement pulls: %v", err)
+	}
+	for _, v := range out.Pulls {
+		k := divyield.StatementSymbol{
+			Exchange: v.Exchange,
+			Symbol:   v.Symbol,
+		}
+		if u, ok := keys[k]; ok {
+			pulls[u] = v.Pulled
 		}
+	}
+	return pulls, nil
+}
 
-		err = ioutil.WriteFile(
-			balanceSheetFile(baseDir, res.URL),
-			[]byte(res.BalanceSheet),
-			0644,
-		)
-		if err != nil {
-			fmt.Printf("%v: %v\n", symbol, err)
-			continue
+// readMissing returns the URLs of the missing file.
+func readMissing(p string) (map[string]bool, error) {
+	f, err := os.Open(p)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return map[string]bool{}, nil
 		}
+		return nil, err
+	}
+	defer f.Close()
 
-		err = ioutil.WriteFile(
-			cashFlowFile(baseDir, res.URL),
-			[]byte(res.CashFlow),
-			0644,
-		)
-		if err != nil {
-			fmt.Printf("%v: %v\n", symbol, err)
-			continue
+	urls := make(map[string]bool)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		u := strings.TrimSpace(scanner.Text())
+		if u != "" {
+			urls[u] = true
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("read missing: %v", err)
+	}
+	return urls, nil
+}
+
+// writeMissing writes the URLs to the missing file.
+func writeMissing(p string, urls []string) error {
+	sort.Strings(urls)
+	b := &bytes.Buffer{}
+	for _, u := range urls {
+		b.WriteString(u)
+		b.WriteByte('\n')
+	}
+	err := os.WriteFile(p, b.Bytes(), 0666)
+	if err != nil {
+		return fmt.Errorf("write missing: %v", err)
+	}
 	return nil
 }
 
@@ -2201,36 +1136,6 @@ func writeFile(p string, records [][]string) error {
 	return writeCSV(f, records)
 }
 
-func missingFile(baseDir, u string) string {
-	_, symbol, exch := morningstarURLValuation(u)
-	return filepath.Join(baseDir, exch, symbol, "missing")
-}
-
-func realtimeFile(baseDir, u string) string {
-	_, symbol, exch := morningstarURLValuation(u)
-	return filepath.Join(baseDir, exch, symbol, "rt.json")
-}
-
-func valuationFile(baseDir, u string) string {
-	_, symbol, exch := morningstarURLValuation(u)
-	return filepath.Join(baseDir, exch, symbol, "valuation.json")
-}
-
-func incomeStatementFile(baseDir, u string) string {
-	_, symbol, exch := morningstarURLValuation(u)
-	return filepath.Join(baseDir, exch, symbol, "is.json")
-}
-
-func balanceSheetFile(baseDir, u string) string {
-	_, symbol, exch := morningstarURLValuation(u)
-	return filepath.Join(baseDir, exch, symbol, "bs.json")
-}
-
-func cashFlowFile(baseDir, u string) string {
-	_, symbol, exch := morningstarURLValuation(u)
-	return filepath.Join(baseDir, exch, symbol, "cf.json")
-}
-
 func writeCSV(o io.Writer, records [][]string) error {
 	w := csv.NewWriter(o)
 	w.Comma = ';'
@@ -2264,19 +1169,28 @@ func (c *Command) pull(ctx context.Context) error {
 		return fmt.Errorf("Symbol not found")
 	}
 
+	_, err = c.opts.db.Migrate(ctx, &divyield.DBMigrateInput{})
+	if err != nil {
+		return fmt.Errorf("migrate: %v", err)
+	}
+
 	err = c.opts.db.InitSchema(ctx, symbols)
 	if err != nil {
 		return fmt.Errorf("init schema: %v", err)
 	}
 
-	eout, err := c.opts.exchangeService.Fetch(
-		ctx,
-		&divyield.ExchangeFetchInput{},
-	)
-	if err != nil {
-		return err
+	if c.opts.creditService != nil {
+		err = c.checkCreditBudget(ctx, symbols, from)
+		if err != nil {
+			return err
+		}
+		defer c.writeCredits(ctx)
 	}
 
+	// the exchanges are fetched only for
+	// the symbols with an exchange suffix
+	var exchanges []*divyield.Exchange
+
 	for _, symbol := range symbols {
 		utd, err := c.upToDate(ctx, symbol)
 		if err != nil {
@@ -2314,8 +1228,19 @@ func (c *Command) pull(ctx context.Context) error {
 		var priceCurrency string
 		dashIdx := strings.LastIndexByte(symbol, '-')
 		if dashIdx != -1 {
+			if exchanges == nil {
+				eout, err := c.opts.exchangeService.Fetch(
+					ctx,
+					&divyield.ExchangeFetchInput{},
+				)
+				if err != nil {
+					return err
+				}
+				exchanges = eout.Exchanges
+			}
+
 			symbolSuffix := symbol[dashIdx:]
-			for _, ex := range eout.Exchanges {
+			for _, ex := range exchanges {
 				if ex.Suffix == symbolSuffix {
 					priceCurrency = ex.Currency
 				}
@@ -2390,6 +1315,9 @@ func (c *Command) pull(ctx context.Context) error {
 			return fmt.Errorf("%v: %v", symbol, err)
 		}
 		for _, v := range dout.Dividends {
+			if v.Currency == "" {
+				v.Currency = priceCurrency
+			}
 			if v.Currency != priceCurrency {
 				ccout, err := c.opts.currencyService.Convert(
 					ctx,
@@ -2841,10 +1769,16 @@ func (c *Command) writef(format string, v ...interface{}) {
 	}
 }
 
+// statsChunkSize is the number of symbols
+// loaded by one batch query.
+const statsChunkSize = 50
+
 type statsGenerator struct {
 	db                 divyield.DB
 	writer             io.Writer
+	workers            int
 	startDate          time.Time
+	asOf               time.Time
 	inflation          *divyield.Inflation
 	sp500DividendYield *divyield.SP500DividendYield
 
@@ -2854,10 +1788,29 @@ type statsGenerator struct {
 	ggrROI              float64
 	ggrMin              float64
 	ggrMax              float64
+	intrinsic           *intrinsic.Model
 	noCutDividend       bool
 	noDecliningDGR      bool
 	dgrAvgMin           float64
 	dgrYearly           bool
+
+	currencyService divyield.CurrencyService
+	reportCurrency  string
+	homeRates       homeRates
+
+	cpiService     divyield.CPIService
+	cpiCountry     string
+	realDGRMin     float64
+	inflationRates map[int]float64
+}
+
+// now returns the as-of date, the current
+// time if the as-of date is not set.
+func (g *statsGenerator) now() time.Time {
+	if !g.asOf.IsZero() {
+		return g.asOf
+	}
+	return time.Now().UTC()
 }
 
 func (g *statsGenerator) divYieldFwdMin() float64 {
@@ -2872,6 +1825,15 @@ func (g *statsGenerator) Generate(
 	ctx context.Context,
 	symbols []string,
 ) (*divyield.Stats, error) {
+	err := g.loadCPI(ctx)
+	if err != nil {
+		return nil, err
+	}
+	err = g.checkCPI()
+	if err != nil {
+		return nil, err
+	}
+
 	var workerWg sync.WaitGroup
 	var resultWg sync.WaitGroup
 	resultCh := make(chan result)
@@ -2904,10 +1866,26 @@ func (g *statsGenerator) Generate(
 
 	}()
 
-LOOP:
-	for _, symbol := range symbols {
-		symbol := symbol
+	workers := g.workers
+	if workers < 1 {
+		workers = 1
+	}
+
+	chunkCh := make(chan []string)
+	for i := 0; i < workers; i++ {
+		workerWg.Add(1)
+		go func() {
+			defer workerWg.Done()
+			for chunk := range chunkCh {
+				for _, res := range g.generateStatsRows(ctx, chunk) {
+					resultCh <- res
+				}
+			}
+		}()
+	}
 
+LOOP:
+	for i := 0; i < len(symbols); i += statsChunkSize {
 		select {
 		case <-ctx.Done():
 			break LOOP
@@ -2915,13 +1893,13 @@ LOOP:
 			// noop
 		}
 
-		workerWg.Add(1)
-		go func(symbol string) {
-			defer workerWg.Done()
-			row, err := g.generateStatsRow(ctx, symbol)
-			resultCh <- result{Symbol: symbol, Row: row, Err: err}
-		}(symbol)
+		end := i + statsChunkSize
+		if end > len(symbols) {
+			end = len(symbols)
+		}
+		chunkCh <- symbols[i:end]
 	}
+	close(chunkCh)
 
 	workerWg.Wait()
 	close(resultCh)
@@ -2942,6 +1920,16 @@ LOOP:
 		g.filterDGRYearly,
 	)
 
+	if g.reportCurrency != "" {
+		err := g.convertHome(ctx, stats)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	g.realDGRs(stats)
+	g.filter(stats, g.filterRealDGRMin)
+
 	return stats, nil
 }
 
@@ -2951,55 +1939,111 @@ type result struct {
 	Err    error
 }
 
-func (g *statsGenerator) generateStatsRow(
+// generateStatsRows loads the data of the symbols
+// with batch queries and returns a result for each symbol.
+func (g *statsGenerator) generateStatsRows(
 	ctx context.Context,
-	symbol string,
-) (*divyield.StatsRow, error) {
+	symbols []string,
+) []result {
+	results := make([]result, 0, len(symbols))
+	fail := func(err error) []result {
+		for _, symbol := range symbols {
+			results = append(results, result{Symbol: symbol, Err: err})
+		}
+		return results
+	}
 
 	proOut, err := g.db.Profiles(
 		ctx,
 		&divyield.DBProfilesInput{
-			Symbols: []string{symbol},
+			Symbols: symbols,
 		},
 	)
 	if err != nil {
-		return nil, err
+		return fail(err)
 	}
-	profile := proOut.Profiles[0]
-
-	dyf := &divyield.DividendYieldFilter{
-		Limit: 1,
-	}
-	dividendYields, err := g.db.DividendYields(ctx, symbol, dyf)
-	if err != nil {
-		return nil, fmt.Errorf("get dividend yields: %s", err)
+	profiles := make(map[string]*divyield.Profile)
+	for _, p := range proOut.Profiles {
+		profiles[p.Symbol] = p
 	}
 
-	divYieldFwd := float64(0)
-	divFwd := float64(0)
-	ggr := float64(0)
-	if len(dividendYields) > 0 {
-		divYieldFwd = dividendYields[0].ForwardTTM()
-		divFwd = dividendYields[0].DividendForwardTTM()
-	}
-	if g.ggrROI > 0 {
-		ggr = g.ggrROI - divYieldFwd
+	dyOut, err := g.db.LatestDividendYields(
+		ctx,
+		&divyield.DBLatestDividendYieldsInput{
+			Symbols: symbols,
+			Date:    g.asOf,
+		},
+	)
+	if err != nil {
+		return fail(fmt.Errorf("get dividend yields: %s", err))
 	}
 
 	df := &divyield.DividendFilter{
 		From: time.Date(
-			time.Now().UTC().Year()-11, time.January, 1,
+			g.now().Year()-11, time.January, 1,
 			0, 0, 0, 0, time.UTC),
 		CashOnly: true,
 		Regular:  true,
 	}
-	dividendsDB, err := g.db.Dividends(ctx, symbol, df)
+	divOut, err := g.db.DividendsBySymbol(
+		ctx,
+		&divyield.DBDividendsBySymbolInput{
+			Symbols: symbols,
+			Filter:  df,
+		},
+	)
 	if err != nil {
-		return nil, fmt.Errorf("get dividends: %s", err)
+		return fail(fmt.Errorf("get dividends: %s", err))
+	}
+
+	for _, symbol := range symbols {
+		profile, ok := profiles[symbol]
+		if !ok {
+			results = append(results, result{
+				Symbol: symbol,
+				Err:    fmt.Errorf("profile not found"),
+			})
+			continue
+		}
+
+		row := g.generateStatsRow(
+			profile,
+			dyOut.DividendYields[symbol],
+			divOut.Dividends[symbol],
+		)
+		results = append(results, result{Symbol: symbol, Row: row})
+	}
+	return results
+}
+
+func (g *statsGenerator) generateStatsRow(
+	profile *divyield.Profile,
+	dividendYield *divyield.DividendYield,
+	dividendsDB []*divyield.Dividend,
+) *divyield.StatsRow {
+	divYieldFwd := float64(0)
+	divFwd := float64(0)
+	ggr := float64(0)
+	ddm := math.NaN()
+	ddmMoS := math.NaN()
+	if dividendYield != nil {
+		divYieldFwd = dividendYield.ForwardTTM()
+		divFwd = dividendYield.DividendForwardTTM()
+		ddm = g.intrinsic.DividendDiscount(divFwd)
+		ddmMoS = intrinsic.MarginOfSafety(
+			ddm,
+			dividendYield.CloseAdjSplits,
+		)
+	}
+	if g.ggrROI > 0 {
+		ggr = g.ggrROI - divYieldFwd
 	}
 
 	dividends := make([]*divyield.DividendChange, 0, len(dividendsDB))
 	for _, d := range dividendsDB {
+		if !g.asOf.IsZero() && d.ExDate.After(g.asOf) {
+			continue
+		}
 		dividends = append(dividends, &divyield.DividendChange{
 			Dividend: d,
 		})
@@ -3013,6 +2057,8 @@ func (g *statsGenerator) generateStatsRow(
 		DivYieldFwd:          divYieldFwd,
 		DivFwd:               divFwd,
 		GordonGrowthRate:     ggr,
+		FairPriceDDM:         ddm,
+		MarginOfSafetyDDM:    ddmMoS,
 		Dividends:            dividends,
 		DividendChangeMR:     divChangeMR,
 		DividendChangeMRDate: divChangeMRDate,
@@ -3026,7 +2072,7 @@ func (g *statsGenerator) generateStatsRow(
 		//		},
 	}
 
-	return row, nil
+	return row
 }
 
 func (g *statsGenerator) calcDividendChanges(
@@ -3190,7 +2236,7 @@ func (g *statsGenerator) filterDGRYearly(
 	}
 
 	m := make(map[int]*divyield.DividendChange)
-	endYear := time.Now().UTC().Year() - 1
+	endYear := g.now().Year() - 1
 	startYear := g.startDate.Year() + 1
 
 	for _, v := range row.Dividends {
@@ -3222,6 +2268,18 @@ func (g *statsGenerator) writef(
 
 func (g *statsGenerator) dgrs(
 	dividends []*divyield.DividendChange,
+) map[int]float64 {
+	return g.dgrsOf(
+		dividends,
+		func(v *divyield.DividendChange) float64 {
+			return v.AmountAdj
+		},
+	)
+}
+
+func (g *statsGenerator) dgrsOf(
+	dividends []*divyield.DividendChange,
+	amount func(v *divyield.DividendChange) float64,
 ) map[int]float64 {
 	if len(dividends) == 0 {
 		return nil
@@ -3230,11 +2288,11 @@ func (g *statsGenerator) dgrs(
 	amounts := make(map[int]float64)
 	for _, v := range dividends {
 		y := v.ExDate.Year()
-		amounts[y] += v.AmountAdj
+		amounts[y] += amount(v)
 	}
 	//fmt.Println(amounts)
 
-	y := time.Now().UTC().Year()
+	y := g.now().Year()
 	ye := y - 1
 	changes := make(map[int]float64)
 	for _, i := range []int{1, 2, 3, 4} {
@@ -3266,7 +2324,7 @@ func (g *statsGenerator) dgr(
 		return 0
 	}
 
-	y := time.Now().UTC().Year()
+	y := g.now().Year()
 	ed := time.Date(
 		y-1, time.December, 31,
 		0, 0, 0, 0, time.UTC,
@@ -3341,12 +2399,30 @@ type chartGenerator struct {
 	db        divyield.DB
 	startDate time.Time
 	dir       string
+
+	sp500Service        divyield.SP500Service
+	divYieldFwdSP500Min float64
+	divYieldFwdSP500Max float64
 }
 
 func (g *chartGenerator) Generate(
 	ctx context.Context,
 	stats *divyield.Stats,
 ) error {
+	var sp500Yields []*divyield.SP500DividendYield
+	if g.sp500Service != nil {
+		out, err := g.sp500Service.DividendYields(
+			ctx,
+			&divyield.SP500DividendYieldsInput{
+				Until: time.Now().UTC(),
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("get S&P 500 dividend yields: %v", err)
+		}
+		sp500Yields = out.DividendYields
+	}
+
 	for _, row := range stats.Rows {
 		symbol := row.Profile.Symbol
 		dividends := row.Dividends
@@ -3360,21 +2436,31 @@ func (g *chartGenerator) Generate(
 		)
 
 		chartDir := filepath.Join(g.dir, "work/chart")
-		err = g.writeFileYields(symbol, yields, chartDir)
+		err = g.writeFileYields(symbol, yields, sp500Yields, chartDir)
 		if err != nil {
 			return err
 		}
-		err = g.writeFileDividends(symbol, dividends, chartDir)
+		err = g.writeFileDividends(
+			symbol,
+			dividends,
+			stats.ReportCurrency != "",
+			chartDir,
+		)
 		if err != nil {
 			return err
 		}
 
 		minPrice, maxPrice := g.rangePrices(yields)
 		minYieldFwd, maxYieldFwd := g.rangeYieldsFwd(yields)
+		maxYieldRel := g.maxYieldRel(yields, sp500Yields)
 		yieldStart := yields[0].ForwardTTM()
 
 		//		minYieldTrail, maxYieldTrail := g.rangeYieldsTrail(yields)
 		_, maxDiv := g.rangeDividends(dividends)
+		maxDivHome := 0.0
+		for _, v := range dividends {
+			maxDivHome = math.Max(maxDivHome, v.AmountAdjHome)
+		}
 		minDGR, maxDGR := g.rangeDividendChanges(dividends)
 
 		chartParams := chartParams{
@@ -3448,6 +2534,23 @@ func (g *chartGenerator) Generate(
 				0.01,
 			),
 			DGRAvg: row.DGRs[4],
+
+			ReportCurrency: stats.ReportCurrency,
+			DivHomeYrMax:   math.Max(maxDivHome*1.1, 0.01),
+			DGRAvgHome:     row.DGRsHome[4],
+
+			SP500:            len(sp500Yields) > 0,
+			YieldRelYrMax:    math.Max(maxYieldRel*1.1, 0.01),
+			YieldRelSP500Min: g.divYieldFwdSP500Min,
+			YieldRelSP500Max: g.divYieldFwdSP500Max,
+		}
+		if chartParams.SP500 {
+			chartParams.TitleDivYieldFwd += " (relative to the S&P 500 on the right axis)"
+		}
+		if stats.ReportCurrency != "" {
+			chartParams.TitleDividends += " (" +
+				row.Currency + ", " +
+				stats.ReportCurrency + " on the right axis)"
 		}
 		chartTmpl, err := template.
 			New("plot").
@@ -3490,6 +2593,7 @@ func (g *chartGenerator) Generate(
 func (g *chartGenerator) writeFileYields(
 	symbol string,
 	yields []*divyield.DividendYield,
+	sp500Yields []*divyield.SP500DividendYield,
 	dir string,
 ) error {
 	err := os.MkdirAll(dir, 0666)
@@ -3514,6 +2618,12 @@ func (g *chartGenerator) writeFileYields(
 	if err != nil {
 		return err
 	}
+	if len(sp500Yields) > 0 {
+		_, err = w.Write([]byte("DivYieldForwardSP500Rel,"))
+		if err != nil {
+			return err
+		}
+	}
 
 	for i := 0; i < len(yields); i++ {
 		y := yields[i]
@@ -3532,6 +2642,16 @@ func (g *chartGenerator) writeFileYields(
 		if err != nil {
 			return err
 		}
+		if len(sp500Yields) > 0 {
+			rel := math.NaN()
+			if r, ok := sp500Rate(sp500Yields, y.Date); ok && r > 0 {
+				rel = y.ForwardTTM() / r
+			}
+			_, err = fmt.Fprintf(w, ",%.2f", rel)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return w.Flush()
@@ -3540,6 +2660,7 @@ func (g *chartGenerator) writeFileYields(
 func (g *chartGenerator) writeFileDividends(
 	symbol string,
 	dividends []*divyield.DividendChange,
+	home bool,
 	dir string,
 ) error {
 	p := filepath.Join(dir, symbol+".dividends.csv")
@@ -3559,6 +2680,12 @@ func (g *chartGenerator) writeFileDividends(
 	if err != nil {
 		return err
 	}
+	if home {
+		_, err = w.Write([]byte("DivAdjHome,"))
+		if err != nil {
+			return err
+		}
+	}
 
 	for i := 0; i < len(dividends); i++ {
 		y := dividends[i]
@@ -3577,6 +2704,12 @@ func (g *chartGenerator) writeFileDividends(
 		if err != nil {
 			return err
 		}
+		if home {
+			_, err = fmt.Fprintf(w, ",%.2f", y.AmountAdjHome)
+			if err != nil {
+				return err
+			}
+		}
 	}
 
 	return w.Flush()
@@ -3630,6 +2763,23 @@ func (g *chartGenerator) rangeYieldsFwd(
 	return min, max
 }
 
+// maxYieldRel returns the maximum of the forward yields
+// relative to the S&P 500 dividend yield of the month.
+func (g *chartGenerator) maxYieldRel(
+	yields []*divyield.DividendYield,
+	sp500Yields []*divyield.SP500DividendYield,
+) float64 {
+	max := 0.0
+	for _, v := range yields {
+		r, ok := sp500Rate(sp500Yields, v.Date)
+		if !ok || r <= 0 {
+			continue
+		}
+		max = math.Max(max, v.ForwardTTM()/r)
+	}
+	return max
+}
+
 func (g *chartGenerator) rangeYieldsTrail(
 	yields []*divyield.DividendYield,
 ) (float64, float64) {
@@ -3720,6 +2870,15 @@ type chartParams struct {
 	DGRYrMin float64
 	DGRYrMax float64
 	DGRAvg   float64
+
+	ReportCurrency string
+	DivHomeYrMax   float64
+	DGRAvgHome     float64
+
+	SP500            bool
+	YieldRelYrMax    float64
+	YieldRelSP500Min float64
+	YieldRelSP500Max float64
 }
 
 const chartTmpl = `
@@ -3760,22 +2919,26 @@ plot yieldsfile using 1:2 with filledcurves above y = 0 lc 'royalblue';
 set origin 0.0,0.50;
 set title '{{.TitleDivYieldFwd}}';
 set yrange [{{.YieldFwdYrMin}}:{{.YieldFwdYrMax}}];
-set y2range [{{.YieldFwdYrMin}}:{{.YieldFwdYrMax}}];
+{{if .SP500}}set y2range [0:{{.YieldRelYrMax}}];
+plot yieldsfile using 1:3 with filledcurves above y = 0 lc 'royalblue', {{.YieldStart}} title '' lw 4 lc 'red', yieldsfile using 1:4 axes x1y2 with lines lw 2 lc 'orange'{{if .YieldRelSP500Min}}, {{.YieldRelSP500Min}} axes x1y2 title 'S&P 500 min' lw 2 dt 2 lc 'orange'{{end}}{{if .YieldRelSP500Max}}, {{.YieldRelSP500Max}} axes x1y2 title 'S&P 500 max' lw 2 dt 2 lc 'orange'{{end}};
+{{else}}set y2range [{{.YieldFwdYrMin}}:{{.YieldFwdYrMax}}];
 plot yieldsfile using 1:3 with filledcurves above y = 0 lc 'royalblue', {{.YieldStart}} title '' lw 4 lc 'red';
-
+{{end}}
 set boxwidth 1 absolute;
 
 set origin 0.0,0.25;
 set title '{{.TitleDividends}}';
 set yrange [{{.DivYrMin}}:{{.DivYrMax}}];
-set y2range [{{.DivYrMin}}:{{.DivYrMax}}];
+{{if .ReportCurrency}}set y2range [0:{{.DivHomeYrMax}}];
+plot dividendsfile using 1:($2 == 0 ? NaN : $2) with fsteps lw 4 lc 'royalblue', dividendsfile using 1:($2 == 0 ? NaN : $2) with boxes lw 4 lc 'royalblue', dividendsfile using 1:($4 == 0 ? NaN : $4) axes x1y2 with fsteps lw 4 lc 'orange';
+{{else}}set y2range [{{.DivYrMin}}:{{.DivYrMax}}];
 plot dividendsfile using 1:($2 == 0 ? NaN : $2) with fsteps lw 4 lc 'royalblue', dividendsfile using 1:($2 == 0 ? NaN : $2) with boxes lw 4 lc 'royalblue';
-
+{{end}}
 set origin 0.0,0.0;
 set title '{{.TitleDGR}}';
 set yrange [{{.DGRYrMin}}:{{.DGRYrMax}}];
 set y2range [{{.DGRYrMin}}:{{.DGRYrMax}}];
-plot dividendsfile using 1:($3 == 0 ? NaN : $3) with boxes lw 4 lc 'royalblue', 0 title '' lw 4 lc 'royalblue', {{.DGRAvg}} title 'DGRAvg' lw 4 lc 'red';
+plot dividendsfile using 1:($3 == 0 ? NaN : $3) with boxes lw 4 lc 'royalblue', 0 title '' lw 4 lc 'royalblue', {{.DGRAvg}} title 'DGRAvg' lw 4 lc 'red'{{if .ReportCurrency}}, {{.DGRAvgHome}} title 'DGRAvg {{.ReportCurrency}}' lw 4 lc 'orange'{{end}};
 
 unset multiplot;
 `
@@ -3785,7 +2948,11 @@ func isNaN(v float64) bool {
 }
 
 var defaultOptions = options{
-	writer: nil,
+	writer:    nil,
+	workers:   4,
+	periods:   5,
+	profile:   fundamentals.ProfileIndustrial,
+	intrinsic: intrinsic.New(),
 }
 
 type options struct {
@@ -3803,8 +2970,22 @@ type options struct {
 	priceService      divyield.PriceService
 	currencyService   divyield.CurrencyService
 	inflationService  divyield.InflationService
+	cpiService        divyield.CPIService
 	sp500Service      divyield.SP500Service
 	financialsService divyield.FinancialsService
+	creditService     divyield.CreditService
+	creditBudget      int64
+	metrics           []string
+	periods           int
+	filters           []string
+	sortKey           string
+	profile           string
+	intrinsic         *intrinsic.Model
+	fscoreMin         int
+	zscoreMin         float64
+	scores            bool
+	retryMissing      bool
+	maxAge            time.Duration
 
 	divYieldFwdSP500Min float64
 	divYieldFwdSP500Max float64
@@ -3818,6 +2999,15 @@ type options struct {
 	dgrYearly           bool
 	chart               bool
 	force               bool
+	workers             int
+	reportCurrency      string
+	asOf                time.Time
+	cpiCountry          string
+	realDGRMin          float64
+
+	auditGapDays   int
+	auditJump      float64
+	auditStaleDays int
 }
 
 type Option func(o options) options
@@ -3915,6 +3105,15 @@ func InflationService(
 	}
 }
 
+func CPIService(
+	v divyield.CPIService,
+) Option {
+	return func(o options) options {
+		o.cpiService = v
+		return o
+	}
+}
+
 func SP500Service(
 	v divyield.SP500Service,
 ) Option {
@@ -3933,6 +3132,122 @@ func FinancialsService(
 	}
 }
 
+func CreditService(
+	v divyield.CreditService,
+) Option {
+	return func(o options) options {
+		o.creditService = v
+		return o
+	}
+}
+
+// Metrics are the names of the metrics of bargain.
+func Metrics(v []string) Option {
+	return func(o options) options {
+		o.metrics = v
+		return o
+	}
+}
+
+// Periods is the number of the fiscal years of
+// the periodic metrics of bargain.
+func Periods(v int) Option {
+	return func(o options) options {
+		o.periods = v
+		return o
+	}
+}
+
+// Filters are the filters of bargain with their
+// optional parameters, e.g. debt-equity-max=0.5.
+func Filters(v []string) Option {
+	return func(o options) options {
+		o.filters = v
+		return o
+	}
+}
+
+// SortKey is the metric bargain sorts by
+// with an optional order, e.g. CapRate:asc.
+func SortKey(v string) Option {
+	return func(o options) options {
+		o.sortKey = v
+		return o
+	}
+}
+
+// Profile is the profile of the default metrics,
+// filters and sort key of bargain, e.g. bank.
+func Profile(v string) Option {
+	return func(o options) options {
+		o.profile = v
+		return o
+	}
+}
+
+// Intrinsic is the model of the intrinsic values
+// of stats and bargain.
+func Intrinsic(v *intrinsic.Model) Option {
+	return func(o options) options {
+		o.intrinsic = v
+		return o
+	}
+}
+
+// FScoreMin is the minimum Piotroski F-score
+// of bargain, 0 means no limit.
+func FScoreMin(v int) Option {
+	return func(o options) options {
+		o.fscoreMin = v
+		return o
+	}
+}
+
+// ZScoreMin is the minimum Altman Z-score
+// of bargain, 0 means no limit.
+func ZScoreMin(v float64) Option {
+	return func(o options) options {
+		o.zscoreMin = v
+		return o
+	}
+}
+
+// Scores writes the components of the F-score
+// and the Z-score of the companies of bargain.
+func Scores(v bool) Option {
+	return func(o options) options {
+		o.scores = v
+		return o
+	}
+}
+
+// RetryMissing pulls only the valuations of the
+// URLs that failed in the previous pull.
+func RetryMissing(v bool) Option {
+	return func(o options) options {
+		o.retryMissing = v
+		return o
+	}
+}
+
+// MaxAge is the age of the statements pull-valuation
+// does not pull again, 0 means all are pulled.
+func MaxAge(v time.Duration) Option {
+	return func(o options) options {
+		o.maxAge = v
+		return o
+	}
+}
+
+// CreditBudget is the maximum estimated message
+// credits of a pull, 0 means no limit.
+func CreditBudget(v int64) Option {
+	return func(o options) options {
+		o.creditBudget = v
+		return o
+	}
+}
+
 func DB(db divyield.DB) Option {
 	return func(o options) options {
 		o.db = db
@@ -4023,3 +3338,63 @@ func Force(v bool) Option {
 		return o
 	}
 }
+
+func ReportCurrency(v string) Option {
+	return func(o options) options {
+		o.reportCurrency = strings.ToUpper(v)
+		return o
+	}
+}
+
+// AsOf sets the date of the yields and the S&P 500
+// dividend yield of the stats, the latest if it is zero.
+func AsOf(v time.Time) Option {
+	return func(o options) options {
+		o.asOf = v
+		return o
+	}
+}
+
+// CPICountry sets the country of the CPI series
+// used by the real DGRs, e.g. HU or US.
+func CPICountry(v string) Option {
+	return func(o options) options {
+		o.cpiCountry = strings.ToUpper(v)
+		return o
+	}
+}
+
+func RealDGRMin(v float64) Option {
+	return func(o options) options {
+		o.realDGRMin = v
+		return o
+	}
+}
+
+func Workers(v int) Option {
+	return func(o options) options {
+		o.workers = v
+		return o
+	}
+}
+
+func AuditGapDays(v int) Option {
+	return func(o options) options {
+		o.auditGapDays = v
+		return o
+	}
+}
+
+func AuditJump(v float64) Option {
+	return func(o options) options {
+		o.auditJump = v
+		return o
+	}
+}
+
+func AuditStaleDays(v int) Option {
+	return func(o options) options {
+		o.auditStaleDays = v
+		return o
+	}
+}
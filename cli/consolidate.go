@@ -0,0 +1,80 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"text/tabwriter"
+
+	"szakszon.com/divyield"
+)
+
+func (c *Command) consolidate(ctx context.Context) error {
+	symbols, err := c.resolveSymbols(ctx, c.args)
+	if err != nil {
+		return err
+	}
+	if len(symbols) == 0 {
+		return fmt.Errorf("Symbol not found")
+	}
+
+	_, err = c.opts.db.Migrate(ctx, &divyield.DBMigrateInput{})
+	if err != nil {
+		return fmt.Errorf("migrate: %v", err)
+	}
+
+	out, err := c.opts.db.Consolidate(
+		ctx,
+		&divyield.DBConsolidateInput{
+			Symbols: symbols,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	c.writeConsolidated(out)
+	return nil
+}
+
+func (c *Command) writeConsolidated(out *divyield.DBConsolidateOutput) {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	b := &bytes.Buffer{}
+	b.WriteString("Symbol")
+	b.WriteByte('\t')
+	b.WriteString("Prices")
+	b.WriteByte('\t')
+	b.WriteString("Dividends")
+	b.WriteByte('\t')
+	b.WriteString("Splits")
+	b.WriteByte('\t')
+	fmt.Fprintln(w, b.String())
+
+	for _, v := range out.Consolidated {
+		b.Reset()
+		b.WriteString(v.Symbol)
+		b.WriteByte('\t')
+		b.WriteString(strconv.FormatInt(v.Prices, 10))
+		b.WriteByte('\t')
+		b.WriteString(strconv.FormatInt(v.Dividends, 10))
+		b.WriteByte('\t')
+		b.WriteString(strconv.FormatInt(v.Splits, 10))
+		b.WriteByte('\t')
+		fmt.Fprintln(w, b.String())
+	}
+
+	w.Flush()
+	c.writef("%s", buf.String())
+
+	for _, v := range out.Skipped {
+		c.writef("%v: schema not found, skipped", v)
+	}
+	c.writef(
+		"Consolidated %v symbols, skipped %v",
+		len(out.Consolidated),
+		len(out.Skipped),
+	)
+}
@@ -0,0 +1,283 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"szakszon.com/divyield"
+	"szakszon.com/divyield/cpi"
+	"szakszon.com/divyield/internal/csvutil"
+)
+
+func (c *Command) cpi(ctx context.Context) error {
+	if len(c.args) == 0 {
+		return fmt.Errorf("missing cpi subcommand")
+	}
+
+	switch c.args[0] {
+	case "import":
+		return c.cpiImport(ctx)
+	default:
+		return fmt.Errorf("invalid cpi subcommand: %v", c.args[0])
+	}
+}
+
+const (
+	cpiValueIndex = "index"
+	cpiValueYoY   = "yoy"
+)
+
+// cpiImport loads a monthly CPI series of a country into
+// the database. The file is either a BLS CSV export with
+// Year, Period and Value columns, or month, value rows where
+// the value is the index level or the year-over-year change
+// as a percentage, e.g. a KSH export.
+func (c *Command) cpiImport(ctx context.Context) error {
+	if len(c.args) < 3 {
+		return fmt.Errorf("usage: cpi import <country> <file> [index|yoy]")
+	}
+	country := strings.ToUpper(c.args[1])
+	if len(country) != 2 {
+		return fmt.Errorf("invalid country: %v", c.args[1])
+	}
+	file := c.args[2]
+
+	kind := cpiValueIndex
+	if len(c.args) > 3 {
+		kind = strings.ToLower(c.args[3])
+	}
+	if kind != cpiValueIndex && kind != cpiValueYoY {
+		return fmt.Errorf("invalid cpi value: %v", c.args[3])
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	series, err := readCPI(f, country, kind)
+	if err != nil {
+		return fmt.Errorf("%v: %v", file, err)
+	}
+
+	_, err = c.opts.db.Migrate(ctx, &divyield.DBMigrateInput{})
+	if err != nil {
+		return fmt.Errorf("migrate: %v", err)
+	}
+
+	_, err = c.opts.db.SaveCPI(
+		ctx,
+		&divyield.DBSaveCPIInput{
+			Series: series,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	c.writef("Imported %v months of %v CPI from %v", len(series), country, file)
+	return nil
+}
+
+func readCPI(
+	in io.Reader,
+	country string,
+	kind string,
+) ([]*divyield.CPI, error) {
+	records, err := csvutil.ReadDelimited(in, -1)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	// BLS exports: Series ID, Year, Period, Value, ...
+	yearCol, periodCol, valueCol := -1, -1, -1
+	for i, h := range records[0] {
+		switch strings.ToLower(strings.TrimSpace(h)) {
+		case "year":
+			yearCol = i
+		case "period":
+			periodCol = i
+		case "value":
+			valueCol = i
+		}
+	}
+	bls := yearCol >= 0 && periodCol >= 0 && valueCol >= 0
+
+	series := make([]*divyield.CPI, 0, len(records))
+	for i, rec := range records {
+		var month time.Time
+		var value string
+
+		if bls {
+			if i == 0 {
+				continue
+			}
+			if len(rec) <= valueCol {
+				return nil, fmt.Errorf("line %v: missing fields", i+1)
+			}
+			period := strings.TrimSpace(rec[periodCol])
+			// M13 is the annual average, S01 and S02 are half-years
+			if !strings.HasPrefix(period, "M") || period == "M13" {
+				continue
+			}
+			month, err = time.Parse(
+				"2006-M01",
+				strings.TrimSpace(rec[yearCol])+"-"+period,
+			)
+			if err != nil {
+				return nil, fmt.Errorf("line %v: parse period: %v", i+1, err)
+			}
+			value = rec[valueCol]
+		} else {
+			if len(rec) < 2 {
+				return nil, fmt.Errorf("line %v: missing fields", i+1)
+			}
+			month, err = parseMonth(strings.TrimSpace(rec[0]))
+			if err != nil {
+				if i == 0 {
+					// header
+					continue
+				}
+				return nil, fmt.Errorf("line %v: parse month: %v", i+1, err)
+			}
+			value = rec[1]
+		}
+
+		v, err := strconv.ParseFloat(
+			strings.ReplaceAll(strings.TrimSpace(value), ",", "."),
+			64,
+		)
+		if err != nil {
+			return nil, fmt.Errorf("line %v: invalid value: %v", i+1, value)
+		}
+
+		p := &divyield.CPI{
+			Country: country,
+			Month:   month,
+		}
+		if kind == cpiValueYoY {
+			p.YoY = v
+		} else {
+			if v <= 0 {
+				return nil, fmt.Errorf("line %v: invalid index: %v", i+1, value)
+			}
+			p.Index = v
+		}
+		series = append(series, p)
+	}
+	return series, nil
+}
+
+func parseMonth(s string) (time.Time, error) {
+	t, err := time.Parse(divyield.DateFormat, s)
+	if err == nil {
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC), nil
+	}
+	for _, layout := range []string{"2006-01", "2006.01", "2006.01."} {
+		t, err2 := time.Parse(layout, s)
+		if err2 == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, err
+}
+
+// loadCPI loads the yearly inflation rates of the CPI
+// country used by the real DGRs. The real DGRs are the
+// DGRs in the report currency, so no rates are loaded if
+// the currency of the CPI country is not the report
+// currency or the series is not imported.
+func (g *statsGenerator) loadCPI(ctx context.Context) error {
+	if g.cpiService == nil || !g.cpiReportCurrency() {
+		return nil
+	}
+
+	y := g.now().Year()
+	out, err := g.cpiService.Series(ctx, &divyield.CPISeriesInput{
+		Country: g.cpiCountry,
+		// the rate of the first year needs the previous year
+		Since: time.Date(y-6, time.January, 1, 0, 0, 0, 0, time.UTC),
+	})
+	if err != nil {
+		return fmt.Errorf("get %v cpi: %v", g.cpiCountry, err)
+	}
+	if len(out.Series) == 0 {
+		return nil
+	}
+	g.inflationRates = cpi.YearlyRates(out.Series)
+	return nil
+}
+
+// cpiReportCurrency returns true if the
+// CPI country uses the report currency.
+func (g *statsGenerator) cpiReportCurrency() bool {
+	if g.cpiCountry == "" || g.reportCurrency == "" {
+		return false
+	}
+	currency, ok := cpi.Currency(g.cpiCountry)
+	return ok && currency == g.reportCurrency
+}
+
+// checkCPI returns an error if the real DGR
+// filter is set but the real DGRs are unknown.
+func (g *statsGenerator) checkCPI() error {
+	if g.realDGRMin <= 0 || g.inflationRates != nil {
+		return nil
+	}
+	if g.reportCurrency == "" {
+		return fmt.Errorf("real DGRs need a report currency")
+	}
+	if !g.cpiReportCurrency() {
+		return fmt.Errorf(
+			"cpi country %v does not use the report currency %v",
+			g.cpiCountry,
+			g.reportCurrency,
+		)
+	}
+	return fmt.Errorf(
+		"no %v cpi series, run cpi import first",
+		g.cpiCountry,
+	)
+}
+
+// realDGRs deflates the DGRs in the report currency
+// by the inflation of the same years.
+func (g *statsGenerator) realDGRs(stats *divyield.Stats) {
+	if g.inflationRates == nil {
+		return
+	}
+	stats.InflationCountry = g.cpiCountry
+
+	ye := g.now().Year() - 1
+	for _, row := range stats.Rows {
+		row.RealDGRs = make(map[int]float64)
+		for _, n := range []int{1, 2, 3, 4} {
+			v, ok := cpi.RealGrowth(row.DGRsHome[n], g.inflationRates, ye, n)
+			if !ok {
+				v = math.NaN()
+			}
+			row.RealDGRs[n] = v
+		}
+	}
+}
+
+func (g *statsGenerator) filterRealDGRMin(
+	row *divyield.StatsRow,
+) bool {
+	if g.realDGRMin <= 0 {
+		return true
+	}
+
+	v := row.RealDGRs[4]
+	return !isNaN(v) && g.realDGRMin <= v
+}
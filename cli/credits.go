@@ -0,0 +1,136 @@
+package cli
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"szakszon.com/divyield"
+)
+
+// checkCreditBudget estimates the message credits of pulling
+// the symbols that are not up to date and refuses the pull
+// if the estimate exceeds the budget.
+func (c *Command) checkCreditBudget(
+	ctx context.Context,
+	symbols []string,
+	from time.Time,
+) error {
+	in := &divyield.CreditEstimateInput{}
+	for _, symbol := range symbols {
+		utd, err := c.upToDate(ctx, symbol)
+		if err != nil {
+			return fmt.Errorf(
+				"%v: check up to date: %v",
+				symbol,
+				err,
+			)
+		}
+		if utd {
+			continue
+		}
+
+		es := &divyield.CreditEstimateSymbol{
+			Symbol:        symbol,
+			FromSplits:    from,
+			FromDividends: from,
+			FromPrices:    from,
+		}
+		if !c.opts.reset {
+			es.FromSplits, err = c.adjustFromSplits(ctx, symbol, from)
+			if err != nil {
+				return fmt.Errorf("%v: %v", symbol, err)
+			}
+			es.FromDividends, err = c.adjustFromDividends(ctx, symbol, from)
+			if err != nil {
+				return fmt.Errorf("%v: %v", symbol, err)
+			}
+			es.FromPrices, err = c.adjustFromPrices(ctx, symbol, from)
+			if err != nil {
+				return fmt.Errorf("%v: %v", symbol, err)
+			}
+		}
+		in.Symbols = append(in.Symbols, es)
+		if strings.LastIndexByte(symbol, '-') != -1 {
+			in.Exchanges = true
+		}
+	}
+
+	out, err := c.opts.creditService.Estimate(ctx, in)
+	if err != nil {
+		return fmt.Errorf("estimate credits: %v", err)
+	}
+	c.writef(
+		"Estimated credits: %v (%v symbols)",
+		out.Credits,
+		len(in.Symbols),
+	)
+
+	budget := c.opts.creditBudget
+	if budget > 0 && out.Credits > budget {
+		return fmt.Errorf(
+			"estimated credits exceed the budget: %v > %v",
+			out.Credits,
+			budget,
+		)
+	}
+	return nil
+}
+
+// writeCredits saves the consumed credits to the monthly
+// total and writes the credits consumed by the endpoints.
+func (c *Command) writeCredits(ctx context.Context) {
+	_, err := c.opts.creditService.Save(
+		ctx,
+		&divyield.CreditSaveInput{},
+	)
+	if err != nil {
+		c.writef("%v", err)
+	}
+
+	out, err := c.opts.creditService.Usage(
+		ctx,
+		&divyield.CreditUsageInput{},
+	)
+	if err != nil {
+		c.writef("%v", err)
+		return
+	}
+
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 0, 2, ' ', tabwriter.AlignRight)
+
+	b := &bytes.Buffer{}
+	b.WriteString("Endpoint")
+	b.WriteByte('\t')
+	b.WriteString("Requests")
+	b.WriteByte('\t')
+	b.WriteString("Credits")
+	b.WriteByte('\t')
+	fmt.Fprintln(w, b.String())
+
+	for _, v := range out.Endpoints {
+		b.Reset()
+		b.WriteString(v.Endpoint)
+		b.WriteByte('\t')
+		b.WriteString(strconv.FormatInt(v.Requests, 10))
+		b.WriteByte('\t')
+		b.WriteString(strconv.FormatInt(v.Credits, 10))
+		b.WriteByte('\t')
+		fmt.Fprintln(w, b.String())
+	}
+
+	w.Flush()
+	c.writef("%s", buf.String())
+
+	c.writef(
+		"Credits: %v, %v: %v",
+		out.Credits,
+		out.Month,
+		out.MonthCredits,
+	)
+}
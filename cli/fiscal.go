@@ -0,0 +1,35 @@
+package cli
+
+import (
+	"strconv"
+
+	"szakszon.com/divyield/fundamentals"
+)
+
+// fiscalYearColumn returns the header of the metric of the
+// i-th latest fiscal year, e.g. RONTA2020% if the years of
+// the companies are the same, RONTA1% otherwise.
+func fiscalYearColumn(
+	years []string,
+	name string,
+	i int,
+	suffix string,
+) string {
+	if i-1 < len(years) {
+		return name + years[i-1] + suffix
+	}
+	return name + strconv.Itoa(i) + suffix
+}
+
+// fiscalYearEndStr returns the end date of
+// the latest fiscal year of the company.
+func fiscalYearEndStr(c *fundamentals.Company) string {
+	end := c.FiscalYearEnd()
+	if !end.IsZero() {
+		return end.Format("2006-01-02")
+	}
+	if len(c.FiscalYears) > 0 && c.FiscalYears[0] != "" {
+		return c.FiscalYears[0]
+	}
+	return "-"
+}
@@ -0,0 +1,109 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"szakszon.com/divyield"
+	"szakszon.com/divyield/internal/csvutil"
+)
+
+func (c *Command) fx(ctx context.Context) error {
+	if len(c.args) == 0 {
+		return fmt.Errorf("missing fx subcommand")
+	}
+
+	switch c.args[0] {
+	case "import":
+		return c.fxImport(ctx)
+	default:
+		return fmt.Errorf("invalid fx subcommand: %v", c.args[0])
+	}
+}
+
+// fxImport loads the date, from, to, rate rows of a CSV
+// file into the database. The file may start with a header
+// and its fields may be separated by comma or semicolon.
+func (c *Command) fxImport(ctx context.Context) error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("missing file")
+	}
+	file := c.args[1]
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	rates, err := readFXRates(f)
+	if err != nil {
+		return fmt.Errorf("%v: %v", file, err)
+	}
+
+	_, err = c.opts.db.Migrate(ctx, &divyield.DBMigrateInput{})
+	if err != nil {
+		return fmt.Errorf("migrate: %v", err)
+	}
+
+	_, err = c.opts.db.SaveFXRates(
+		ctx,
+		&divyield.DBSaveFXRatesInput{
+			Rates: rates,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	c.writef("Imported %v rates from %v", len(rates), file)
+	return nil
+}
+
+func readFXRates(in io.Reader) ([]*divyield.FXRate, error) {
+	records, err := csvutil.ReadDelimited(in, 4)
+	if err != nil {
+		return nil, err
+	}
+
+	rates := make([]*divyield.FXRate, 0, len(records))
+	for i, rec := range records {
+		date, err := time.Parse(divyield.DateFormat, strings.TrimSpace(rec[0]))
+		if err != nil {
+			if i == 0 {
+				// header
+				continue
+			}
+			return nil, fmt.Errorf("line %v: parse date: %v", i+1, err)
+		}
+
+		from := strings.ToUpper(strings.TrimSpace(rec[1]))
+		to := strings.ToUpper(strings.TrimSpace(rec[2]))
+		if len(from) != 3 || len(to) != 3 {
+			return nil, fmt.Errorf(
+				"line %v: invalid currency pair: %v/%v",
+				i+1,
+				from,
+				to,
+			)
+		}
+
+		rate, err := strconv.ParseFloat(strings.TrimSpace(rec[3]), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("line %v: invalid rate: %v", i+1, rec[3])
+		}
+
+		rates = append(rates, &divyield.FXRate{
+			From: from,
+			To:   to,
+			Date: date,
+			Rate: rate,
+		})
+	}
+	return rates, nil
+}
@@ -0,0 +1,112 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"szakszon.com/divyield"
+)
+
+// homeRates caches the exchange rates
+// into the report currency by currency and date.
+type homeRates struct {
+	mu    sync.Mutex
+	rates map[string]float64
+}
+
+func (r *homeRates) rate(
+	ctx context.Context,
+	srv divyield.CurrencyService,
+	from string,
+	to string,
+	date time.Time,
+) (float64, error) {
+	if strings.EqualFold(from, to) {
+		return 1, nil
+	}
+
+	k := from + " " + date.Format(divyield.DateFormat)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.rates == nil {
+		r.rates = make(map[string]float64)
+	}
+	if v, ok := r.rates[k]; ok {
+		return v, nil
+	}
+
+	out, err := srv.Convert(ctx, &divyield.CurrencyConvertInput{
+		From:   from,
+		To:     to,
+		Amount: 1,
+		Date:   date,
+	})
+	if err != nil {
+		return 0, fmt.Errorf(
+			"convert %v to %v on %v: %v",
+			from,
+			to,
+			date.Format(divyield.DateFormat),
+			err,
+		)
+	}
+	r.rates[k] = out.Rate
+	return out.Rate, nil
+}
+
+// convertHome adds the figures in the report currency
+// to the rows. The dividends are converted with the rate
+// of their ex-date, so the DGRs in the report currency
+// include the changes of the exchange rate.
+func (g *statsGenerator) convertHome(
+	ctx context.Context,
+	stats *divyield.Stats,
+) error {
+	stats.ReportCurrency = g.reportCurrency
+	today := date(g.now())
+
+	for _, row := range stats.Rows {
+		if len(row.Dividends) == 0 {
+			continue
+		}
+		row.Currency = row.Dividends[0].Currency
+
+		for _, d := range row.Dividends {
+			rate, err := g.homeRates.rate(
+				ctx,
+				g.currencyService,
+				d.Currency,
+				g.reportCurrency,
+				d.ExDate,
+			)
+			if err != nil {
+				return fmt.Errorf("%v: %v", row.Profile.Symbol, err)
+			}
+			d.AmountAdjHome = d.AmountAdj * rate
+		}
+
+		rate, err := g.homeRates.rate(
+			ctx,
+			g.currencyService,
+			row.Currency,
+			g.reportCurrency,
+			today,
+		)
+		if err != nil {
+			return fmt.Errorf("%v: %v", row.Profile.Symbol, err)
+		}
+		row.DivFwdHome = row.DivFwd * rate
+		row.DGRsHome = g.dgrsOf(
+			row.Dividends,
+			func(v *divyield.DividendChange) float64 {
+				return v.AmountAdjHome
+			},
+		)
+	}
+	return nil
+}
@@ -0,0 +1,39 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"szakszon.com/divyield"
+)
+
+func (c *Command) migrate(ctx context.Context) error {
+	in := &divyield.DBMigrateInput{}
+	if len(c.args) > 0 {
+		v, err := strconv.Atoi(c.args[0])
+		if err != nil || v < 0 {
+			return fmt.Errorf("invalid version: %v", c.args[0])
+		}
+		in.Version = v
+	}
+
+	out, err := c.opts.db.Migrate(ctx, in)
+	if err != nil {
+		return err
+	}
+
+	for _, m := range out.Applied {
+		c.writef("Applied %04d_%v", m.Version, m.Name)
+	}
+	if len(out.Applied) == 0 {
+		c.writef("Schema is up to date, version %v", out.ToVersion)
+		return nil
+	}
+	c.writef(
+		"Schema migrated from version %v to %v",
+		out.FromVersion,
+		out.ToVersion,
+	)
+	return nil
+}
@@ -0,0 +1,149 @@
+package cli
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"szakszon.com/divyield"
+	"szakszon.com/divyield/internal/csvutil"
+)
+
+func (c *Command) sp500(ctx context.Context) error {
+	if len(c.args) == 0 {
+		return fmt.Errorf("missing sp500 subcommand")
+	}
+
+	switch c.args[0] {
+	case "import":
+		return c.sp500Import(ctx)
+	case "pull":
+		return c.sp500Pull(ctx)
+	default:
+		return fmt.Errorf("invalid sp500 subcommand: %v", c.args[0])
+	}
+}
+
+// sp500Import loads the date, yield rows of a CSV file into
+// the database. The dates are normalized to the first day
+// of the month, the yields are percentages.
+func (c *Command) sp500Import(ctx context.Context) error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("missing file")
+	}
+	file := c.args[1]
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	yields, err := readSP500DividendYields(f)
+	if err != nil {
+		return fmt.Errorf("%v: %v", file, err)
+	}
+
+	_, err = c.opts.db.Migrate(ctx, &divyield.DBMigrateInput{})
+	if err != nil {
+		return fmt.Errorf("migrate: %v", err)
+	}
+
+	_, err = c.opts.db.SaveSP500DividendYields(
+		ctx,
+		&divyield.DBSaveSP500DividendYieldsInput{
+			DividendYields: yields,
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	c.writef("Imported %v months from %v", len(yields), file)
+	return nil
+}
+
+// sp500Pull refreshes the stored series from the source.
+func (c *Command) sp500Pull(ctx context.Context) error {
+	_, err := c.opts.db.Migrate(ctx, &divyield.DBMigrateInput{})
+	if err != nil {
+		return fmt.Errorf("migrate: %v", err)
+	}
+
+	out, err := c.opts.sp500Service.DividendYields(
+		ctx,
+		&divyield.SP500DividendYieldsInput{
+			Refresh: true,
+		},
+	)
+	if err != nil {
+		return err
+	}
+	if len(out.DividendYields) == 0 {
+		return fmt.Errorf("no S&P 500 dividend yields")
+	}
+
+	c.writef(
+		"Stored %v months, %v..%v",
+		len(out.DividendYields),
+		out.DividendYields[len(out.DividendYields)-1].Timestamp,
+		out.DividendYields[0].Timestamp,
+	)
+	return nil
+}
+
+func readSP500DividendYields(
+	in io.Reader,
+) ([]*divyield.SP500DividendYield, error) {
+	records, err := csvutil.ReadDelimited(in, 2)
+	if err != nil {
+		return nil, err
+	}
+
+	yields := make([]*divyield.SP500DividendYield, 0, len(records))
+	for i, rec := range records {
+		month, err := parseMonth(strings.TrimSpace(rec[0]))
+		if err != nil {
+			if i == 0 {
+				// header
+				continue
+			}
+			return nil, fmt.Errorf("line %v: parse date: %v", i+1, err)
+		}
+
+		v := strings.TrimSuffix(strings.TrimSpace(rec[1]), "%")
+		rate, err := strconv.ParseFloat(
+			strings.ReplaceAll(strings.TrimSpace(v), ",", "."),
+			64,
+		)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("line %v: invalid yield: %v", i+1, rec[1])
+		}
+
+		yields = append(yields, &divyield.SP500DividendYield{
+			Date:      month,
+			Rate:      rate,
+			Timestamp: month.Format(divyield.DateFormat),
+		})
+	}
+	return yields, nil
+}
+
+// sp500Rate returns the yield of the latest month
+// not after the date. The yields are ordered by
+// date descending.
+func sp500Rate(
+	yields []*divyield.SP500DividendYield,
+	date time.Time,
+) (float64, bool) {
+	for _, v := range yields {
+		if !v.Date.After(date) {
+			return v.Rate, true
+		}
+	}
+	return 0, false
+}
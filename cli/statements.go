@@ -0,0 +1,151 @@
+package cli
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"szakszon.com/divyield"
+	"szakszon.com/divyield/fundamentals"
+)
+
+func (c *Command) statements(ctx context.Context) error {
+	if len(c.args) == 0 {
+		return fmt.Errorf("missing statements subcommand")
+	}
+
+	switch c.args[0] {
+	case "import":
+		return c.statementsImport(ctx)
+	default:
+		return fmt.Errorf("invalid statements subcommand: %v", c.args[0])
+	}
+}
+
+// statementsImport loads the JSON files of the statements
+// written by the earlier versions of pull-valuation into
+// the database. The modification time of the income
+// statement is the pulled time of the snapshot.
+func (c *Command) statementsImport(ctx context.Context) error {
+	if len(c.args) < 2 {
+		return fmt.Errorf("missing urls file")
+	}
+
+	baseDir := c.opts.dir
+	if baseDir == "" {
+		return fmt.Errorf("dir must be specified")
+	}
+
+	symbols, err := readStatementSymbols(ctx, c.args[1])
+	if err != nil {
+		return err
+	}
+
+	_, err = c.opts.db.Migrate(ctx, &divyield.DBMigrateInput{})
+	if err != nil {
+		return fmt.Errorf("migrate: %v", err)
+	}
+
+	imported := 0
+	for _, v := range symbols {
+		dir := filepath.Join(baseDir, v.Exchange, v.Symbol)
+		fin, err := fundamentals.Load(dir)
+		if err == nil && fin != nil {
+			err = fin.Validate(v.Exchange, v.Symbol)
+		}
+		var perr *fundamentals.ParseError
+		if errors.As(err, &perr) {
+			perr.Exchange = v.Exchange
+			perr.Symbol = v.Symbol
+			c.writef("%v", perr)
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("%v: %v", v.Symbol, err)
+		}
+		if fin == nil {
+			c.writef("%v/%v: statements not found", v.Exchange, v.Symbol)
+			continue
+		}
+
+		fi, err := os.Stat(filepath.Join(dir, "is.json"))
+		if err != nil {
+			return fmt.Errorf("%v: %v", v.Symbol, err)
+		}
+
+		items := fin.Items(v.Exchange, v.Symbol, fi.ModTime())
+		_, err = c.opts.db.SaveStatementItems(
+			ctx,
+			&divyield.DBSaveStatementItemsInput{
+				Items: items,
+			},
+		)
+		if err != nil {
+			return fmt.Errorf("%v: save statements: %v", v.Symbol, err)
+		}
+		imported++
+	}
+
+	c.writef("Imported %v of %v symbols", imported, len(symbols))
+	return nil
+}
+
+// readStatementSymbols returns the exchanges and symbols
+// of the Morningstar URLs of the file.
+func readStatementSymbols(
+	ctx context.Context,
+	file string,
+) ([]*divyield.StatementSymbol, error) {
+	urls, err := readURLs(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+
+	symbols := make([]*divyield.StatementSymbol, 0, len(urls))
+	for _, u := range urls {
+		_, symbol, exch := morningstarURLValuation(u)
+		symbols = append(symbols, &divyield.StatementSymbol{
+			Exchange: exch,
+			Symbol:   symbol,
+		})
+	}
+	return symbols, nil
+}
+
+// readURLs returns the Morningstar URLs of the file,
+// the empty lines and the comments are skipped.
+func readURLs(
+	ctx context.Context,
+	file string,
+) ([]string, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	urls := make([]string, 0)
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+			// noop
+		}
+
+		u := strings.TrimSpace(scanner.Text())
+		if u == "" || strings.HasPrefix(u, "#") {
+			continue
+		}
+		urls = append(urls, u)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return urls, nil
+}
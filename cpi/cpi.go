@@ -0,0 +1,164 @@
+// Package cpi provides the monthly consumer price index series
+// stored in the database and the yearly inflation rates
+// calculated from them.
+package cpi
+
+import (
+	"context"
+	"math"
+	"strings"
+
+	"szakszon.com/divyield"
+)
+
+// InflationService is both a divyield.InflationService
+// and a divyield.CPIService.
+type InflationService struct {
+	db      divyield.DB
+	current divyield.InflationService
+}
+
+// NewInflationService returns a service that provides
+// the current inflation of the given service and
+// the CPI series stored in the database.
+func NewInflationService(
+	db divyield.DB,
+	current divyield.InflationService,
+) *InflationService {
+	return &InflationService{
+		db:      db,
+		current: current,
+	}
+}
+
+func (s *InflationService) Fetch(
+	ctx context.Context,
+	in *divyield.InflationFetchInput,
+) (*divyield.InflationFetchOutput, error) {
+	return s.current.Fetch(ctx, in)
+}
+
+func (s *InflationService) Series(
+	ctx context.Context,
+	in *divyield.CPISeriesInput,
+) (*divyield.CPISeriesOutput, error) {
+	out, err := s.db.CPI(ctx, &divyield.DBCPIInput{
+		Country: strings.ToUpper(in.Country),
+		Since:   in.Since,
+		Until:   in.Until,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.CPISeriesOutput{
+		Series: out.Series,
+	}, nil
+}
+
+// YearlyRates returns the inflation of the years as
+// a percentage. If the index levels of a year and the
+// previous year are known, the rate is the change of
+// their averages. Otherwise it is the average of the
+// monthly year-over-year changes of the year.
+func YearlyRates(series []*divyield.CPI) map[int]float64 {
+	indexSum := make(map[int]float64)
+	indexN := make(map[int]int)
+	yoySum := make(map[int]float64)
+	yoyN := make(map[int]int)
+
+	for _, v := range series {
+		y := v.Month.Year()
+		if v.Index > 0 {
+			indexSum[y] += v.Index
+			indexN[y]++
+		}
+		if v.YoY != 0 || v.Index == 0 {
+			yoySum[y] += v.YoY
+			yoyN[y]++
+		}
+	}
+
+	rates := make(map[int]float64)
+	for y, n := range indexN {
+		// partial years would distort the averages
+		if n != 12 || indexN[y-1] != 12 {
+			continue
+		}
+		avg := indexSum[y] / float64(n)
+		avgPrev := indexSum[y-1] / float64(indexN[y-1])
+		rates[y] = (avg/avgPrev - 1) * 100
+	}
+	for y, n := range yoyN {
+		if _, ok := rates[y]; ok || n != 12 {
+			continue
+		}
+		rates[y] = yoySum[y] / float64(n)
+	}
+	return rates
+}
+
+// RealGrowth deflates the nominal compound growth rate of
+// the n years ending with endYear by the inflation of the
+// same years. The rates are percentages. The second return
+// value is false if the inflation of a year is unknown.
+func RealGrowth(
+	nominal float64,
+	rates map[int]float64,
+	endYear int,
+	n int,
+) (float64, bool) {
+	if n < 1 || isNaN(nominal) {
+		return 0, false
+	}
+
+	prices := 1.0
+	for y := endYear - n + 1; y <= endYear; y++ {
+		r, ok := rates[y]
+		if !ok {
+			return 0, false
+		}
+		prices *= 1 + r/100
+	}
+	inflation := math.Pow(prices, 1/float64(n))
+
+	return ((1+nominal/100)/inflation - 1) * 100, true
+}
+
+// currencies are the currencies of the countries.
+var currencies = map[string]string{
+	"US": "USD",
+	"HU": "HUF",
+	"GB": "GBP",
+	"CA": "CAD",
+	"CH": "CHF",
+	"JP": "JPY",
+	"AU": "AUD",
+	"SE": "SEK",
+	"NO": "NOK",
+	"DK": "DKK",
+	"PL": "PLN",
+	"CZ": "CZK",
+	"EA": "EUR",
+	"EU": "EUR",
+	"DE": "EUR",
+	"FR": "EUR",
+	"IT": "EUR",
+	"ES": "EUR",
+	"NL": "EUR",
+	"BE": "EUR",
+	"AT": "EUR",
+	"FI": "EUR",
+	"IE": "EUR",
+	"PT": "EUR",
+}
+
+// Currency returns the currency of the country,
+// false if the country is unknown.
+func Currency(country string) (string, bool) {
+	v, ok := currencies[strings.ToUpper(country)]
+	return v, ok
+}
+
+func isNaN(v float64) bool {
+	return math.IsNaN(v) || math.IsInf(v, 1) || math.IsInf(v, -1)
+}
@@ -3,7 +3,10 @@ package divyield
 import (
 	"context"
 	"fmt"
+	"hash/fnv"
 	"math"
+	"sort"
+	"strconv"
 	"time"
 )
 
@@ -12,11 +15,21 @@ type Command interface {
 }
 
 type DB interface {
+	Migrate(
+		ctx context.Context,
+		in *DBMigrateInput,
+	) (*DBMigrateOutput, error)
+
 	InitSchema(
 		ctx context.Context,
 		tickers []string,
 	) error
 
+	Consolidate(
+		ctx context.Context,
+		in *DBConsolidateInput,
+	) (*DBConsolidateOutput, error)
+
 	Prices(
 		ctx context.Context,
 		ticker string,
@@ -45,6 +58,16 @@ type DB interface {
 		f *DividendYieldFilter,
 	) ([]*DividendYield, error)
 
+	LatestDividendYields(
+		ctx context.Context,
+		in *DBLatestDividendYieldsInput,
+	) (*DBLatestDividendYieldsOutput, error)
+
+	DividendsBySymbol(
+		ctx context.Context,
+		in *DBDividendsBySymbolInput,
+	) (*DBDividendsBySymbolOutput, error)
+
 	Splits(
 		ctx context.Context,
 		ticker string,
@@ -65,6 +88,83 @@ type DB interface {
 		ctx context.Context,
 		in *DBProfilesInput,
 	) (*DBProfilesOutput, error)
+
+	FXRates(
+		ctx context.Context,
+		in *DBFXRatesInput,
+	) (*DBFXRatesOutput, error)
+
+	SaveFXRates(
+		ctx context.Context,
+		in *DBSaveFXRatesInput,
+	) (*DBSaveFXRatesOutput, error)
+
+	CPI(
+		ctx context.Context,
+		in *DBCPIInput,
+	) (*DBCPIOutput, error)
+
+	SaveCPI(
+		ctx context.Context,
+		in *DBSaveCPIInput,
+	) (*DBSaveCPIOutput, error)
+
+	SP500DividendYields(
+		ctx context.Context,
+		in *DBSP500DividendYieldsInput,
+	) (*DBSP500DividendYieldsOutput, error)
+
+	SaveSP500DividendYields(
+		ctx context.Context,
+		in *DBSaveSP500DividendYieldsInput,
+	) (*DBSaveSP500DividendYieldsOutput, error)
+
+	StatementItems(
+		ctx context.Context,
+		in *DBStatementItemsInput,
+	) (*DBStatementItemsOutput, error)
+
+	SaveStatementItems(
+		ctx context.Context,
+		in *DBSaveStatementItemsInput,
+	) (*DBSaveStatementItemsOutput, error)
+
+	StatementPulls(
+		ctx context.Context,
+		in *DBStatementPullsInput,
+	) (*DBStatementPullsOutput, error)
+}
+
+type DBMigrateInput struct {
+	// Version is the target version, 0 means the latest.
+	Version int
+}
+
+type DBMigrateOutput struct {
+	FromVersion int
+	ToVersion   int
+	Applied     []*Migration
+}
+
+type Migration struct {
+	Version int
+	Name    string
+}
+
+type DBConsolidateInput struct {
+	Symbols []string
+}
+
+type DBConsolidateOutput struct {
+	Consolidated []*DBConsolidated
+	Skipped      []string
+}
+
+type DBConsolidated struct {
+	Symbol    string
+	Prices    int64
+	Dividends int64
+	Splits    int64
 }
 
 type DBSavePricesInput struct {
@@ -85,6 +185,27 @@ type DBSaveDividendsInput struct {
 type DBSaveDividendsOutput struct {
 }
 
+type DBLatestDividendYieldsInput struct {
+	Symbols []string
+
+	// Date is the date of the yields, the latest
+	// if it is zero.
+	Date time.Time
+}
+
+type DBLatestDividendYieldsOutput struct {
+	DividendYields map[string]*DividendYield
+}
+
+type DBDividendsBySymbolInput struct {
+	Symbols []string
+	Filter  *DividendFilter
+}
+
+type DBDividendsBySymbolOutput struct {
+	Dividends map[string][]*Dividend
+}
+
 type DBSaveSplitsInput struct {
 	Symbol string
 	Splits []*Split
@@ -110,6 +231,140 @@ type DBProfilesOutput struct {
 	Profiles []*Profile
 }
 
+type DBFXRatesInput struct {
+	From  string
+	To    string
+	Since time.Time
+	Until time.Time
+	Limit uint64
+}
+
+type DBFXRatesOutput struct {
+	// Rates are ordered by date descending.
+	Rates []*FXRate
+}
+
+type DBSaveFXRatesInput struct {
+	Rates []*FXRate
+}
+
+type DBSaveFXRatesOutput struct {
+}
+
+type FXRate struct {
+	From string
+	To   string
+	Date time.Time
+	Rate float64
+}
+
+type DBCPIInput struct {
+	Country string
+	Since   time.Time
+	Until   time.Time
+}
+
+type DBCPIOutput struct {
+	// Series is ordered by month ascending.
+	Series []*CPI
+}
+
+type DBSaveCPIInput struct {
+	Series []*CPI
+}
+
+type DBSaveCPIOutput struct {
+}
+
+type DBSP500DividendYieldsInput struct {
+	Since time.Time
+	Until time.Time
+	Limit uint64
+}
+
+type DBSP500DividendYieldsOutput struct {
+	// DividendYields is ordered by date descending.
+	DividendYields []*SP500DividendYield
+}
+
+type DBSaveSP500DividendYieldsInput struct {
+	DividendYields []*SP500DividendYield
+}
+
+type DBSaveSP500DividendYieldsOutput struct {
+}
+
+type DBStatementItemsInput struct {
+	// Symbols are the companies, empty means all.
+	Symbols []*StatementSymbol
+
+	// AsOf selects the latest snapshot pulled not after
+	// the time, the zero time means the latest snapshot.
+	AsOf time.Time
+}
+
+type DBStatementItemsOutput struct {
+	// Items are the items of the latest
+	// snapshot of the companies.
+	Items []*StatementItem
+}
+
+type DBSaveStatementItemsInput struct {
+	Items []*StatementItem
+}
+
+type DBSaveStatementItemsOutput struct {
+}
+
+type DBStatementPullsInput struct {
+	// Symbols are the companies, empty means all.
+	Symbols []*StatementSymbol
+}
+
+type DBStatementPullsOutput struct {
+	// Pulls are the latest snapshots of the companies,
+	// the companies without a snapshot are omitted.
+	Pulls []*StatementPull
+}
+
+// StatementPull is the time of the latest
+// snapshot of the statements of a company.
+type StatementPull struct {
+	Exchange string
+	Symbol   string
+	Pulled   time.Time
+}
+
+type StatementSymbol struct {
+	Exchange string
+	Symbol   string
+}
+
+// StatementItem is a line item of a financial statement
+// of a company. The items of a snapshot have the same
+// Pulled time.
+type StatementItem struct {
+	Exchange string
+	Symbol   string
+
+	// Statement is is, bs, cf, valuation or rt.
+	Statement string
+
+	// Period is the column of the statement,
+	// e.g. 2020, TTM or Current.
+	Period string
+
+	Label    string
+	Value    float64
+	Currency string
+
+	// FiscalYearEnd is the month and day of the
+	// end of the fiscal year, e.g. 12-31.
+	FiscalYearEnd string
+
+	Pulled time.Time
+}
+
 const DateFormat = "2006-01-02"
 
 type PriceService interface {
@@ -128,15 +383,18 @@ type PriceFetchOutput struct {
 	Prices []*Price
 }
 type Price struct {
-	Date           time.Time
-	Symbol         string
-	Close          float64
-	CloseAdjSplits float64
-	High           float64
-	Low            float64
-	Open           float64
-	Volume         float64
-	Currency       string
+	Date            time.Time
+	Symbol          string
+	Close           float64
+	CloseAdjSplits  float64
+	FactorAdjSplits float64
+	CloseAdj        float64
+	FactorAdj       float64
+	High            float64
+	Low             float64
+	Open            float64
+	Volume          float64
+	Currency        string
 }
 
 func (p *Price) String() string {
@@ -172,11 +430,16 @@ type Dividend struct {
 	ExDate      time.Time
 	Amount      float64
 	AmountAdj   float64
+	FactorAdj   float64
 	Currency    string
 	Frequency   int
 	Symbol      string
 	PaymentType string
 	Created     time.Time
+
+	// Source is the name of the provider
+	// of the dividend, empty if unknown.
+	Source string
 }
 
 func (d *Dividend) Year() int {
@@ -198,6 +461,88 @@ func (d *Dividend) AmountAdjYear() float64 {
 	return d.AmountAdj * float64(d.Frequency)
 }
 
+// SetDividendIDs sets stable IDs of the dividends of the
+// providers without IDs, e.g. files and downloads. The ID
+// hashes the symbol, the ex-date, the amount and the payment
+// type, so a regular and a special dividend of the same
+// ex-date differ. The identical dividends are numbered.
+func SetDividendIDs(symbol string, dividends []*Dividend) {
+	seen := make(map[string]int)
+	for _, d := range dividends {
+		k := symbol + " " +
+			d.ExDate.Format(DateFormat) + " " +
+			strconv.FormatFloat(d.Amount, 'f', -1, 64) + " " +
+			d.PaymentType
+		n := seen[k]
+		seen[k]++
+		if n > 0 {
+			k += " " + strconv.Itoa(n)
+		}
+
+		h := fnv.New64a()
+		h.Write([]byte(k))
+		d.ID = int64(h.Sum64() >> 1)
+	}
+}
+
+// InferFrequencies returns the number of payments per year
+// of the dividends, ordered by ex-date descending, from the
+// median of the gaps to the neighbouring ex-dates. The
+// dividends of the same ex-date share the gaps of the date.
+func InferFrequencies(dividends []*Dividend) []int {
+	frequencies := make([]int, len(dividends))
+
+	dates := make([]time.Time, 0, len(dividends))
+	dateIdx := make([]int, len(dividends))
+	for i, d := range dividends {
+		if len(dates) == 0 || !d.ExDate.Equal(dates[len(dates)-1]) {
+			dates = append(dates, d.ExDate)
+		}
+		dateIdx[i] = len(dates) - 1
+	}
+	if len(dates) < 2 {
+		return frequencies
+	}
+
+	gaps := make([]float64, len(dates)-1)
+	for i := 0; i < len(dates)-1; i++ {
+		gaps[i] = dates[i].Sub(dates[i+1]).Hours() / 24
+	}
+
+	for i := range dividends {
+		start := dateIdx[i] - 2
+		if start < 0 {
+			start = 0
+		}
+		end := dateIdx[i] + 2
+		if end > len(gaps) {
+			end = len(gaps)
+		}
+
+		near := append([]float64{}, gaps[start:end]...)
+		sort.Float64s(near)
+		frequencies[i] = gapFrequency(near[len(near)/2])
+	}
+	return frequencies
+}
+
+func gapFrequency(gapDays float64) int {
+	switch {
+	case gapDays <= 20:
+		return 24
+	case gapDays <= 45:
+		return 12
+	case gapDays <= 135:
+		return 4
+	case gapDays <= 250:
+		return 2
+	case gapDays <= 500:
+		return 1
+	default:
+		return 0
+	}
+}
+
 type DividendFilter struct {
 	From     time.Time
 	Limit    uint64
@@ -235,6 +580,7 @@ func (y *DividendYield) TrailingTTM() float64 {
 
 type DividendYieldFilter struct {
 	From  time.Time
+	To    time.Time
 	Limit uint64
 }
 
@@ -262,6 +608,10 @@ type Split struct {
 	ExDate     time.Time
 	ToFactor   float64
 	FromFactor float64
+
+	// Source is the name of the provider
+	// of the split, empty if unknown.
+	Source string
 }
 
 func (s *Split) String() string {
@@ -371,8 +721,82 @@ type Exchange struct {
 	Description string
 }
 
+// CreditService accounts the message credits
+// consumed by a metered data provider.
+type CreditService interface {
+	Estimate(
+		ctx context.Context,
+		in *CreditEstimateInput,
+	) (*CreditEstimateOutput, error)
+	Usage(
+		ctx context.Context,
+		in *CreditUsageInput,
+	) (*CreditUsageOutput, error)
+	Save(
+		ctx context.Context,
+		in *CreditSaveInput,
+	) (*CreditSaveOutput, error)
+}
+
+type CreditEstimateInput struct {
+	Symbols []*CreditEstimateSymbol
+
+	// Exchanges is true if the exchanges are fetched.
+	Exchanges bool
+}
+
+type CreditEstimateSymbol struct {
+	Symbol        string
+	FromSplits    time.Time
+	FromDividends time.Time
+	FromPrices    time.Time
+}
+
+type CreditEstimateOutput struct {
+	Credits int64
+}
+
+type CreditUsageInput struct {
+}
+
+type CreditUsageOutput struct {
+	// Endpoints are the credits consumed
+	// by the endpoints in this run.
+	Endpoints []*CreditUsage
+
+	// Credits is the total of this run.
+	Credits int64
+
+	// Month is the billing month, format 2006-01.
+	Month string
+
+	// MonthCredits is the total of the month
+	// including the credits of this run.
+	MonthCredits int64
+}
+
+type CreditUsage struct {
+	Endpoint string
+	Requests int64
+	Credits  int64
+}
+
+type CreditSaveInput struct {
+}
+
+type CreditSaveOutput struct {
+}
+
 type Stats struct {
 	Rows []*StatsRow
+
+	// ReportCurrency is the currency of the Home figures
+	// of the rows, empty if they are not calculated.
+	ReportCurrency string
+
+	// InflationCountry is the country of the CPI series
+	// of the RealDGRs, empty if they are not calculated.
+	InflationCountry string
 }
 
 type StatsRow struct {
@@ -381,15 +805,22 @@ type StatsRow struct {
 	DivYieldFwd          float64
 	DivFwd               float64
 	GordonGrowthRate     float64
+	FairPriceDDM         float64
+	MarginOfSafetyDDM    float64
 	Dividends            []*DividendChange
 	DividendChangeMR     float64
 	DividendChangeMRDate time.Time
 	DGRs                 map[int]float64
+	Currency             string
+	DivFwdHome           float64
+	DGRsHome             map[int]float64
+	RealDGRs             map[int]float64
 }
 
 type DividendChange struct {
 	*Dividend
-	Change float64
+	Change        float64
+	AmountAdjHome float64
 }
 
 type InflationService interface {
@@ -410,20 +841,74 @@ type Inflation struct {
 	Period string
 }
 
+type CPIService interface {
+	Series(
+		ctx context.Context,
+		in *CPISeriesInput,
+	) (*CPISeriesOutput, error)
+}
+
+type CPISeriesInput struct {
+	Country string
+	Since   time.Time
+	Until   time.Time
+}
+
+type CPISeriesOutput struct {
+	Series []*CPI
+}
+
+// CPI is the consumer price index of a month.
+// Either Index or YoY is set depending on the source.
+type CPI struct {
+	Country string
+	Month   time.Time
+	Index   float64
+
+	// YoY is the change to the same month
+	// of the previous year as a percentage.
+	YoY float64
+}
+
 type SP500Service interface {
 	DividendYield(
 		ctx context.Context,
 		in *SP500DividendYieldInput,
 	) (*SP500DividendYieldOutput, error)
+
+	DividendYields(
+		ctx context.Context,
+		in *SP500DividendYieldsInput,
+	) (*SP500DividendYieldsOutput, error)
 }
 
-type SP500DividendYieldInput struct{}
+type SP500DividendYieldInput struct {
+	// Date is the date of the yield,
+	// the current yield if it is zero.
+	Date time.Time
+}
 
 type SP500DividendYieldOutput struct {
 	SP500DividendYield SP500DividendYield
 }
 
+type SP500DividendYieldsInput struct {
+	Since time.Time
+	Until time.Time
+
+	// Refresh fetches the series from the source
+	// even if it is already stored.
+	Refresh bool
+}
+
+type SP500DividendYieldsOutput struct {
+	// DividendYields is the monthly series
+	// ordered by date descending.
+	DividendYields []*SP500DividendYield
+}
+
 type SP500DividendYield struct {
+	Date      time.Time
 	Rate      float64
 	Timestamp string
 }
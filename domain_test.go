@@ -0,0 +1,159 @@
+package divyield
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func dividends(exDates ...string) []*Dividend {
+	dividends := make([]*Dividend, 0, len(exDates))
+	for _, v := range exDates {
+		t, err := time.Parse(DateFormat, v)
+		if err != nil {
+			panic(err)
+		}
+		dividends = append(dividends, &Dividend{ExDate: t, Amount: 1})
+	}
+	return dividends
+}
+
+func TestGapFrequency(t *testing.T) {
+	tests := []struct {
+		gapDays float64
+		want    int
+	}{
+		{0, 24},
+		{14, 24},
+		{20, 24},
+		{21, 12},
+		{30, 12},
+		{45, 12},
+		{46, 4},
+		{91, 4},
+		{135, 4},
+		{136, 2},
+		{182, 2},
+		{250, 2},
+		{251, 1},
+		{365, 1},
+		{500, 1},
+		{501, 0},
+		{1000, 0},
+	}
+	for _, tt := range tests {
+		if got := gapFrequency(tt.gapDays); got != tt.want {
+			t.Errorf("gapFrequency(%v) = %v, want %v", tt.gapDays, got, tt.want)
+		}
+	}
+}
+
+func TestInferFrequencies(t *testing.T) {
+	tests := []struct {
+		name      string
+		dividends []*Dividend
+		want      []int
+	}{
+		{
+			name:      "empty",
+			dividends: dividends(),
+			want:      []int{},
+		},
+		{
+			name:      "single",
+			dividends: dividends("2021-03-01"),
+			want:      []int{0},
+		},
+		{
+			name: "monthly",
+			dividends: dividends(
+				"2021-04-01", "2021-03-01", "2021-02-01", "2021-01-01",
+			),
+			want: []int{12, 12, 12, 12},
+		},
+		{
+			name: "quarterly",
+			dividends: dividends(
+				"2021-10-01", "2021-07-01", "2021-04-01", "2021-01-01",
+			),
+			want: []int{4, 4, 4, 4},
+		},
+		{
+			name: "semi-annual",
+			dividends: dividends(
+				"2022-01-01", "2021-07-01", "2021-01-01",
+			),
+			want: []int{2, 2, 2},
+		},
+		{
+			name: "annual",
+			dividends: dividends(
+				"2022-05-01", "2021-05-01", "2020-05-01",
+			),
+			want: []int{1, 1, 1},
+		},
+		{
+			name: "quarterly to monthly",
+			dividends: dividends(
+				"2021-09-01", "2021-08-01", "2021-07-01", "2021-06-01",
+				"2021-03-01", "2020-12-01", "2020-09-01",
+			),
+			want: []int{12, 12, 12, 4, 4, 4, 4},
+		},
+		{
+			name: "same ex-date",
+			dividends: dividends(
+				"2021-10-01", "2021-07-01", "2021-07-01",
+				"2021-04-01", "2021-01-01",
+			),
+			want: []int{4, 4, 4, 4, 4},
+		},
+		{
+			name: "same ex-date only",
+			dividends: dividends(
+				"2021-07-01", "2021-07-01",
+			),
+			want: []int{0, 0},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := InferFrequencies(tt.dividends)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetDividendIDs(t *testing.T) {
+	ds := dividends("2021-07-01", "2021-07-01", "2021-07-01", "2021-04-01")
+	ds[1].PaymentType = "Special"
+	SetDividendIDs("AAPL", ds)
+
+	seen := make(map[int64]bool)
+	for _, d := range ds {
+		if d.ID <= 0 {
+			t.Errorf("%v: got ID %v, want positive", d, d.ID)
+		}
+		if seen[d.ID] {
+			t.Errorf("%v: duplicate ID", d)
+		}
+		seen[d.ID] = true
+	}
+
+	again := dividends("2021-07-01", "2021-07-01", "2021-07-01", "2021-04-01")
+	again[1].PaymentType = "Special"
+	SetDividendIDs("AAPL", again)
+	for i := range ds {
+		if ds[i].ID != again[i].ID {
+			t.Errorf("%v: got ID %v, want stable %v", again[i], again[i].ID, ds[i].ID)
+		}
+	}
+
+	other := dividends("2021-07-01")
+	SetDividendIDs("MSFT", other)
+	if other[0].ID == ds[0].ID {
+		t.Errorf("got the same ID for another symbol")
+	}
+}
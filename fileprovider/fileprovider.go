@@ -0,0 +1,435 @@
+// Package fileprovider reads the profiles, prices, dividends
+// and splits of the symbols from CSV files, e.g. data bought
+// from other vendors or exported from brokers.
+//
+// The files of a symbol are in its own directory:
+//
+//	<dir>/<SYMBOL>/profile.csv    name, exchange, sector, industry, ...
+//	<dir>/<SYMBOL>/prices.csv     date, close, open, high, low, volume
+//	<dir>/<SYMBOL>/dividends.csv  ex_date, amount, currency, frequency, payment_type
+//	<dir>/<SYMBOL>/splits.csv     ex_date, to_factor, from_factor or ex_date, ratio
+//	<dir>/exchanges.csv           suffix, currency, region, exchange, description
+//
+// The first line of a file is the header, the columns are
+// matched by name and the optional columns may be missing.
+// The fields may be separated by comma or semicolon.
+// Only the prices of a symbol are required, the name of
+// a symbol without a profile is the symbol itself. The
+// missing dividend frequencies are inferred from the ex-dates.
+// The currency of the prices is the currency of the exchange of
+// the symbol suffix in exchanges.csv, USD without a suffix, the
+// dividends without a currency are in the currency of the prices.
+// Parquet files are not supported.
+package fileprovider
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"szakszon.com/divyield"
+	"szakszon.com/divyield/internal/csvutil"
+)
+
+type Files struct {
+	dir string
+}
+
+func NewFiles(dir string) *Files {
+	return &Files{
+		dir: dir,
+	}
+}
+
+func (f *Files) path(symbol, name string) string {
+	return filepath.Join(f.dir, strings.ToUpper(symbol), name)
+}
+
+// read returns the rows of the file as maps keyed by the
+// lower case column names of the header. A missing file
+// has no rows if it is optional.
+func (f *Files) read(
+	p string,
+	required bool,
+) ([]map[string]string, error) {
+	file, err := os.Open(p)
+	if err != nil {
+		if !required && errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	defer file.Close()
+
+	rows, err := readRows(file)
+	if err != nil {
+		return nil, fmt.Errorf("%v: %v", p, err)
+	}
+	return rows, nil
+}
+
+func readRows(in io.Reader) ([]map[string]string, error) {
+	records, err := csvutil.ReadDelimited(in, -1)
+	if err != nil {
+		return nil, err
+	}
+	if len(records) == 0 {
+		return nil, nil
+	}
+
+	header := make([]string, len(records[0]))
+	for i, h := range records[0] {
+		h = strings.ToLower(strings.TrimSpace(h))
+		header[i] = strings.NewReplacer(" ", "_", "-", "_").Replace(h)
+	}
+
+	rows := make([]map[string]string, 0, len(records)-1)
+	for _, rec := range records[1:] {
+		row := make(map[string]string, len(header))
+		for i, v := range rec {
+			if i < len(header) {
+				row[header[i]] = strings.TrimSpace(v)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parseDate(row map[string]string, keys ...string) (time.Time, error) {
+	for _, k := range keys {
+		if v, ok := row[k]; ok && v != "" {
+			return time.Parse(divyield.DateFormat, v)
+		}
+	}
+	return time.Time{}, fmt.Errorf("missing %v", keys[0])
+}
+
+// parseFloat returns zero for the missing optional values.
+func parseFloat(
+	row map[string]string,
+	key string,
+	required bool,
+) (float64, error) {
+	v, ok := row[key]
+	if !ok || v == "" {
+		if required {
+			return 0, fmt.Errorf("missing %v", key)
+		}
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(strings.ReplaceAll(v, ",", "."), 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %v: %v", key, v)
+	}
+	return f, nil
+}
+
+func (f *Files) NewProfileService() divyield.ProfileService {
+	return &profileService{
+		Files: f,
+	}
+}
+
+type profileService struct {
+	*Files
+}
+
+func (s *profileService) Fetch(
+	ctx context.Context,
+	in *divyield.ProfileFetchInput,
+) (*divyield.ProfileFetchOutput, error) {
+	rows, err := s.read(s.path(in.Symbol, "profile.csv"), false)
+	if err != nil {
+		return nil, err
+	}
+	row := map[string]string{}
+	if len(rows) > 0 {
+		row = rows[0]
+	}
+
+	sic := 0
+	if v := row["primary_sic_code"]; v != "" {
+		sic, err = strconv.Atoi(v)
+		if err != nil {
+			return nil, fmt.Errorf("invalid primary_sic_code: %v", v)
+		}
+	}
+
+	name := row["name"]
+	if name == "" {
+		name = in.Symbol
+	}
+
+	return &divyield.ProfileFetchOutput{
+		Profile: &divyield.Profile{
+			Symbol:         in.Symbol,
+			Name:           name,
+			Exchange:       row["exchange"],
+			IssueType:      row["issue_type"],
+			Industry:       row["industry"],
+			Sector:         row["sector"],
+			Description:    row["description"],
+			Website:        row["website"],
+			PrimarySicCode: sic,
+			Address:        row["address"],
+			City:           row["city"],
+			Zip:            row["zip"],
+			State:          row["state"],
+			Country:        row["country"],
+			Phone:          row["phone"],
+		},
+	}, nil
+}
+
+func (f *Files) NewPriceService() divyield.PriceService {
+	return &priceService{
+		Files: f,
+	}
+}
+
+type priceService struct {
+	*Files
+}
+
+func (s *priceService) Fetch(
+	ctx context.Context,
+	in *divyield.PriceFetchInput,
+) (*divyield.PriceFetchOutput, error) {
+	p := s.path(in.Symbol, "prices.csv")
+	rows, err := s.read(p, true)
+	if err != nil {
+		return nil, err
+	}
+
+	prices := make([]*divyield.Price, 0, len(rows))
+	for i, row := range rows {
+		v := &divyield.Price{
+			Symbol: in.Symbol,
+		}
+		v.Date, err = parseDate(row, "date")
+		if err == nil && v.Date.Before(in.From) {
+			continue
+		}
+		if err == nil {
+			v.Close, err = parseFloat(row, "close", true)
+		}
+		if err == nil {
+			v.Open, err = parseFloat(row, "open", false)
+		}
+		if err == nil {
+			v.High, err = parseFloat(row, "high", false)
+		}
+		if err == nil {
+			v.Low, err = parseFloat(row, "low", false)
+		}
+		if err == nil {
+			v.Volume, err = parseFloat(row, "volume", false)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: line %v: %v", p, i+2, err)
+		}
+		prices = append(prices, v)
+	}
+
+	sort.SliceStable(prices, func(i, j int) bool {
+		return prices[i].Date.After(prices[j].Date)
+	})
+	return &divyield.PriceFetchOutput{
+		Prices: prices,
+	}, nil
+}
+
+func (f *Files) NewDividendService() divyield.DividendService {
+	return &dividendService{
+		Files: f,
+	}
+}
+
+type dividendService struct {
+	*Files
+}
+
+func (s *dividendService) Fetch(
+	ctx context.Context,
+	in *divyield.DividendFetchInput,
+) (*divyield.DividendFetchOutput, error) {
+	p := s.path(in.Symbol, "dividends.csv")
+	rows, err := s.read(p, false)
+	if err != nil {
+		return nil, err
+	}
+
+	dividends := make([]*divyield.Dividend, 0, len(rows))
+	inferred := false
+	for i, row := range rows {
+		v := &divyield.Dividend{
+			Symbol:      in.Symbol,
+			Currency:    strings.ToUpper(row["currency"]),
+			PaymentType: row["payment_type"],
+		}
+		if v.PaymentType == "" {
+			v.PaymentType = "Cash"
+		}
+
+		v.ExDate, err = parseDate(row, "ex_date", "date")
+		if err == nil {
+			v.Amount, err = parseFloat(row, "amount", true)
+		}
+		if err == nil {
+			var freq float64
+			freq, err = parseFloat(row, "frequency", false)
+			v.Frequency = int(freq)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: line %v: %v", p, i+2, err)
+		}
+		if row["frequency"] == "" {
+			// inferred from all the dividends below
+			v.Frequency = -1
+			inferred = true
+		}
+		dividends = append(dividends, v)
+	}
+
+	sort.SliceStable(dividends, func(i, j int) bool {
+		return dividends[i].ExDate.After(dividends[j].ExDate)
+	})
+	divyield.SetDividendIDs(in.Symbol, dividends)
+
+	var frequencies []int
+	if inferred {
+		frequencies = divyield.InferFrequencies(dividends)
+	}
+
+	out := &divyield.DividendFetchOutput{
+		Dividends: make([]*divyield.Dividend, 0, len(dividends)),
+	}
+	for i, v := range dividends {
+		if v.Frequency < 0 {
+			v.Frequency = frequencies[i]
+		}
+		if v.ExDate.Before(in.From) {
+			continue
+		}
+		out.Dividends = append(out.Dividends, v)
+	}
+	return out, nil
+}
+
+func (f *Files) NewSplitService() divyield.SplitService {
+	return &splitService{
+		Files: f,
+	}
+}
+
+type splitService struct {
+	*Files
+}
+
+func (s *splitService) Fetch(
+	ctx context.Context,
+	in *divyield.SplitFetchInput,
+) (*divyield.SplitFetchOutput, error) {
+	p := s.path(in.Symbol, "splits.csv")
+	rows, err := s.read(p, false)
+	if err != nil {
+		return nil, err
+	}
+
+	splits := make([]*divyield.Split, 0, len(rows))
+	for i, row := range rows {
+		v := &divyield.Split{}
+		v.ExDate, err = parseDate(row, "ex_date", "date")
+		if err == nil && v.ExDate.Before(in.From) {
+			continue
+		}
+		if err == nil {
+			v.ToFactor, v.FromFactor, err = parseSplitFactors(row)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%v: line %v: %v", p, i+2, err)
+		}
+		splits = append(splits, v)
+	}
+
+	sort.SliceStable(splits, func(i, j int) bool {
+		return splits[i].ExDate.After(splits[j].ExDate)
+	})
+	return &divyield.SplitFetchOutput{
+		Splits: splits,
+	}, nil
+}
+
+// parseSplitFactors parses the to_factor and from_factor
+// columns or the ratio column, e.g. 4:1 or 4/1.
+func parseSplitFactors(row map[string]string) (float64, float64, error) {
+	var to, from float64
+	var err error
+	if ratio, ok := row["ratio"]; ok {
+		parts := strings.FieldsFunc(ratio, func(r rune) bool {
+			return r == ':' || r == '/'
+		})
+		if len(parts) != 2 {
+			return 0, 0, fmt.Errorf("invalid ratio: %v", ratio)
+		}
+		row = map[string]string{
+			"to_factor":   parts[0],
+			"from_factor": parts[1],
+		}
+	}
+
+	to, err = parseFloat(row, "to_factor", true)
+	if err != nil {
+		return 0, 0, err
+	}
+	from, err = parseFloat(row, "from_factor", true)
+	if err != nil {
+		return 0, 0, err
+	}
+	if to <= 0 || from <= 0 {
+		return 0, 0, fmt.Errorf("invalid factors: %v/%v", to, from)
+	}
+	return to, from, nil
+}
+
+func (f *Files) NewExchangeService() divyield.ExchangeService {
+	return &exchangeService{
+		Files: f,
+	}
+}
+
+type exchangeService struct {
+	*Files
+}
+
+func (s *exchangeService) Fetch(
+	ctx context.Context,
+	in *divyield.ExchangeFetchInput,
+) (*divyield.ExchangeFetchOutput, error) {
+	rows, err := s.read(filepath.Join(s.dir, "exchanges.csv"), false)
+	if err != nil {
+		return nil, err
+	}
+
+	exchanges := make([]*divyield.Exchange, 0, len(rows))
+	for _, row := range rows {
+		exchanges = append(exchanges, &divyield.Exchange{
+			Region:      strings.ToUpper(row["region"]),
+			Exchange:    row["exchange"],
+			Suffix:      strings.ToUpper(row["suffix"]),
+			Currency:    strings.ToUpper(row["currency"]),
+			Description: row["description"],
+		})
+	}
+	return &divyield.ExchangeFetchOutput{
+		Exchanges: exchanges,
+	}, nil
+}
@@ -0,0 +1,431 @@
+package fundamentals
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Filter is the definition of a filter of the companies.
+// The filter has a parameter, a threshold or the number
+// of the latest fiscal years checked.
+type Filter struct {
+	// Name is the name of the filter in the -filter flag.
+	Name string
+
+	// Description describes the filter, n is the parameter.
+	Description string
+
+	// Default is the default value of the parameter.
+	Default float64
+
+	// Years is true if the parameter is
+	// the number of the latest fiscal years.
+	Years bool
+
+	Match func(c *Company, param float64) bool
+}
+
+var filters = []*Filter{
+	{
+		Name:        "ronta-positive",
+		Description: "RONTA of the n latest fiscal years positive",
+		Default:     3,
+		Years:       true,
+		Match: func(c *Company, n float64) bool {
+			return positive(c.Series("RONTA"), int(n))
+		},
+	},
+	{
+		Name:        "rps-growing",
+		Description: "RPS growing from the n-th latest fiscal year to TTM",
+		Default:     3,
+		Years:       true,
+		Match: func(c *Company, n float64) bool {
+			return growing(c.Series("RPS"), int(n))
+		},
+	},
+	{
+		Name:        "rev-growing",
+		Description: "Rev TTM above the n-th latest fiscal year, all positive",
+		Default:     3,
+		Years:       true,
+		Match: func(c *Company, n float64) bool {
+			s := c.Series("Rev")
+			return s.TTM > s.At(int(n)-1) &&
+				positive(s, int(n))
+		},
+	},
+	{
+		Name:        "e-growing",
+		Description: "E growing from the n-th latest fiscal year to TTM",
+		Default:     3,
+		Years:       true,
+		Match: func(c *Company, n float64) bool {
+			return growing(c.Series("E"), int(n))
+		},
+	},
+	{
+		Name:        "eps-growing",
+		Description: "EPS growing from the n-th latest fiscal year to TTM",
+		Default:     3,
+		Years:       true,
+		Match: func(c *Company, n float64) bool {
+			return growing(c.Series("EPS"), int(n))
+		},
+	},
+	{
+		Name:        "fcf-positive",
+		Description: "FCF TTM and of the n latest fiscal years positive",
+		Default:     3,
+		Years:       true,
+		Match: func(c *Company, n float64) bool {
+			s := c.Series("FCF")
+			return s.TTM > 0 &&
+				positive(s, int(n))
+		},
+	},
+	{
+		Name:        "fcfps-growing",
+		Description: "FCFPS TTM above the n-th latest fiscal year, all positive",
+		Default:     3,
+		Years:       true,
+		Match: func(c *Company, n float64) bool {
+			s := c.Series("FCFPS")
+			return s.TTM > s.At(int(n)-1) &&
+				s.TTM > 0 &&
+				positive(s, int(n))
+		},
+	},
+	{
+		Name:        "bv-growing",
+		Description: "BV of the latest fiscal year above the n-th, all positive",
+		Default:     3,
+		Years:       true,
+		Match: func(c *Company, n float64) bool {
+			s := c.Series("BV")
+			return s.At(0) > s.At(int(n)-1) &&
+				positive(s, int(n))
+		},
+	},
+	{
+		Name:        "bvps-positive",
+		Description: "BVPS of the n latest fiscal years positive",
+		Default:     3,
+		Years:       true,
+		Match: func(c *Company, n float64) bool {
+			return positive(c.Series("BVPS"), int(n))
+		},
+	},
+	{
+		Name:        "debt-equity-max",
+		Description: "Debt/Equ of the latest fiscal year at most n",
+		Default:     0.33,
+		Match: func(c *Company, max float64) bool {
+			return c.Series("DebtToEqu").At(0) <= max
+		},
+	},
+	{
+		Name:        "cap-rate-min",
+		Description: "Cap rate at least n%",
+		Default:     10,
+		Match: func(c *Company, min float64) bool {
+			return c.Value(lookupMetric("CapRate")) >= min
+		},
+	},
+	{
+		Name:        "dcf-mos-min",
+		Description: "DCF margin of safety at least n%",
+		Default:     25,
+		Match: func(c *Company, min float64) bool {
+			return c.Value(lookupMetric("DCFMoS")) >= min
+		},
+	},
+	{
+		Name:        "graham-mos-min",
+		Description: "Graham number margin of safety at least n%",
+		Default:     25,
+		Match: func(c *Company, min float64) bool {
+			return c.Value(lookupMetric("GrahamMoS")) >= min
+		},
+	},
+	{
+		Name:        "fscore-min",
+		Description: "Piotroski F-score at least n",
+		Default:     7,
+		Match: func(c *Company, min float64) bool {
+			return c.Value(lookupMetric("FScore")) >= min
+		},
+	},
+	{
+		Name:        "zscore-min",
+		Description: "Altman Z-score at least n",
+		Default:     2.99,
+		Match: func(c *Company, min float64) bool {
+			return c.Value(lookupMetric("ZScore")) >= min
+		},
+	},
+	{
+		Name:        "eff-max",
+		Description: "Eff of the latest fiscal year at most n%",
+		Default:     60,
+		Match: func(c *Company, max float64) bool {
+			return c.Series("Eff").At(0) <= max
+		},
+	},
+	{
+		Name:        "eff-improving",
+		Description: "Eff of the latest fiscal year below the n-th, all positive",
+		Default:     3,
+		Years:       true,
+		Match: func(c *Company, n float64) bool {
+			s := c.Series("Eff")
+			return s.At(0) < s.At(int(n)-1) &&
+				positive(s, int(n))
+		},
+	},
+	{
+		Name:        "nim-min",
+		Description: "NIM of the latest fiscal year at least n%",
+		Default:     3,
+		Match: func(c *Company, min float64) bool {
+			return c.Series("NIM").At(0) >= min
+		},
+	},
+	{
+		Name:        "loans-deposits-max",
+		Description: "Loans/Dep of the latest fiscal year at most n%",
+		Default:     90,
+		Match: func(c *Company, max float64) bool {
+			return c.Series("LoansToDep").At(0) <= max
+		},
+	},
+	{
+		Name:        "equity-assets-min",
+		Description: "Equ/Assets of the latest fiscal year at least n%",
+		Default:     8,
+		Match: func(c *Company, min float64) bool {
+			return c.Series("EquToAssets").At(0) >= min
+		},
+	},
+	{
+		Name:        "roa-min",
+		Description: "ROA of the latest fiscal year at least n%",
+		Default:     1,
+		Match: func(c *Company, min float64) bool {
+			return c.Series("ROA").At(0) >= min
+		},
+	},
+	{
+		Name:        "payout-max",
+		Description: "Payout of the latest fiscal year at most n%",
+		Default:     60,
+		Match: func(c *Company, max float64) bool {
+			return c.Series("Payout").At(0) <= max
+		},
+	},
+}
+
+// Filters returns the definitions of the filters.
+func Filters() []*Filter {
+	return filters
+}
+
+// Criterion is a filter with its parameter.
+type Criterion struct {
+	*Filter
+	Param float64
+}
+
+// Match returns true if the company passes the filter.
+func (c *Criterion) Match(co *Company) bool {
+	return c.Filter.Match(co, c.Param)
+}
+
+// FiscalYears returns the number of the
+// fiscal years the criterion needs.
+func (c *Criterion) FiscalYears() int {
+	if !c.Years {
+		return 0
+	}
+	return int(c.Param)
+}
+
+// ParseFilters parses the filters of the specs, a spec
+// is the name of the filter with an optional parameter,
+// e.g. debt-equity-max=0.5.
+func ParseFilters(specs []string) ([]*Criterion, error) {
+	res := make([]*Criterion, 0, len(specs))
+	for _, spec := range specs {
+		spec = strings.TrimSpace(spec)
+		if spec == "" {
+			continue
+		}
+
+		parts := strings.SplitN(spec, "=", 2)
+		name := parts[0]
+		f := lookupFilter(name)
+		if f == nil {
+			return nil, fmt.Errorf("unknown filter: %v", name)
+		}
+
+		c := &Criterion{Filter: f, Param: f.Default}
+		if len(parts) == 2 {
+			v, err := strconv.ParseFloat(parts[1], 64)
+			if err != nil {
+				return nil, fmt.Errorf("filter %v: %v", spec, err)
+			}
+			if f.Years && (v < 1 || v != math.Trunc(v)) {
+				return nil, fmt.Errorf(
+					"filter %v: invalid number of years",
+					spec,
+				)
+			}
+			c.Param = v
+		}
+		res = append(res, c)
+	}
+	return res, nil
+}
+
+func lookupFilter(name string) *Filter {
+	for _, f := range filters {
+		if normalizeName(f.Name) == normalizeName(name) {
+			return f
+		}
+	}
+	return nil
+}
+
+// SortKey is the value the companies are sorted by.
+type SortKey struct {
+	Name  string
+	Desc  bool
+	Value func(c *Company) float64
+}
+
+// sortKeys are the sort keys besides the metrics.
+var sortKeys = []*SortKey{
+	{
+		Name: "MCap",
+		Value: func(c *Company) float64 {
+			return c.Realtime.MarketCap
+		},
+	},
+	{
+		Name: "PE",
+		Value: func(c *Company) float64 {
+			return c.Valuation.PriceToEarnings(periodCurrent)
+		},
+	},
+}
+
+// SortKeys returns the names of the sort keys.
+func SortKeys() []string {
+	names := make([]string, 0, len(sortKeys)+len(metrics))
+	for _, k := range sortKeys {
+		names = append(names, k.Name)
+	}
+	for _, m := range metrics {
+		names = append(names, m.Name)
+	}
+	return names
+}
+
+// ParseSortKey parses the sort key of the spec, a spec is
+// the name of a metric, MCap or PE with an optional order,
+// e.g. cap-rate:desc. The order is descending by default.
+func ParseSortKey(spec string) (*SortKey, error) {
+	parts := strings.SplitN(strings.TrimSpace(spec), ":", 2)
+	name := parts[0]
+	order := ""
+	if len(parts) == 2 {
+		order = parts[1]
+	}
+
+	k := &SortKey{Desc: true}
+	switch strings.ToLower(order) {
+	case "", "desc":
+	case "asc":
+		k.Desc = false
+	default:
+		return nil, fmt.Errorf("invalid sort order: %v", order)
+	}
+
+	for _, v := range sortKeys {
+		if normalizeName(v.Name) == normalizeName(name) {
+			k.Name = v.Name
+			k.Value = v.Value
+			return k, nil
+		}
+	}
+
+	m := lookupMetric(name)
+	if m == nil {
+		return nil, fmt.Errorf("unknown sort key: %v", name)
+	}
+	k.Name = m.Name
+	k.Value = func(c *Company) float64 {
+		return c.Value(m)
+	}
+	return k, nil
+}
+
+// Sort sorts the companies by the key,
+// the companies without a value are the last.
+func Sort(companies []*Company, k *SortKey) {
+	values := make(map[*Company]float64, len(companies))
+	for _, c := range companies {
+		values[c] = k.Value(c)
+	}
+
+	sort.SliceStable(
+		companies,
+		func(i, j int) bool {
+			v0 := values[companies[i]]
+			v1 := values[companies[j]]
+			if math.IsNaN(v0) || math.IsNaN(v1) {
+				return !math.IsNaN(v0) && math.IsNaN(v1)
+			}
+			if k.Desc {
+				return v0 > v1
+			}
+			return v0 < v1
+		},
+	)
+}
+
+// positive returns true if the values of
+// the n latest fiscal years are positive.
+func positive(s *Series, n int) bool {
+	for i := 0; i < n; i++ {
+		if !(s.At(i) > 0) {
+			return false
+		}
+	}
+	return n > 0
+}
+
+// growing returns true if the values are growing from
+// the n-th latest fiscal year to TTM and are positive.
+func growing(s *Series, n int) bool {
+	prev := s.TTM
+	for i := 0; i < n; i++ {
+		if !(prev > s.At(i)) {
+			return false
+		}
+		prev = s.At(i)
+	}
+	return positive(s, n)
+}
+
+// normalizeName returns the name in lower case
+// without dashes and underscores, so CapRate,
+// cap-rate and cap_rate are the same.
+func normalizeName(name string) string {
+	name = strings.ToLower(strings.TrimSpace(name))
+	name = strings.ReplaceAll(name, "-", "")
+	return strings.ReplaceAll(name, "_", "")
+}
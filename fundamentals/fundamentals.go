@@ -0,0 +1,418 @@
+// Package fundamentals calculates the metrics of the
+// Morningstar financial statements of the companies
+// for their latest fiscal years.
+package fundamentals
+
+import (
+	"encoding/json"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"szakszon.com/divyield/intrinsic"
+)
+
+// Financials are the statements of a company.
+type Financials struct {
+	IncomeStatement *Statement
+	BalanceSheet    *Statement
+	CashFlow        *Statement
+	Valuation       *Valuation
+	Realtime        *Realtime
+}
+
+// Load reads the statements of the directory.
+// It returns nil if a statement is missing.
+func Load(dir string) (*Financials, error) {
+	f := &Financials{
+		IncomeStatement: &Statement{},
+		BalanceSheet:    &Statement{},
+		CashFlow:        &Statement{},
+		Valuation:       &Valuation{},
+		Realtime:        &Realtime{},
+	}
+
+	files := []struct {
+		name string
+		v    interface{}
+	}{
+		{StatementIncome, f.IncomeStatement},
+		{StatementBalance, f.BalanceSheet},
+		{StatementCashFlow, f.CashFlow},
+		{StatementValuation, f.Valuation},
+		{StatementRealtime, f.Realtime},
+	}
+	for _, file := range files {
+		ok, err := decodeJSON(
+			filepath.Join(dir, file.name+".json"),
+			file.v,
+		)
+		if err != nil {
+			return nil, &ParseError{File: file.name, Err: err}
+		}
+		if !ok {
+			return nil, nil
+		}
+	}
+	return f, nil
+}
+
+func decodeJSON(file string, v interface{}) (bool, error) {
+	f, err := os.Open(file)
+	if os.IsNotExist(err) {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	defer f.Close()
+	dec := json.NewDecoder(f)
+	err = dec.Decode(v)
+	if err != nil && err != io.EOF {
+		return false, err
+	}
+	return true, nil
+}
+
+// FiscalYears returns the n latest annual periods of the
+// income statement that the balance sheet and the cash flow
+// statement also have, the latest first. The missing
+// periods are empty, their values are zeros.
+func (f *Financials) FiscalYears(n int) []string {
+	bs := make(map[string]bool)
+	for _, v := range f.BalanceSheet.annualPeriods() {
+		bs[v] = true
+	}
+	cf := make(map[string]bool)
+	for _, v := range f.CashFlow.annualPeriods() {
+		cf[v] = true
+	}
+
+	years := make([]string, 0, n)
+	for _, v := range f.IncomeStatement.annualPeriods() {
+		if len(years) == n {
+			break
+		}
+		if bs[v] && cf[v] {
+			years = append(years, v)
+		}
+	}
+	for len(years) < n {
+		years = append(years, "")
+	}
+	return years
+}
+
+func (f *Financials) ReturnOnInvestedCapital(
+	period string,
+) float64 {
+	ear := f.IncomeStatement.OperatingIncome(period)
+	if ear <= 0 {
+		return math.NaN()
+	}
+	cap := f.BalanceSheet.InvestedCapital(period)
+	if cap <= 0 {
+		return math.NaN()
+	}
+	return (ear / cap) * 100
+}
+
+func (f *Financials) ReturnOnEquity(
+	period string,
+) float64 {
+	ear := f.IncomeStatement.OperatingIncome(period)
+	if ear <= 0 {
+		return math.NaN()
+	}
+	equ := f.BalanceSheet.Equity(period)
+	if equ <= 0 {
+		return math.NaN()
+	}
+	return (ear / equ) * 100
+}
+
+func (f *Financials) ReturnOnNetTangibleAssets(
+	period string,
+) float64 {
+	ear := f.IncomeStatement.OperatingIncome(period)
+	nta := f.BalanceSheet.NetTangibleAssets(period)
+	return (ear / nta) * 100
+}
+
+func (f *Financials) DebtToFreeCashFlow(
+	period string,
+) float64 {
+	debt := f.BalanceSheet.Debt(period)
+	fcf := f.CashFlow.FreeCashFlow(period)
+	if fcf <= 0 {
+		return math.NaN()
+	}
+	return debt / fcf
+}
+
+func (f *Financials) FreeCashFlowPerShare(
+	period string,
+) float64 {
+	sha := f.IncomeStatement.SharesOutstanding(period)
+	fcf := f.CashFlow.FreeCashFlow(period)
+	if sha <= 0 {
+		return math.NaN()
+	}
+	return fcf / sha
+}
+
+func (f *Financials) BookValuePerShare(
+	period string,
+) float64 {
+	sha := f.IncomeStatement.SharesOutstanding(period)
+	equ := f.BalanceSheet.Equity(period)
+	if sha <= 0 {
+		return math.NaN()
+	}
+	return equ / sha
+}
+
+func (f *Financials) DividendPerShare(
+	period string,
+) float64 {
+	sha := f.IncomeStatement.SharesOutstanding(period)
+	div := f.CashFlow.DividendPaid(period)
+	if sha <= 0 {
+		return math.NaN()
+	}
+	return div / sha
+}
+
+func (f *Financials) DebtToEquity(
+	period string,
+) float64 {
+	debt := f.BalanceSheet.Debt(period)
+	equ := f.BalanceSheet.Equity(period)
+	if equ <= 0 {
+		return math.NaN()
+	}
+	return debt / equ
+}
+
+func (f *Financials) NetInterestMargin(
+	period string,
+) float64 {
+	inc := f.IncomeStatement.NetInterestIncome(period)
+	ea := f.BalanceSheet.EarningAssets(period)
+	if ea <= 0 {
+		return math.NaN()
+	}
+	return (inc / ea) * 100
+}
+
+func (f *Financials) LoansToDeposits(
+	period string,
+) float64 {
+	loans := f.BalanceSheet.Loans(period)
+	dep := f.BalanceSheet.Deposits(period)
+	if dep <= 0 {
+		return math.NaN()
+	}
+	return (loans / dep) * 100
+}
+
+func (f *Financials) EquityToAssets(
+	period string,
+) float64 {
+	equ := f.BalanceSheet.Equity(period)
+	ass := f.BalanceSheet.TotalAssets(period)
+	if ass <= 0 {
+		return math.NaN()
+	}
+	return (equ / ass) * 100
+}
+
+// ReturnOnAssets is the net income to the total
+// assets at the end of the period as a percentage,
+// not to the assets at the beginning of the period.
+func (f *Financials) ReturnOnAssets(
+	period string,
+) float64 {
+	ear := f.IncomeStatement.NetIncome(period)
+	ass := f.BalanceSheet.TotalAssets(period)
+	if ass <= 0 {
+		return math.NaN()
+	}
+	return (ear / ass) * 100
+}
+
+// NetReturnOnEquity is the return on equity of the net
+// income, the operating income of banks is not comparable.
+func (f *Financials) NetReturnOnEquity(
+	period string,
+) float64 {
+	ear := f.IncomeStatement.NetIncome(period)
+	equ := f.BalanceSheet.Equity(period)
+	if equ <= 0 {
+		return math.NaN()
+	}
+	return (ear / equ) * 100
+}
+
+func (f *Financials) PayoutRatio(
+	period string,
+) float64 {
+	div := f.CashFlow.DividendPaid(period)
+	ear := f.IncomeStatement.NetIncome(period)
+	if ear <= 0 {
+		return math.NaN()
+	}
+	return (math.Abs(div) / ear) * 100
+}
+
+// Series are the values of a metric, TTM is the trailing
+// twelve months, Values are of the fiscal years of the
+// company, the latest first.
+type Series struct {
+	TTM    float64
+	Values []float64
+}
+
+// At returns the value of the i-th latest fiscal year
+// or NaN if it is not calculated.
+func (s *Series) At(i int) float64 {
+	if s == nil || i < 0 || len(s.Values) <= i {
+		return math.NaN()
+	}
+	return s.Values[i]
+}
+
+// Company is the financials of a company with
+// the series of the metrics of its latest fiscal years.
+type Company struct {
+	*Financials
+
+	Exchange string
+	Symbol   string
+
+	// FiscalYears are the annual periods of
+	// the series, the latest first.
+	FiscalYears []string
+
+	// Intrinsic is the model of the intrinsic value
+	// metrics, the default model if nil.
+	Intrinsic *intrinsic.Model
+
+	series map[string]*Series
+}
+
+// MinFiscalYears is the minimum number of the fiscal
+// years of the series, the growth rates need 3 years.
+const MinFiscalYears = 3
+
+// NewCompany calculates the series of the periodic
+// metrics of the n latest fiscal years.
+func NewCompany(
+	exchange string,
+	symbol string,
+	f *Financials,
+	n int,
+) *Company {
+	if n < MinFiscalYears {
+		n = MinFiscalYears
+	}
+	c := &Company{
+		Financials:  f,
+		Exchange:    exchange,
+		Symbol:      symbol,
+		FiscalYears: f.FiscalYears(n),
+		series:      make(map[string]*Series),
+	}
+
+	for _, m := range metrics {
+		if m.Period == nil {
+			continue
+		}
+		s := &Series{
+			TTM:    math.NaN(),
+			Values: make([]float64, 0, n),
+		}
+		if m.TTM {
+			s.TTM = m.Period(f, periodTTM)
+		}
+		for _, y := range c.FiscalYears {
+			s.Values = append(s.Values, m.Period(f, y))
+		}
+		c.series[m.Name] = s
+	}
+	return c
+}
+
+// Series returns the series of the periodic metric.
+func (c *Company) Series(name string) *Series {
+	return c.series[name]
+}
+
+func (c *Company) model() *intrinsic.Model {
+	if c.Intrinsic == nil {
+		return intrinsic.New()
+	}
+	return c.Intrinsic
+}
+
+// Value returns the value of the metric, the value of
+// the latest fiscal year of the periodic metrics.
+func (c *Company) Value(m *Metric) float64 {
+	if m.Period != nil {
+		return c.Series(m.Name).At(0)
+	}
+	return m.Company(c)
+}
+
+// FiscalYearEnd returns the end date of the latest
+// fiscal year, the zero time if it is unknown.
+func (c *Company) FiscalYearEnd() time.Time {
+	if len(c.FiscalYears) == 0 || c.FiscalYears[0] == "" {
+		return time.Time{}
+	}
+	return c.IncomeStatement.FiscalYearEnd(c.FiscalYears[0])
+}
+
+// calendarYear returns the calendar year that contains
+// most of the fiscal year, e.g. 2020 for the fiscal year
+// ending on 2021-01-31, so the fiscal years of the companies
+// can be compared.
+func calendarYear(fiscalYearEnd time.Time) int {
+	if fiscalYearEnd.Month() < time.June {
+		return fiscalYearEnd.Year() - 1
+	}
+	return fiscalYearEnd.Year()
+}
+
+// CommonYears returns the calendar years of the fiscal years
+// if they are the same for all the companies, otherwise nil.
+func CommonYears(companies []*Company) []string {
+	var years []string
+	for _, c := range companies {
+		cy := make([]string, len(c.FiscalYears))
+		for i, v := range c.FiscalYears {
+			if v == "" {
+				return nil
+			}
+			end := c.IncomeStatement.FiscalYearEnd(v)
+			if end.IsZero() {
+				return nil
+			}
+			cy[i] = strconv.Itoa(calendarYear(end))
+		}
+
+		if years == nil {
+			years = cy
+			continue
+		}
+		for i := range years {
+			if years[i] != cy[i] {
+				return nil
+			}
+		}
+	}
+	return years
+}
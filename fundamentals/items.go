@@ -0,0 +1,256 @@
+package fundamentals
+
+import (
+	"encoding/json"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"szakszon.com/divyield"
+)
+
+// The statements of the items.
+const (
+	StatementIncome    = "is"
+	StatementBalance   = "bs"
+	StatementCashFlow  = "cf"
+	StatementValuation = "valuation"
+	StatementRealtime  = "rt"
+)
+
+const periodCurrent = "Current"
+
+// magnitudeUnit is the order of magnitude of the
+// statements built from items, the values are scaled.
+const magnitudeUnit = "Unit"
+
+// Parse decodes the JSON documents of the statements.
+func Parse(
+	realtime string,
+	valuation string,
+	incomeStatement string,
+	balanceSheet string,
+	cashFlow string,
+) (*Financials, error) {
+	f := &Financials{
+		IncomeStatement: &Statement{},
+		BalanceSheet:    &Statement{},
+		CashFlow:        &Statement{},
+		Valuation:       &Valuation{},
+		Realtime:        &Realtime{},
+	}
+
+	docs := []struct {
+		name string
+		doc  string
+		v    interface{}
+	}{
+		{StatementRealtime, realtime, f.Realtime},
+		{StatementValuation, valuation, f.Valuation},
+		{StatementIncome, incomeStatement, f.IncomeStatement},
+		{StatementBalance, balanceSheet, f.BalanceSheet},
+		{StatementCashFlow, cashFlow, f.CashFlow},
+	}
+	for _, d := range docs {
+		err := json.Unmarshal([]byte(d.doc), d.v)
+		if err != nil {
+			return nil, &ParseError{File: d.name, Err: err}
+		}
+	}
+	return f, nil
+}
+
+// Items returns the line items of the statements.
+// The values are scaled by the order of magnitude
+// of the statements, except the per-share and the
+// ratio rows.
+func (f *Financials) Items(
+	exchange string,
+	symbol string,
+	pulled time.Time,
+) []*divyield.StatementItem {
+	items := make([]*divyield.StatementItem, 0)
+	add := func(statement, period, label, currency, fye string, v float64) {
+		items = append(items, &divyield.StatementItem{
+			Exchange:      exchange,
+			Symbol:        symbol,
+			Statement:     statement,
+			Period:        period,
+			Label:         label,
+			Value:         v,
+			Currency:      currency,
+			FiscalYearEnd: fye,
+			Pulled:        pulled,
+		})
+	}
+
+	statements := []struct {
+		name string
+		s    *Statement
+	}{
+		{StatementIncome, f.IncomeStatement},
+		{StatementBalance, f.BalanceSheet},
+		{StatementCashFlow, f.CashFlow},
+	}
+	for _, st := range statements {
+		s := st.s
+		if s == nil || s.Footer == nil {
+			continue
+		}
+
+		// the first row of a label is used, like value does
+		seen := make(map[string]bool)
+		levels := make([]*StatementRow, 0)
+		levels = append(levels, s.Rows...)
+		for len(levels) > 0 {
+			next := levels[0]
+			levels = levels[1:]
+			levels = append(levels, next.SubLevels...)
+
+			if seen[next.Label] {
+				continue
+			}
+			seen[next.Label] = true
+
+			for i, period := range s.ColumnDefs {
+				if i >= len(next.Datum) {
+					break
+				}
+				if _, ok, err := datumValue(next.Datum[i]); !ok || err != nil {
+					continue
+				}
+				add(
+					st.name,
+					period,
+					next.Label,
+					s.Footer.Currency,
+					s.Footer.FiscalYearEndDate,
+					s.value(i, next.Label, []*StatementRow{next}),
+				)
+			}
+		}
+	}
+
+	if f.Valuation != nil && f.Valuation.Collapsed != nil {
+		for _, row := range f.Valuation.Collapsed.Rows {
+			for i, period := range f.Valuation.Collapsed.ColumnDefs {
+				if i == 0 || i-1 >= len(row.Datum) {
+					continue
+				}
+				v, ok, err := datumValue(row.Datum[i-1])
+				if !ok || err != nil {
+					continue
+				}
+				add(StatementValuation, period, row.Label, "", "", v)
+			}
+		}
+	}
+
+	if f.Realtime != nil {
+		add(StatementRealtime, periodCurrent, "marketCap", "", "", f.Realtime.MarketCap)
+		add(StatementRealtime, periodCurrent, "lastPrice", "", "", f.Realtime.LastPrice)
+	}
+	return items
+}
+
+// FromItems builds the financials of the items of a company.
+func FromItems(items []*divyield.StatementItem) *Financials {
+	f := &Financials{
+		IncomeStatement: &Statement{},
+		BalanceSheet:    &Statement{},
+		CashFlow:        &Statement{},
+		Valuation: &Valuation{
+			Collapsed: &ValuationCollapsed{},
+		},
+		Realtime: &Realtime{},
+	}
+	statements := map[string]*Statement{
+		StatementIncome:   f.IncomeStatement,
+		StatementBalance:  f.BalanceSheet,
+		StatementCashFlow: f.CashFlow,
+	}
+
+	periods := make(map[string]map[string]bool)
+	for _, v := range items {
+		if periods[v.Statement] == nil {
+			periods[v.Statement] = make(map[string]bool)
+		}
+		periods[v.Statement][v.Period] = true
+	}
+
+	for name, s := range statements {
+		s.ColumnDefs = sortPeriods(periods[name])
+		s.Footer = &StatementFooter{
+			OrderOfMagnitude: magnitudeUnit,
+		}
+	}
+	f.Valuation.Collapsed.ColumnDefs = append(
+		[]string{""},
+		sortPeriods(periods[StatementValuation])...,
+	)
+
+	rows := make(map[string]*StatementRow)
+	valuationRows := make(map[string]*ValuationRow)
+	for _, v := range items {
+		if s, ok := statements[v.Statement]; ok {
+			s.Footer.Currency = v.Currency
+			s.Footer.FiscalYearEndDate = v.FiscalYearEnd
+
+			k := v.Statement + "\x00" + v.Label
+			row, ok := rows[k]
+			if !ok {
+				row = &StatementRow{
+					Label: v.Label,
+					Datum: make([]interface{}, len(s.ColumnDefs)),
+				}
+				rows[k] = row
+				s.Rows = append(s.Rows, row)
+			}
+			row.Datum[s.periodIndex(v.Period)] = v.Value
+			continue
+		}
+
+		switch v.Statement {
+		case StatementValuation:
+			c := f.Valuation.Collapsed
+			row, ok := valuationRows[v.Label]
+			if !ok {
+				row = &ValuationRow{
+					Label: v.Label,
+					Datum: make([]interface{}, len(c.ColumnDefs)-1),
+				}
+				valuationRows[v.Label] = row
+				c.Rows = append(c.Rows, row)
+			}
+			row.Datum[f.Valuation.periodIndex(v.Period)] =
+				strconv.FormatFloat(v.Value, 'f', -1, 64)
+		case StatementRealtime:
+			switch v.Label {
+			case "marketCap":
+				f.Realtime.MarketCap = v.Value
+			case "lastPrice":
+				f.Realtime.LastPrice = v.Value
+			}
+		}
+	}
+	return f
+}
+
+// sortPeriods returns the periods in the order of the
+// Morningstar columns, the years first, then the others.
+func sortPeriods(periods map[string]bool) []string {
+	res := make([]string, 0, len(periods))
+	for v := range periods {
+		res = append(res, v)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		_, ei := strconv.Atoi(res[i])
+		_, ej := strconv.Atoi(res[j])
+		if (ei == nil) != (ej == nil) {
+			return ei == nil
+		}
+		return strings.Compare(res[i], res[j]) < 0
+	})
+	return res
+}
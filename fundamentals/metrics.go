@@ -0,0 +1,406 @@
+package fundamentals
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"szakszon.com/divyield/intrinsic"
+)
+
+const periodTTM = "TTM"
+
+// Metric is the definition of a metric. The periodic metrics
+// are calculated for the periods of the statements, the others
+// from the series of the company.
+type Metric struct {
+	// Name is the name of the metric in the series
+	// and the -metrics flag.
+	Name string
+
+	// Header is the column header of the metric.
+	Header string
+
+	// Percent is true if the values are percentages.
+	Percent bool
+
+	// TTM is true if the periodic metric has
+	// a trailing twelve months value.
+	TTM bool
+
+	Period  func(f *Financials, period string) float64
+	Company func(c *Company) float64
+}
+
+var metrics = []*Metric{
+	{
+		Name:    "ROIC",
+		Header:  "ROIC",
+		Percent: true,
+		Period: func(f *Financials, period string) float64 {
+			return f.ReturnOnInvestedCapital(period)
+		},
+	},
+	{
+		Name:    "ROE",
+		Header:  "ROE",
+		Percent: true,
+		Period: func(f *Financials, period string) float64 {
+			return f.ReturnOnEquity(period)
+		},
+	},
+	{
+		Name:    "RONTA",
+		Header:  "RONTA",
+		Percent: true,
+		Period: func(f *Financials, period string) float64 {
+			return f.ReturnOnNetTangibleAssets(period)
+		},
+	},
+	{
+		Name:   "DebtToEqu",
+		Header: "Debt/Equ",
+		Period: func(f *Financials, period string) float64 {
+			return f.DebtToEquity(period)
+		},
+	},
+	{
+		Name:   "DebtToFCF",
+		Header: "Debt/FCF",
+		Period: func(f *Financials, period string) float64 {
+			return f.DebtToFreeCashFlow(period)
+		},
+	},
+	{
+		Name:    "CapRate",
+		Header:  "Cap rate",
+		Percent: true,
+		Company: func(c *Company) float64 {
+			return c.Series("FCFPS").TTM / c.Realtime.LastPrice * 100
+		},
+	},
+	{
+		Name:    "FCFPSCAGR",
+		Header:  "FCFPS CAGR",
+		Percent: true,
+		Company: func(c *Company) float64 {
+			s := c.Series("FCFPS")
+			return cagr(s.TTM, s.At(2), 3)
+		},
+	},
+	{
+		Name:   "FCF",
+		Header: "FCF",
+		TTM:    true,
+		Period: func(f *Financials, period string) float64 {
+			return f.CashFlow.FreeCashFlow(period)
+		},
+	},
+	{
+		Name:   "FCFPS",
+		Header: "FCFPS",
+		TTM:    true,
+		Period: func(f *Financials, period string) float64 {
+			return f.FreeCashFlowPerShare(period)
+		},
+	},
+	{
+		Name:    "EPSCAGR",
+		Header:  "EPS CAGR",
+		Percent: true,
+		Company: func(c *Company) float64 {
+			s := c.Series("EPS")
+			return cagr(s.TTM, s.At(2), 3)
+		},
+	},
+	{
+		Name:   "E",
+		Header: "E",
+		TTM:    true,
+		Period: func(f *Financials, period string) float64 {
+			return f.IncomeStatement.NetIncome(period)
+		},
+	},
+	{
+		Name:   "EPS",
+		Header: "EPS",
+		TTM:    true,
+		Period: func(f *Financials, period string) float64 {
+			return f.IncomeStatement.EarningsPerShare(period)
+		},
+	},
+	{
+		Name:    "BVDivPSCAGR",
+		Header:  "BVDivPS CAGR",
+		Percent: true,
+		Company: func(c *Company) float64 {
+			bvps := c.Series("BVPS")
+			divps := c.Series("DivPS")
+			return cagr(
+				bvps.At(0)+math.Abs(divps.At(0)),
+				bvps.At(2)+math.Abs(divps.At(2)),
+				2,
+			)
+		},
+	},
+	{
+		Name:   "BV",
+		Header: "BV",
+		Period: func(f *Financials, period string) float64 {
+			return f.BalanceSheet.Equity(period)
+		},
+	},
+	{
+		Name:   "BVPS",
+		Header: "BVPS",
+		Period: func(f *Financials, period string) float64 {
+			return f.BookValuePerShare(period)
+		},
+	},
+	{
+		Name:   "DivPS",
+		Header: "DivPS",
+		Period: func(f *Financials, period string) float64 {
+			return f.DividendPerShare(period)
+		},
+	},
+	{
+		Name:    "RPSCAGR",
+		Header:  "RPS CAGR",
+		Percent: true,
+		Company: func(c *Company) float64 {
+			s := c.Series("RPS")
+			return cagr(s.TTM, s.At(2), 3)
+		},
+	},
+	{
+		Name:   "Rev",
+		Header: "Rev",
+		TTM:    true,
+		Period: func(f *Financials, period string) float64 {
+			return f.IncomeStatement.Revenue(period)
+		},
+	},
+	{
+		Name:   "RPS",
+		Header: "RPS",
+		TTM:    true,
+		Period: func(f *Financials, period string) float64 {
+			return f.IncomeStatement.RevenuePerShare(period)
+		},
+	},
+	{
+		Name:   "CORToRev",
+		Header: "COR/Rev",
+		TTM:    true,
+		Period: func(f *Financials, period string) float64 {
+			return f.IncomeStatement.CostOfRevenueToRevenue(period)
+		},
+	},
+	{
+		Name:    "Eff",
+		Header:  "Eff",
+		Percent: true,
+		TTM:     true,
+		Period: func(f *Financials, period string) float64 {
+			return f.IncomeStatement.OperatingEfficiency(period)
+		},
+	},
+	{
+		Name:   "DCF",
+		Header: "DCF",
+		Company: func(c *Company) float64 {
+			return dcf(c)
+		},
+	},
+	{
+		Name:    "DCFMoS",
+		Header:  "DCF MoS",
+		Percent: true,
+		Company: func(c *Company) float64 {
+			return intrinsic.MarginOfSafety(
+				dcf(c),
+				c.Realtime.LastPrice,
+			)
+		},
+	},
+	{
+		Name:   "Graham",
+		Header: "Graham",
+		Company: func(c *Company) float64 {
+			return graham(c)
+		},
+	},
+	{
+		Name:    "GrahamMoS",
+		Header:  "Graham MoS",
+		Percent: true,
+		Company: func(c *Company) float64 {
+			return intrinsic.MarginOfSafety(
+				graham(c),
+				c.Realtime.LastPrice,
+			)
+		},
+	},
+	{
+		Name:   "FScore",
+		Header: "F",
+		Company: func(c *Company) float64 {
+			s := c.FScore()
+			if s == nil {
+				return math.NaN()
+			}
+			return float64(s.Score())
+		},
+	},
+	{
+		Name:   "ZScore",
+		Header: "Z",
+		Company: func(c *Company) float64 {
+			s := c.ZScore()
+			if s == nil {
+				return math.NaN()
+			}
+			return s.Score()
+		},
+	},
+	{
+		Name:    "EffChg",
+		Header:  "Eff chg",
+		Percent: true,
+		Company: func(c *Company) float64 {
+			s := c.Series("Eff")
+			return s.At(0) - s.At(2)
+		},
+	},
+	{
+		Name:    "NIM",
+		Header:  "NIM",
+		Percent: true,
+		Period: func(f *Financials, period string) float64 {
+			return f.NetInterestMargin(period)
+		},
+	},
+	{
+		Name:    "LoansToDep",
+		Header:  "Loans/Dep",
+		Percent: true,
+		Period: func(f *Financials, period string) float64 {
+			return f.LoansToDeposits(period)
+		},
+	},
+	{
+		Name:    "EquToAssets",
+		Header:  "Equ/Assets",
+		Percent: true,
+		Period: func(f *Financials, period string) float64 {
+			return f.EquityToAssets(period)
+		},
+	},
+	{
+		Name:    "ROA",
+		Header:  "ROA",
+		Percent: true,
+		Period: func(f *Financials, period string) float64 {
+			return f.ReturnOnAssets(period)
+		},
+	},
+	{
+		Name:    "NetROE",
+		Header:  "Net ROE",
+		Percent: true,
+		Period: func(f *Financials, period string) float64 {
+			return f.NetReturnOnEquity(period)
+		},
+	},
+	{
+		Name:    "Payout",
+		Header:  "Payout",
+		Percent: true,
+		TTM:     true,
+		Period: func(f *Financials, period string) float64 {
+			return f.PayoutRatio(period)
+		},
+	},
+}
+
+// DefaultMetrics are the metrics of the industrial profile.
+var DefaultMetrics = []string{
+	"RONTA",
+	"DebtToEqu",
+	"DebtToFCF",
+	"CapRate",
+	"FCFPSCAGR",
+	"FCFPS",
+	"EPSCAGR",
+	"EPS",
+	"BVDivPSCAGR",
+	"BVPS",
+	"DivPS",
+	"RPSCAGR",
+	"RPS",
+	"CORToRev",
+	"Eff",
+	"DCF",
+	"DCFMoS",
+	"Graham",
+	"GrahamMoS",
+	"FScore",
+	"ZScore",
+}
+
+// Metrics returns the definitions of the metrics.
+func Metrics() []*Metric {
+	return metrics
+}
+
+// LookupMetrics returns the metrics of the names, the names
+// are case insensitive, dashes and underscores are ignored.
+func LookupMetrics(names []string) ([]*Metric, error) {
+	res := make([]*Metric, 0, len(names))
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if name == "" {
+			continue
+		}
+		m := lookupMetric(name)
+		if m == nil {
+			return nil, fmt.Errorf("unknown metric: %v", name)
+		}
+		res = append(res, m)
+	}
+	return res, nil
+}
+
+func lookupMetric(name string) *Metric {
+	for _, m := range metrics {
+		if normalizeName(m.Name) == normalizeName(name) {
+			return m
+		}
+	}
+	return nil
+}
+
+// dcf returns the fair price of the
+// discounted cash flow of FCFPS TTM.
+func dcf(c *Company) float64 {
+	return c.model().DiscountedCashFlow(c.Series("FCFPS").TTM)
+}
+
+// graham returns the Graham number of EPS TTM
+// and BVPS of the latest fiscal year.
+func graham(c *Company) float64 {
+	return intrinsic.GrahamNumber(
+		c.Series("EPS").TTM,
+		c.Series("BVPS").At(0),
+	)
+}
+
+// cagr returns the compound annual growth rate
+// of n years as a percentage.
+func cagr(to, from float64, n int) float64 {
+	if to <= 0 || from <= 0 {
+		return math.NaN()
+	}
+	return (math.Pow(to/from, 1/float64(n)) - 1) * 100
+}
@@ -0,0 +1,233 @@
+package fundamentals
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrMagnitude is the error of an unknown order of magnitude.
+	ErrMagnitude = errors.New("unknown order of magnitude")
+
+	// ErrNumber is the error of a value that is not a number.
+	ErrNumber = errors.New("invalid number")
+
+	// errMissing is the error of a value that is not
+	// reported, e.g. empty or a dash.
+	errMissing = errors.New("missing value")
+)
+
+// ParseError is an error of a value of a statement.
+type ParseError struct {
+	Exchange string
+	Symbol   string
+
+	// File is the statement, e.g. is or valuation.
+	File string
+
+	Label  string
+	Period string
+	Value  string
+	Err    error
+}
+
+func (e *ParseError) Error() string {
+	b := &strings.Builder{}
+	if e.Symbol != "" {
+		if e.Exchange != "" {
+			b.WriteString(e.Exchange + "/")
+		}
+		b.WriteString(e.Symbol + ": ")
+	}
+	b.WriteString(e.File)
+	if e.Label != "" {
+		b.WriteString(": " + e.Label)
+	}
+	if e.Period != "" {
+		b.WriteString(" " + e.Period)
+	}
+	if e.Value != "" {
+		b.WriteString(fmt.Sprintf(": %q", e.Value))
+	}
+	b.WriteString(": " + e.Err.Error())
+	return b.String()
+}
+
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+var magnitudes = map[string]float64{
+	"trillion":         1000000000000,
+	"billion":          1000000000,
+	"hundred million":  100000000,
+	"ten million":      10000000,
+	"million":          1000000,
+	"hundred thousand": 100000,
+	"ten thousand":     10000,
+	"thousand":         1000,
+	"hundred":          100,
+	"unit":             1,
+	"units":            1,
+	"one":              1,
+	"ones":             1,
+}
+
+// parseMagnitude returns the multiplier of
+// the order of magnitude, e.g. 1000 of Thousand.
+func parseMagnitude(s string) (float64, error) {
+	v, ok := magnitudes[strings.ToLower(strings.TrimSpace(s))]
+	if !ok {
+		return 0, ErrMagnitude
+	}
+	return v, nil
+}
+
+// parseNumber parses the number formats of the statements,
+// e.g. 1,234.5, (12.3) or 4.5%, the percentages are not
+// divided by 100. Empty values, dashes and the _PO_
+// placeholders of the premium values are missing.
+func parseNumber(s string) (float64, error) {
+	s = strings.TrimSpace(s)
+	switch s {
+	case "", "-", "--", "–", "—", "N/A", "NA", "n/a", "_PO_":
+		return 0, errMissing
+	}
+
+	neg := false
+	if strings.HasPrefix(s, "(") && strings.HasSuffix(s, ")") {
+		neg = true
+		s = strings.TrimSpace(s[1 : len(s)-1])
+	}
+	s = strings.TrimSuffix(s, "%")
+	s = strings.ReplaceAll(s, ",", "")
+	s = strings.Replace(s, "−", "-", 1)
+
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0, ErrNumber
+	}
+	if neg {
+		v = -v
+	}
+	return v, nil
+}
+
+// datumValue returns the number of a datum of
+// a statement, false if the value is missing.
+func datumValue(d interface{}) (float64, bool, error) {
+	switch v := d.(type) {
+	case float64:
+		return v, true, nil
+	case string:
+		num, err := parseNumber(v)
+		if err == errMissing {
+			return 0, false, nil
+		}
+		if err != nil {
+			return 0, false, err
+		}
+		return num, true, nil
+	default:
+		return 0, false, nil
+	}
+}
+
+// Validate returns a *ParseError of the first value of
+// the statements that can not be parsed, nil otherwise.
+func (f *Financials) Validate(exchange, symbol string) error {
+	statements := []struct {
+		name string
+		s    *Statement
+	}{
+		{StatementIncome, f.IncomeStatement},
+		{StatementBalance, f.BalanceSheet},
+		{StatementCashFlow, f.CashFlow},
+	}
+	for _, st := range statements {
+		err := st.s.validate()
+		if err != nil {
+			err.Exchange = exchange
+			err.Symbol = symbol
+			err.File = st.name
+			return err
+		}
+	}
+
+	err := f.Valuation.validate()
+	if err != nil {
+		err.Exchange = exchange
+		err.Symbol = symbol
+		err.File = StatementValuation
+		return err
+	}
+	return nil
+}
+
+func (s *Statement) validate() *ParseError {
+	if s == nil || len(s.Rows) == 0 {
+		return nil
+	}
+	if s.Footer == nil {
+		return &ParseError{Err: fmt.Errorf("missing footer")}
+	}
+	_, err := parseMagnitude(s.Footer.OrderOfMagnitude)
+	if err != nil {
+		return &ParseError{
+			Label: "orderOfMagnitude",
+			Value: s.Footer.OrderOfMagnitude,
+			Err:   err,
+		}
+	}
+
+	levels := make([]*StatementRow, 0)
+	levels = append(levels, s.Rows...)
+	for len(levels) > 0 {
+		next := levels[0]
+		levels = levels[1:]
+		levels = append(levels, next.SubLevels...)
+
+		for i, d := range next.Datum {
+			_, _, err := datumValue(d)
+			if err == nil {
+				continue
+			}
+			pe := &ParseError{
+				Label: next.Label,
+				Value: fmt.Sprint(d),
+				Err:   err,
+			}
+			if i < len(s.ColumnDefs) {
+				pe.Period = s.ColumnDefs[i]
+			}
+			return pe
+		}
+	}
+	return nil
+}
+
+func (s *Valuation) validate() *ParseError {
+	if s == nil || s.Collapsed == nil {
+		return nil
+	}
+	for _, row := range s.Collapsed.Rows {
+		for i, d := range row.Datum {
+			_, _, err := datumValue(d)
+			if err == nil {
+				continue
+			}
+			pe := &ParseError{
+				Label: row.Label,
+				Value: fmt.Sprint(d),
+				Err:   err,
+			}
+			if i+1 < len(s.Collapsed.ColumnDefs) {
+				pe.Period = s.Collapsed.ColumnDefs[i+1]
+			}
+			return pe
+		}
+	}
+	return nil
+}
@@ -0,0 +1,266 @@
+package fundamentals
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseNumber(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  error
+	}{
+		{"0", 0, nil},
+		{"12", 12, nil},
+		{"-12.5", -12.5, nil},
+		{" 12.5 ", 12.5, nil},
+		{"1,234.5", 1234.5, nil},
+		{"1,234,567", 1234567, nil},
+		{"(12.3)", -12.3, nil},
+		{"(1,234.5)", -1234.5, nil},
+		{"4.5%", 4.5, nil},
+		{"(4.5%)", -4.5, nil},
+		{"−7", -7, nil},
+		{"", 0, errMissing},
+		{"  ", 0, errMissing},
+		{"-", 0, errMissing},
+		{"--", 0, errMissing},
+		{"–", 0, errMissing},
+		{"—", 0, errMissing},
+		{"N/A", 0, errMissing},
+		{"NA", 0, errMissing},
+		{"n/a", 0, errMissing},
+		{"_PO_", 0, errMissing},
+		{"abc", 0, ErrNumber},
+		{"12abc", 0, ErrNumber},
+		{"1.2.3", 0, ErrNumber},
+	}
+	for _, tt := range tests {
+		got, err := parseNumber(tt.in)
+		if err != tt.err {
+			t.Errorf("parseNumber(%q): got error %v, want %v", tt.in, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseNumber(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseMagnitude(t *testing.T) {
+	tests := []struct {
+		in   string
+		want float64
+		err  error
+	}{
+		{"Trillion", 1000000000000, nil},
+		{"Billion", 1000000000, nil},
+		{"Hundred Million", 100000000, nil},
+		{"Ten Million", 10000000, nil},
+		{"Million", 1000000, nil},
+		{"Hundred Thousand", 100000, nil},
+		{"Ten Thousand", 10000, nil},
+		{"Thousand", 1000, nil},
+		{" thousand ", 1000, nil},
+		{"Hundred", 100, nil},
+		{"Unit", 1, nil},
+		{"Units", 1, nil},
+		{"One", 1, nil},
+		{"Ones", 1, nil},
+		{"", 0, ErrMagnitude},
+		{"Thousands", 0, ErrMagnitude},
+		{"_PO_", 0, ErrMagnitude},
+	}
+	for _, tt := range tests {
+		got, err := parseMagnitude(tt.in)
+		if err != tt.err {
+			t.Errorf("parseMagnitude(%q): got error %v, want %v", tt.in, err, tt.err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("parseMagnitude(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestDatumValue(t *testing.T) {
+	tests := []struct {
+		in   interface{}
+		want float64
+		ok   bool
+		err  error
+	}{
+		{12.5, 12.5, true, nil},
+		{"1,234.5", 1234.5, true, nil},
+		{"(12.3)", -12.3, true, nil},
+		{"_PO_", 0, false, nil},
+		{"", 0, false, nil},
+		{"—", 0, false, nil},
+		{nil, 0, false, nil},
+		{true, 0, false, nil},
+		{"abc", 0, false, ErrNumber},
+	}
+	for _, tt := range tests {
+		got, ok, err := datumValue(tt.in)
+		if err != tt.err {
+			t.Errorf("datumValue(%#v): got error %v, want %v", tt.in, err, tt.err)
+			continue
+		}
+		if got != tt.want || ok != tt.ok {
+			t.Errorf(
+				"datumValue(%#v) = %v, %v, want %v, %v",
+				tt.in, got, ok, tt.want, tt.ok,
+			)
+		}
+	}
+}
+
+func statement(magnitude string, datum ...interface{}) *Statement {
+	return &Statement{
+		ColumnDefs: []string{"2019", "2020", "TTM"},
+		Rows: []*StatementRow{
+			{
+				Label: "Total Revenue",
+				Datum: []interface{}{100.0, "1,200", "_PO_"},
+				SubLevels: []*StatementRow{
+					{
+						Label: "Net Income",
+						Datum: datum,
+					},
+				},
+			},
+		},
+		Footer: &StatementFooter{
+			Currency:         "USD",
+			OrderOfMagnitude: magnitude,
+		},
+	}
+}
+
+func valuation(datum ...interface{}) *Valuation {
+	return &Valuation{
+		Collapsed: &ValuationCollapsed{
+			ColumnDefs: []string{"Calendar", "2019", "2020", "Current"},
+			Rows: []*ValuationRow{
+				{
+					Label: "Price/Earnings",
+					Datum: datum,
+				},
+			},
+		},
+	}
+}
+
+func TestValidate(t *testing.T) {
+	valid := func() *Financials {
+		return &Financials{
+			IncomeStatement: statement("Million", "(12.3)", "4.5%", "—"),
+			BalanceSheet:    statement("Thousand", 1.0, "", "_PO_"),
+			CashFlow:        statement("Billion", "1,234.5", "-", "N/A"),
+			Valuation:       valuation("12.5", "_PO_", 14.0),
+		}
+	}
+
+	tests := []struct {
+		name   string
+		modify func(f *Financials)
+		err    error
+		want   string
+	}{
+		{
+			name:   "valid",
+			modify: func(f *Financials) {},
+		},
+		{
+			name: "empty statements",
+			modify: func(f *Financials) {
+				f.IncomeStatement = &Statement{}
+				f.BalanceSheet = nil
+				f.Valuation = &Valuation{}
+			},
+		},
+		{
+			name: "number",
+			modify: func(f *Financials) {
+				f.BalanceSheet = statement("Thousand", 1.0, "1.2.3", "")
+			},
+			err:  ErrNumber,
+			want: `XNAS/AAPL: bs: Net Income 2020: "1.2.3": invalid number`,
+		},
+		{
+			name: "number without period",
+			modify: func(f *Financials) {
+				f.CashFlow = statement("Billion", 1.0, 2.0, 3.0, "abc")
+			},
+			err:  ErrNumber,
+			want: `XNAS/AAPL: cf: Net Income: "abc": invalid number`,
+		},
+		{
+			name: "magnitude",
+			modify: func(f *Financials) {
+				f.IncomeStatement = statement("Thousands", 1.0)
+			},
+			err:  ErrMagnitude,
+			want: `XNAS/AAPL: is: orderOfMagnitude: "Thousands": unknown order of magnitude`,
+		},
+		{
+			name: "missing footer",
+			modify: func(f *Financials) {
+				f.CashFlow.Footer = nil
+			},
+			want: `XNAS/AAPL: cf: missing footer`,
+		},
+		{
+			name: "valuation",
+			modify: func(f *Financials) {
+				f.Valuation = valuation("12.5", "n.m.", 14.0)
+			},
+			err:  ErrNumber,
+			want: `XNAS/AAPL: valuation: Price/Earnings 2020: "n.m.": invalid number`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := valid()
+			tt.modify(f)
+			err := f.Validate("XNAS", "AAPL")
+			if tt.want == "" {
+				if err != nil {
+					t.Fatalf("got error %v, want nil", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatalf("got nil error, want %v", tt.want)
+			}
+			if err.Error() != tt.want {
+				t.Errorf("got error %q, want %q", err.Error(), tt.want)
+			}
+			var pe *ParseError
+			if !errors.As(err, &pe) {
+				t.Fatalf("got error %T, want *ParseError", err)
+			}
+			if pe.Exchange != "XNAS" || pe.Symbol != "AAPL" {
+				t.Errorf("got %v/%v, want XNAS/AAPL", pe.Exchange, pe.Symbol)
+			}
+			if tt.err != nil && !errors.Is(err, tt.err) {
+				t.Errorf("got error %v, want %v", err, tt.err)
+			}
+		})
+	}
+}
+
+func TestParseErrorWithoutSymbol(t *testing.T) {
+	err := &ParseError{
+		File:   StatementIncome,
+		Label:  "Total Revenue",
+		Period: "2020",
+		Value:  "x",
+		Err:    ErrNumber,
+	}
+	want := `is: Total Revenue 2020: "x": invalid number`
+	if err.Error() != want {
+		t.Errorf("got %q, want %q", err.Error(), want)
+	}
+}
@@ -0,0 +1,69 @@
+package fundamentals
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Profile is the default metrics, filters and
+// sort key of bargain for a kind of companies.
+type Profile struct {
+	Name    string
+	Metrics []string
+	Filters []string
+	SortKey string
+}
+
+// ProfileIndustrial is the profile of the industrial companies.
+const ProfileIndustrial = "industrial"
+
+var profiles = []*Profile{
+	{
+		Name:    ProfileIndustrial,
+		Metrics: DefaultMetrics,
+		Filters: []string{
+			"fcf-positive",
+			"ronta-positive",
+		},
+		SortKey: "RONTA:desc",
+	},
+	{
+		Name: "bank",
+		Metrics: []string{
+			"Eff",
+			"EffChg",
+			"NIM",
+			"LoansToDep",
+			"EquToAssets",
+			"ROA",
+			"NetROE",
+			"Payout",
+			"EPS",
+			"BVPS",
+			"DivPS",
+		},
+		Filters: []string{
+			"eff-max",
+			"loans-deposits-max",
+			"equity-assets-min",
+			"roa-min",
+		},
+		SortKey: "ROA:desc",
+	},
+}
+
+// Profiles returns the profiles.
+func Profiles() []*Profile {
+	return profiles
+}
+
+// LookupProfile returns the profile of the name,
+// the name is case insensitive.
+func LookupProfile(name string) (*Profile, error) {
+	for _, p := range profiles {
+		if strings.EqualFold(p.Name, name) {
+			return p, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown profile: %v", name)
+}
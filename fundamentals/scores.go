@@ -0,0 +1,161 @@
+package fundamentals
+
+import (
+	"math"
+)
+
+// FScore is the Piotroski F-score, nine binary signals of
+// the profitability, the leverage and liquidity, and the
+// operating efficiency of a fiscal year versus the previous.
+type FScore struct {
+	// ROA is true if the return on assets is positive,
+	// see ReturnOnAssets.
+	ROA bool
+	// CFO is true if the operating cash flow is positive.
+	CFO bool
+	// DeltaROA is true if the return on assets increased.
+	DeltaROA bool
+	// Accrual is true if the operating cash
+	// flow is above the net income.
+	Accrual bool
+	// DeltaLeverage is true if the long term debt to
+	// assets did not increase, e.g. there is no long
+	// term debt in both years.
+	DeltaLeverage bool
+	// DeltaCurrentRatio is true if the current ratio increased.
+	DeltaCurrentRatio bool
+	// NoDilution is true if the diluted
+	// shares outstanding did not increase.
+	NoDilution bool
+	// DeltaGrossMargin is true if the gross margin increased.
+	DeltaGrossMargin bool
+	// DeltaAssetTurnover is true if the
+	// revenue to assets increased.
+	DeltaAssetTurnover bool
+}
+
+// Signals returns the signals in the order of the fields.
+func (s *FScore) Signals() []bool {
+	return []bool{
+		s.ROA,
+		s.CFO,
+		s.DeltaROA,
+		s.Accrual,
+		s.DeltaLeverage,
+		s.DeltaCurrentRatio,
+		s.NoDilution,
+		s.DeltaGrossMargin,
+		s.DeltaAssetTurnover,
+	}
+}
+
+// Score returns the number of the true signals.
+func (s *FScore) Score() int {
+	n := 0
+	for _, v := range s.Signals() {
+		if v {
+			n++
+		}
+	}
+	return n
+}
+
+// FScore returns the Piotroski F-score of the
+// period versus the previous period.
+func (f *Financials) FScore(period, prev string) *FScore {
+	is := f.IncomeStatement
+	bs := f.BalanceSheet
+	cf := f.CashFlow
+
+	roa := f.ReturnOnAssets(period)
+	roaPrev := f.ReturnOnAssets(prev)
+	cfo := cf.OperatingCashFlow(period)
+	ni := is.NetIncome(period)
+
+	return &FScore{
+		ROA:      roa > 0,
+		CFO:      cfo > 0,
+		DeltaROA: roa > roaPrev,
+		Accrual:  cfo > ni,
+		DeltaLeverage: ratio(bs.LongTermDebt(period), bs.TotalAssets(period)) <=
+			ratio(bs.LongTermDebt(prev), bs.TotalAssets(prev)),
+		DeltaCurrentRatio: ratio(bs.CurrentAssets(period), bs.CurrentLiabilities(period)) >
+			ratio(bs.CurrentAssets(prev), bs.CurrentLiabilities(prev)),
+		NoDilution: is.DilutedSharesOutstanding(period) > 0 &&
+			is.DilutedSharesOutstanding(period) <= is.DilutedSharesOutstanding(prev),
+		DeltaGrossMargin: ratio(is.GrossIncome(period), is.Revenue(period)) >
+			ratio(is.GrossIncome(prev), is.Revenue(prev)),
+		DeltaAssetTurnover: ratio(is.Revenue(period), bs.TotalAssets(period)) >
+			ratio(is.Revenue(prev), bs.TotalAssets(prev)),
+	}
+}
+
+// ZScore is the Altman Z-score, the weighted
+// ratios of the balance sheet of a fiscal year.
+type ZScore struct {
+	// WorkingCapital is the working capital to total assets.
+	WorkingCapital float64
+	// RetainedEarnings is the retained earnings to total assets.
+	RetainedEarnings float64
+	// EBIT is the operating income to total assets.
+	EBIT float64
+	// MarketValue is the market cap to total liabilities.
+	MarketValue float64
+	// Sales is the revenue to total assets.
+	Sales float64
+}
+
+// Score returns the Z-score, below 1.81 is the distress
+// zone, above 2.99 is the safe zone.
+func (s *ZScore) Score() float64 {
+	return 1.2*s.WorkingCapital +
+		1.4*s.RetainedEarnings +
+		3.3*s.EBIT +
+		0.6*s.MarketValue +
+		1.0*s.Sales
+}
+
+// ZScore returns the Altman Z-score of the period
+// with the market cap of the company.
+func (f *Financials) ZScore(period string, marketCap float64) *ZScore {
+	is := f.IncomeStatement
+	bs := f.BalanceSheet
+
+	ta := bs.TotalAssets(period)
+	wc := bs.CurrentAssets(period) - bs.CurrentLiabilities(period)
+	return &ZScore{
+		WorkingCapital:   ratio(wc, ta),
+		RetainedEarnings: ratio(bs.RetainedEarnings(period), ta),
+		EBIT:             ratio(is.OperatingIncome(period), ta),
+		MarketValue:      ratio(marketCap, bs.Liabilities(period)),
+		Sales:            ratio(is.Revenue(period), ta),
+	}
+}
+
+// FScore returns the Piotroski F-score of the latest
+// fiscal year, nil if there is no previous fiscal year.
+func (c *Company) FScore() *FScore {
+	if len(c.FiscalYears) < 2 ||
+		c.FiscalYears[0] == "" ||
+		c.FiscalYears[1] == "" {
+		return nil
+	}
+	return c.Financials.FScore(c.FiscalYears[0], c.FiscalYears[1])
+}
+
+// ZScore returns the Altman Z-score of the latest
+// fiscal year, nil if there is no fiscal year.
+func (c *Company) ZScore() *ZScore {
+	if len(c.FiscalYears) == 0 || c.FiscalYears[0] == "" {
+		return nil
+	}
+	return c.Financials.ZScore(c.FiscalYears[0], c.Realtime.MarketCap)
+}
+
+// ratio returns a divided by b, NaN if b is not positive.
+func ratio(a, b float64) float64 {
+	if !(b > 0) {
+		return math.NaN()
+	}
+	return a / b
+}
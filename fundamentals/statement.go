@@ -0,0 +1,679 @@
+package fundamentals
+
+import (
+	"math"
+	"sort"
+	"strconv"
+	"time"
+)
+
+type Statement struct {
+	ColumnDefs []string         `json:"columnDefs"`
+	Rows       []*StatementRow  `json:"rows"`
+	Footer     *StatementFooter `json:"footer"`
+}
+
+// OrderOfMagnitude returns the multiplier of the values,
+// NaN if the order of magnitude is unknown.
+func (s *Statement) OrderOfMagnitude() float64 {
+	if s.Footer == nil {
+		return math.NaN()
+	}
+	v, err := parseMagnitude(s.Footer.OrderOfMagnitude)
+	if err != nil {
+		return math.NaN()
+	}
+	return v
+}
+
+type StatementRow struct {
+	Label     string          `json:"label"`
+	SubLevels []*StatementRow `json:"subLevel"`
+	Datum     []interface{}   `json:"datum"`
+}
+
+type StatementFooter struct {
+	Currency          string `json:"currency"`
+	CurrencySymbol    string `json:"currencySymbol"`
+	OrderOfMagnitude  string `json:"orderOfMagnitude"`
+	FiscalYearEndDate string `json:"fiscalYearEndDate"`
+}
+
+func (s *Statement) SharesOutstanding(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Diluted Weighted Average Shares Outstanding",
+		s.Rows,
+	)
+}
+
+func (s *Statement) Revenue(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+
+	return s.value(
+		s.periodIndex(period),
+		"Total Revenue",
+		s.Rows,
+	)
+}
+
+func (s *Statement) CostOfRevenue(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+	return s.value(
+		s.periodIndex(period),
+		"Cost of Revenue",
+		s.Rows,
+	)
+}
+
+func (s *Statement) CostOfRevenueToRevenue(
+	period string,
+) float64 {
+	cor := s.CostOfRevenue(period)
+	rev := s.Revenue(period)
+	if rev <= 0 {
+		return math.NaN()
+	}
+	return math.Abs(cor) / rev
+}
+
+func (s *Statement) GrossIncome(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+	// banks, no cost of goods sold
+	netIntInc := s.NetInterestIncome(period)
+	if netIntInc > 0 {
+		return s.Revenue(period)
+	}
+
+	// other companies
+	return s.value(
+		s.periodIndex(period),
+		"Gross Profit",
+		s.Rows,
+	)
+}
+
+func (s *Statement) OperatingIncome(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+	// non bank companies
+	inc := s.value(
+		s.periodIndex(period),
+		"Total Operating Profit/Loss",
+		s.Rows,
+	)
+
+	if inc != 0 {
+		return inc
+	}
+
+	// banks
+	nonIntExp := s.NonInterestExpenses(period)
+	if nonIntExp < 0 {
+		gro := s.GrossIncome(period)
+		return gro + nonIntExp
+	}
+
+	return 0
+}
+
+func (s *Statement) NetInterestIncome(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+	return s.value(
+		s.periodIndex(period),
+		"Net Interest Income",
+		s.Rows,
+	)
+}
+
+func (s *Statement) NonInterestIncome(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+	return s.value(
+		s.periodIndex(period),
+		"Non-Interest Income",
+		s.Rows,
+	)
+}
+
+func (s *Statement) NonInterestExpenses(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+	return s.value(
+		s.periodIndex(period),
+		"Non-Interest Expenses",
+		s.Rows,
+	)
+}
+
+func (s *Statement) OperatingEfficiency(
+	period string,
+) float64 {
+	exp := s.NonInterestExpenses(period)
+	netInc := s.NetInterestIncome(period)
+	nonInc := s.NonInterestIncome(period)
+	return (math.Abs(exp) / (netInc + nonInc)) * 100
+}
+
+func (s *Statement) NetIncome(period string) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+	return s.value(
+		s.periodIndex(period),
+		"Net Income Available to Common Stockholders",
+		s.Rows,
+	)
+}
+
+func (s *Statement) DilutedSharesOutstanding(period string) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+	return s.value(
+		s.periodIndex(period),
+		"Diluted Weighted Average Shares Outstanding",
+		s.Rows,
+	)
+}
+
+func (s *Statement) EarningsPerShare(period string) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+	return s.value(
+		s.periodIndex(period),
+		"Diluted EPS",
+		s.Rows,
+	)
+}
+
+func (s *Statement) RevenuePerShare(period string) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+	sha := s.DilutedSharesOutstanding(period)
+	rev := s.Revenue(period)
+	if sha <= 0 {
+		return math.NaN()
+	}
+	return rev / sha
+}
+
+func (s *Statement) InvestedCapital(
+	period string,
+) float64 {
+	equ := s.Equity(period)
+	debt := s.Debt(period)
+	return equ + debt
+}
+
+func (s *Statement) Equity(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+
+	return s.value(
+		s.periodIndex(period),
+		"Total Equity",
+		s.Rows,
+	)
+}
+
+func (s *Statement) NetTangibleAssets(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+
+	equ := s.Equity(period)
+
+	intan := s.value(
+		s.periodIndex(period),
+		"Net Intangible Assets",
+		s.Rows,
+	)
+
+	return equ - intan
+}
+
+func (s *Statement) Debt(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+	curr := s.value(
+		s.periodIndex(period),
+		"Current Debt and Capital Lease Obligation",
+		s.Rows,
+	)
+
+	long := s.value(
+		s.periodIndex(period),
+		"Long Term Debt and Capital Lease Obligation",
+		s.Rows,
+	)
+
+	// non banks
+	if curr > 0 || long > 0 {
+		return curr + long
+	}
+
+	// banks
+	return s.value(
+		s.periodIndex(period),
+		"Debt and Capital Lease Obligations",
+		s.Rows,
+	)
+}
+
+func (s *Statement) LiabilitiesNoDeposits(
+	period string,
+) float64 {
+	totLia := s.Liabilities(period)
+	totDep := s.Deposits(period)
+	return totLia - totDep
+}
+
+func (s *Statement) Liabilities(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Total Liabilities",
+		s.Rows,
+	)
+}
+
+func (s *Statement) Deposits(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Total Deposits",
+		s.Rows,
+	)
+}
+
+func (s *Statement) TotalAssets(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Total Assets",
+		s.Rows,
+	)
+}
+
+// Loans are the net loans of banks.
+func (s *Statement) Loans(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Loans and Advances",
+		s.Rows,
+	)
+}
+
+// EarningAssets are the interest earning assets of banks,
+// the loans, the investments and the deposits with banks.
+func (s *Statement) EarningAssets(
+	period string,
+) float64 {
+	inv := s.value(
+		s.periodIndex(period),
+		"Total Investments",
+		s.Rows,
+	)
+	dep := s.value(
+		s.periodIndex(period),
+		"Deposits with Banks and Other Financial Institutions",
+		s.Rows,
+	)
+	return s.Loans(period) + inv + dep
+}
+
+func (s *Statement) CurrentAssets(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Total Current Assets",
+		s.Rows,
+	)
+}
+
+func (s *Statement) CurrentLiabilities(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Total Current Liabilities",
+		s.Rows,
+	)
+}
+
+func (s *Statement) RetainedEarnings(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Retained Earnings/Accumulated Deficit",
+		s.Rows,
+	)
+}
+
+func (s *Statement) LongTermDebt(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Long Term Debt and Capital Lease Obligation",
+		s.Rows,
+	)
+}
+
+func (s *Statement) CashAndCashEquivalents(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Cash and Cash Equivalents",
+		s.Rows,
+	)
+}
+
+func (s *Statement) OperatingCashFlow(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+
+	dir := s.value(
+		s.periodIndex(period),
+		"Net Cash Flow from Continuing Operating Activities, Direct",
+		s.Rows,
+	)
+
+	ind := s.value(
+		s.periodIndex(period),
+		"Net Cash Flow from Continuing Operating Activities, Indirect",
+		s.Rows,
+	)
+
+	return dir + ind
+}
+
+func (s *Statement) FreeCashFlow(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+
+	opCash := s.OperatingCashFlow(period)
+
+	pppe := s.value(
+		s.periodIndex(period),
+		"Purchase of Property, Plant and Equipment",
+		s.Rows,
+	)
+
+	capEx := s.value(
+		s.periodIndex(period),
+		"Capital Expenditure, Reported",
+		s.Rows,
+	)
+
+	fcf := opCash + capEx
+	if pppe < 0 {
+		fcf += pppe
+	}
+	return fcf
+}
+
+func (s *Statement) DividendPaid(
+	period string,
+) float64 {
+	if len(s.Rows) == 0 {
+		return 0
+	}
+
+	div := s.value(
+		s.periodIndex(period),
+		"Common Stock Dividends Paid",
+		s.Rows,
+	)
+	if div != 0 {
+		return div
+	}
+
+	// banks
+	return s.value(
+		s.periodIndex(period),
+		"Cash Dividends Paid",
+		s.Rows,
+	)
+}
+
+func (s *Statement) periodIndex(period string) int {
+	for i, v := range s.ColumnDefs {
+		if v == period {
+			return i
+		}
+	}
+	return -1
+}
+
+// unscaledLabels are the per-share and the ratio rows, their
+// values are not scaled by the order of magnitude.
+var unscaledLabels = map[string]bool{
+	"Basic EPS":                                true,
+	"Basic EPS from Continuing Operations":     true,
+	"Basic EPS from Discontinued Operations":   true,
+	"Diluted EPS":                              true,
+	"Diluted EPS from Continuing Operations":   true,
+	"Diluted EPS from Discontinued Operations": true,
+	"Reported Normalized Basic EPS":            true,
+	"Reported Normalized Diluted EPS":          true,
+	"Preferred Dividend Per Share":             true,
+	"Regular Dividend Per Share Calc":          true,
+	"Special Dividend Per Share Calc":          true,
+	"Total Dividend Per Share":                 true,
+	"Reported Effective Tax Rate":              true,
+}
+
+func (s *Statement) value(
+	periodIndex int,
+	label string,
+	rows []*StatementRow,
+) float64 {
+	if periodIndex == -1 {
+		return 0
+	}
+
+	levels := make([]*StatementRow, 0)
+	levels = append(levels, rows...)
+
+	for len(levels) > 0 {
+		next := levels[0]
+		levels = levels[1:]
+
+		if next.Label == label {
+			if periodIndex >= len(next.Datum) {
+				return 0
+			}
+			num, _, err := datumValue(next.Datum[periodIndex])
+			if err != nil {
+				return math.NaN()
+			}
+			if unscaledLabels[label] {
+				return num
+			}
+			return num * s.OrderOfMagnitude()
+		}
+
+		levels = append(levels, next.SubLevels...)
+	}
+	return 0
+}
+
+type Realtime struct {
+	MarketCap float64 `json:"marketCap"`
+	LastPrice float64 `json:"lastPrice"`
+}
+
+const trillion = float64(1000000000000)
+const billion = float64(1000000000)
+const million = float64(1000000)
+const thousand = float64(1000)
+
+func (r *Realtime) MarketCapStr() string {
+	if r.MarketCap > trillion {
+		v := r.MarketCap / trillion
+		return strconv.FormatFloat(v, 'f', 3, 64) + "tr"
+	}
+	if r.MarketCap > billion {
+		v := r.MarketCap / billion
+		return strconv.FormatFloat(v, 'f', 3, 64) + "b"
+	}
+
+	if r.MarketCap > million {
+		v := r.MarketCap / million
+		return strconv.FormatFloat(v, 'f', 3, 64) + "m"
+	}
+
+	v := r.MarketCap / thousand
+	return strconv.FormatFloat(v, 'f', 3, 64) + "th"
+}
+
+type Valuation struct {
+	Collapsed *ValuationCollapsed `json:"Collapsed"`
+}
+
+type ValuationCollapsed struct {
+	ColumnDefs []string        `json:"columnDefs"`
+	Rows       []*ValuationRow `json:"rows"`
+}
+
+type ValuationRow struct {
+	Label string        `json:"label"`
+	Datum []interface{} `json:"datum"`
+}
+
+func (s *Valuation) periodIndex(period string) int {
+	if s.Collapsed == nil {
+		return -1
+	}
+	for i, v := range s.Collapsed.ColumnDefs {
+		if v == period {
+			return i - 1
+		}
+	}
+	return -1
+}
+
+func (s *Valuation) value(
+	periodIndex int,
+	label string,
+) float64 {
+	if periodIndex == -1 {
+		return 0
+	}
+
+	for _, row := range s.Collapsed.Rows {
+		if row.Label == label {
+			if periodIndex >= len(row.Datum) {
+				return 0
+			}
+			num, _, err := datumValue(row.Datum[periodIndex])
+			if err != nil {
+				return math.NaN()
+			}
+			return num
+		}
+	}
+	return 0
+}
+
+func (s *Valuation) PriceToEarnings(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Price/Earnings",
+	)
+}
+
+func (s *Valuation) PriceToBook(
+	period string,
+) float64 {
+	return s.value(
+		s.periodIndex(period),
+		"Price/Book",
+	)
+}
+
+// annualPeriods returns the annual columns of the statement,
+// the latest first. The other columns, e.g. TTM, are skipped.
+func (s *Statement) annualPeriods() []string {
+	periods := make([]string, 0, len(s.ColumnDefs))
+	for _, v := range s.ColumnDefs {
+		if len(v) != 4 {
+			continue
+		}
+		if _, err := strconv.Atoi(v); err != nil {
+			continue
+		}
+		periods = append(periods, v)
+	}
+	sort.Sort(sort.Reverse(sort.StringSlice(periods)))
+	return periods
+}
+
+// FiscalYearEnd returns the end date of the fiscal year
+// of the period, the zero time if it is unknown.
+func (s *Statement) FiscalYearEnd(period string) time.Time {
+	end := "12-31"
+	if s.Footer != nil && s.Footer.FiscalYearEndDate != "" {
+		end = s.Footer.FiscalYearEndDate
+	}
+	t, err := time.Parse("2006-01-02", period+"-"+end)
+	if err != nil {
+		return time.Time{}
+	}
+	return t
+}
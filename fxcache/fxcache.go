@@ -0,0 +1,175 @@
+// Package fxcache caches the exchange rates
+// of a currency service in the database.
+package fxcache
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"szakszon.com/divyield"
+	"szakszon.com/divyield/logger"
+)
+
+type currencyService struct {
+	db       divyield.DB
+	upstream divyield.CurrencyService
+	opts     options
+}
+
+// NewCurrencyService returns a currency service that looks up
+// the rates in the database first and saves the rates
+// fetched from the upstream service. Weekend dates are
+// converted with the rate of the previous business day.
+// If the upstream service fails, the latest stored rate
+// of the lookback period is used.
+func NewCurrencyService(
+	db divyield.DB,
+	upstream divyield.CurrencyService,
+	os ...Option,
+) divyield.CurrencyService {
+	opts := defaultOptions
+	for _, o := range os {
+		opts = o(opts)
+	}
+
+	return &currencyService{
+		db:       db,
+		upstream: upstream,
+		opts:     opts,
+	}
+}
+
+func (s *currencyService) Convert(
+	ctx context.Context,
+	in *divyield.CurrencyConvertInput,
+) (*divyield.CurrencyConvertOutput, error) {
+	from := strings.ToUpper(in.From)
+	to := strings.ToUpper(in.To)
+	if from == to {
+		return &divyield.CurrencyConvertOutput{
+			Amount: in.Amount,
+			Rate:   1,
+		}, nil
+	}
+
+	date := BusinessDay(in.Date)
+
+	rout, err := s.db.FXRates(ctx, &divyield.DBFXRatesInput{
+		From:  from,
+		To:    to,
+		Since: date.AddDate(0, 0, -s.opts.lookbackDays),
+		Until: date,
+		Limit: 1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("get fx rates: %v", err)
+	}
+
+	var stored *divyield.FXRate
+	if len(rout.Rates) > 0 {
+		stored = rout.Rates[0]
+		if stored.Date.Equal(date) {
+			return output(in.Amount, stored.Rate), nil
+		}
+	}
+
+	uout, err := s.upstream.Convert(
+		ctx,
+		&divyield.CurrencyConvertInput{
+			From:   from,
+			To:     to,
+			Amount: 1,
+			Date:   date,
+		},
+	)
+	if err != nil {
+		if stored == nil {
+			return nil, err
+		}
+		s.logf(
+			"%v/%v %v: %v, use the rate of %v",
+			from,
+			to,
+			date.Format(divyield.DateFormat),
+			err,
+			stored.Date.Format(divyield.DateFormat),
+		)
+		return output(in.Amount, stored.Rate), nil
+	}
+
+	_, err = s.db.SaveFXRates(ctx, &divyield.DBSaveFXRatesInput{
+		Rates: []*divyield.FXRate{
+			{
+				From: from,
+				To:   to,
+				Date: date,
+				Rate: uout.Rate,
+			},
+		},
+	})
+	if err != nil {
+		return nil, fmt.Errorf("save fx rate: %v", err)
+	}
+	return output(in.Amount, uout.Rate), nil
+}
+
+func output(amount, rate float64) *divyield.CurrencyConvertOutput {
+	return &divyield.CurrencyConvertOutput{
+		Amount: amount * rate,
+		Rate:   rate,
+	}
+}
+
+// BusinessDay returns the date itself on weekdays
+// and the previous Friday on weekends.
+func BusinessDay(t time.Time) time.Time {
+	d := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch d.Weekday() {
+	case time.Saturday:
+		return d.AddDate(0, 0, -1)
+	case time.Sunday:
+		return d.AddDate(0, 0, -2)
+	default:
+		return d
+	}
+}
+
+func (s *currencyService) logf(
+	format string,
+	v ...interface{},
+) {
+	w := s.opts.logger
+	if w != nil {
+		w.Logf(format, v...)
+	}
+}
+
+var defaultOptions = options{
+	lookbackDays: 7,
+	logger:       nil,
+}
+
+type options struct {
+	lookbackDays int
+	logger       logger.Logger
+}
+
+type Option func(o options) options
+
+// LookbackDays sets the number of days before the date
+// in which a stored rate is used when the upstream fails.
+func LookbackDays(v int) Option {
+	return func(o options) options {
+		o.lookbackDays = v
+		return o
+	}
+}
+
+func Logger(v logger.Logger) Option {
+	return func(o options) options {
+		o.logger = v
+		return o
+	}
+}
@@ -0,0 +1,174 @@
+// Package fxstore converts currencies with the exchange
+// rates stored in the database only, so it works offline.
+package fxstore
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"szakszon.com/divyield"
+)
+
+const (
+	// InterpolationNone uses the rate of the date only.
+	InterpolationNone = "none"
+
+	// InterpolationPrevious uses the latest rate
+	// not after the date.
+	InterpolationPrevious = "previous"
+
+	// InterpolationLinear interpolates between the rates
+	// before and after the date. The previous rate is used
+	// if there is no rate after the date.
+	InterpolationLinear = "linear"
+)
+
+type currencyService struct {
+	db   divyield.DB
+	opts options
+}
+
+func NewCurrencyService(
+	db divyield.DB,
+	os ...Option,
+) divyield.CurrencyService {
+	opts := defaultOptions
+	for _, o := range os {
+		opts = o(opts)
+	}
+
+	return &currencyService{
+		db:   db,
+		opts: opts,
+	}
+}
+
+func (s *currencyService) Convert(
+	ctx context.Context,
+	in *divyield.CurrencyConvertInput,
+) (*divyield.CurrencyConvertOutput, error) {
+	from := strings.ToUpper(in.From)
+	to := strings.ToUpper(in.To)
+	if from == to {
+		return &divyield.CurrencyConvertOutput{
+			Amount: in.Amount,
+			Rate:   1,
+		}, nil
+	}
+
+	date := time.Date(
+		in.Date.Year(), in.Date.Month(), in.Date.Day(),
+		0, 0, 0, 0, time.UTC,
+	)
+
+	rate, found, err := s.rate(ctx, from, to, date)
+	if err != nil {
+		return nil, err
+	}
+	if !found {
+		// the inverse pair may be stored only
+		rate, found, err = s.rate(ctx, to, from, date)
+		if err != nil {
+			return nil, err
+		}
+		if found {
+			rate = 1 / rate
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf(
+			"no stored rate %v/%v on %v",
+			from,
+			to,
+			date.Format(divyield.DateFormat),
+		)
+	}
+
+	return &divyield.CurrencyConvertOutput{
+		Amount: in.Amount * rate,
+		Rate:   rate,
+	}, nil
+}
+
+func (s *currencyService) rate(
+	ctx context.Context,
+	from string,
+	to string,
+	date time.Time,
+) (float64, bool, error) {
+	lookback := s.opts.lookbackDays
+	if s.opts.interpolation == InterpolationNone {
+		lookback = 0
+	}
+
+	prev, err := s.db.FXRates(ctx, &divyield.DBFXRatesInput{
+		From:  from,
+		To:    to,
+		Since: date.AddDate(0, 0, -lookback),
+		Until: date,
+		Limit: 1,
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("get fx rates: %v", err)
+	}
+	if len(prev.Rates) == 0 {
+		return 0, false, nil
+	}
+	r0 := prev.Rates[0]
+
+	if r0.Date.Equal(date) ||
+		s.opts.interpolation != InterpolationLinear {
+		return r0.Rate, true, nil
+	}
+
+	next, err := s.db.FXRates(ctx, &divyield.DBFXRatesInput{
+		From:  from,
+		To:    to,
+		Since: date,
+		Until: date.AddDate(0, 0, lookback),
+	})
+	if err != nil {
+		return 0, false, fmt.Errorf("get fx rates: %v", err)
+	}
+	if len(next.Rates) == 0 {
+		return r0.Rate, true, nil
+	}
+	// the rates are ordered by date descending
+	r1 := next.Rates[len(next.Rates)-1]
+
+	span := r1.Date.Sub(r0.Date).Hours()
+	elapsed := date.Sub(r0.Date).Hours()
+	return r0.Rate + (r1.Rate-r0.Rate)*elapsed/span, true, nil
+}
+
+var defaultOptions = options{
+	interpolation: InterpolationPrevious,
+	lookbackDays:  7,
+}
+
+type options struct {
+	interpolation string
+	lookbackDays  int
+}
+
+type Option func(o options) options
+
+// Interpolation sets how the missing dates are handled:
+// InterpolationNone, InterpolationPrevious or InterpolationLinear.
+func Interpolation(v string) Option {
+	return func(o options) options {
+		o.interpolation = v
+		return o
+	}
+}
+
+// LookbackDays sets the maximum number of days between the date
+// and the stored rates used for the interpolation.
+func LookbackDays(v int) Option {
+	return func(o options) options {
+		o.lookbackDays = v
+		return o
+	}
+}
@@ -0,0 +1,294 @@
+package iexcloud
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"szakszon.com/divyield"
+)
+
+const (
+	endpointCompany   = "company"
+	endpointISIN      = "ref-data/isin"
+	endpointExchanges = "ref-data/exchanges"
+	endpointSplits    = "time-series/SPLITS"
+	endpointDividends = "time-series/DIVIDENDS"
+	endpointPrices    = "time-series/HISTORICAL_PRICES"
+)
+
+// creditWeights are the message credits of a row of
+// the endpoints, used only to estimate the cost of a pull.
+// The consumed credits are read from the responses.
+var creditWeights = map[string]int64{
+	endpointCompany:   1,
+	endpointISIN:      100,
+	endpointExchanges: 1,
+	endpointSplits:    10,
+	endpointDividends: 10,
+	endpointPrices:    10,
+}
+
+const creditsHeader = "iexcloud-messages-used"
+
+const creditsMonthFormat = "2006-01"
+
+type credits struct {
+	mu sync.Mutex
+
+	// used are the endpoints of the services created
+	used map[string]bool
+
+	run map[string]*divyield.CreditUsage
+
+	// saved are the credits of the endpoints
+	// already added to the monthly total
+	saved map[string]int64
+}
+
+func newCredits() *credits {
+	return &credits{
+		used:  make(map[string]bool),
+		run:   make(map[string]*divyield.CreditUsage),
+		saved: make(map[string]int64),
+	}
+}
+
+func (cr *credits) use(endpoint string) {
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	cr.used[endpoint] = true
+}
+
+func (cr *credits) record(endpoint string, resp *http.Response) {
+	n, _ := strconv.ParseInt(resp.Header.Get(creditsHeader), 10, 64)
+
+	cr.mu.Lock()
+	defer cr.mu.Unlock()
+	u, ok := cr.run[endpoint]
+	if !ok {
+		u = &divyield.CreditUsage{Endpoint: endpoint}
+		cr.run[endpoint] = u
+	}
+	u.Requests++
+	u.Credits += n
+}
+
+func (cr *credits) total() int64 {
+	var n int64
+	for _, u := range cr.run {
+		n += u.Credits
+	}
+	return n
+}
+
+// unsaved returns the credits not yet
+// added to the monthly total.
+func (cr *credits) unsaved() int64 {
+	var n int64
+	for endpoint, u := range cr.run {
+		n += u.Credits - cr.saved[endpoint]
+	}
+	return n
+}
+
+// NewCreditService returns the message credit accounting of
+// the services of the client. The monthly totals are
+// persisted in the credits file if it is set.
+func (c *IEXCloud) NewCreditService() divyield.CreditService {
+	return &creditService{
+		IEXCloud: c,
+	}
+}
+
+type creditService struct {
+	*IEXCloud
+}
+
+func (s *creditService) Estimate(
+	ctx context.Context,
+	in *divyield.CreditEstimateInput,
+) (*divyield.CreditEstimateOutput, error) {
+	s.credits.mu.Lock()
+	used := make(map[string]bool, len(s.credits.used))
+	for k, v := range s.credits.used {
+		used[k] = v
+	}
+	s.credits.mu.Unlock()
+
+	now := time.Now().UTC()
+	var n int64
+	if in.Exchanges && used[endpointExchanges] {
+		n += creditWeights[endpointExchanges]
+	}
+	for _, v := range in.Symbols {
+		if used[endpointCompany] {
+			n += creditWeights[endpointCompany]
+		}
+		if used[endpointSplits] {
+			// splits are rare, count one row
+			n += creditWeights[endpointSplits]
+		}
+		if used[endpointDividends] {
+			rows := int64(now.Sub(v.FromDividends).Hours() / 24 / 365 * 4)
+			if rows < 1 {
+				rows = 1
+			}
+			n += rows * creditWeights[endpointDividends]
+		}
+		if used[endpointPrices] {
+			n += weekdays(v.FromPrices, now) * creditWeights[endpointPrices]
+		}
+	}
+
+	return &divyield.CreditEstimateOutput{
+		Credits: n,
+	}, nil
+}
+
+// weekdays returns the number of the weekdays
+// between the dates, an estimate of the trading days.
+func weekdays(from, until time.Time) int64 {
+	from = timeDate(from)
+	until = timeDate(until)
+	if from.After(until) {
+		return 0
+	}
+
+	days := int64(until.Sub(from).Hours()/24) + 1
+	n := days / 7 * 5
+	d := from.AddDate(0, 0, int(days/7*7))
+	for ; !d.After(until); d = d.AddDate(0, 0, 1) {
+		if d.Weekday() != time.Saturday && d.Weekday() != time.Sunday {
+			n++
+		}
+	}
+	return n
+}
+
+func (s *creditService) Usage(
+	ctx context.Context,
+	in *divyield.CreditUsageInput,
+) (*divyield.CreditUsageOutput, error) {
+	month := time.Now().UTC().Format(creditsMonthFormat)
+	months, err := s.readCredits()
+	if err != nil {
+		return nil, err
+	}
+
+	s.credits.mu.Lock()
+	defer s.credits.mu.Unlock()
+
+	out := &divyield.CreditUsageOutput{
+		Endpoints: make([]*divyield.CreditUsage, 0, len(s.credits.run)),
+		Credits:   s.credits.total(),
+		Month:     month,
+	}
+	for _, u := range s.credits.run {
+		v := *u
+		out.Endpoints = append(out.Endpoints, &v)
+	}
+	sort.Slice(out.Endpoints, func(i, j int) bool {
+		return out.Endpoints[i].Endpoint < out.Endpoints[j].Endpoint
+	})
+
+	out.MonthCredits = s.credits.unsaved()
+	if m, ok := months[month]; ok {
+		out.MonthCredits += m.Credits
+	}
+	return out, nil
+}
+
+func (s *creditService) Save(
+	ctx context.Context,
+	in *divyield.CreditSaveInput,
+) (*divyield.CreditSaveOutput, error) {
+	if s.opts.creditsFile == "" {
+		return &divyield.CreditSaveOutput{}, nil
+	}
+
+	months, err := s.readCredits()
+	if err != nil {
+		return nil, err
+	}
+
+	s.credits.mu.Lock()
+	defer s.credits.mu.Unlock()
+
+	month := time.Now().UTC().Format(creditsMonthFormat)
+	m, ok := months[month]
+	if !ok {
+		m = &creditsMonth{
+			Endpoints: make(map[string]int64),
+		}
+		months[month] = m
+	}
+	m.Credits += s.credits.unsaved()
+	for endpoint, u := range s.credits.run {
+		m.Endpoints[endpoint] += u.Credits - s.credits.saved[endpoint]
+	}
+	m.Updated = time.Now().UTC()
+
+	b, err := json.MarshalIndent(months, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("save credits: %v", err)
+	}
+	err = os.MkdirAll(filepath.Dir(s.opts.creditsFile), 0700)
+	if err != nil {
+		return nil, fmt.Errorf("save credits: %v", err)
+	}
+	err = ioutil.WriteFile(s.opts.creditsFile, b, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("save credits: %v", err)
+	}
+	for endpoint, u := range s.credits.run {
+		s.credits.saved[endpoint] = u.Credits
+	}
+
+	return &divyield.CreditSaveOutput{}, nil
+}
+
+// creditsMonth is the monthly total of the credits file.
+type creditsMonth struct {
+	Credits   int64            `json:"credits"`
+	Endpoints map[string]int64 `json:"endpoints"`
+	Updated   time.Time        `json:"updated"`
+}
+
+func (s *creditService) readCredits() (map[string]*creditsMonth, error) {
+	months := make(map[string]*creditsMonth)
+	if s.opts.creditsFile == "" {
+		return months, nil
+	}
+
+	b, err := ioutil.ReadFile(s.opts.creditsFile)
+	if os.IsNotExist(err) {
+		return months, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read credits: %v", err)
+	}
+
+	err = json.Unmarshal(b, &months)
+	if err != nil {
+		return nil, fmt.Errorf(
+			"read credits: %v: %v",
+			s.opts.creditsFile,
+			err,
+		)
+	}
+	for _, m := range months {
+		if m.Endpoints == nil {
+			m.Endpoints = make(map[string]int64)
+		}
+	}
+	return months, nil
+}
@@ -21,6 +21,7 @@ import (
 type IEXCloud struct {
 	opts       options
 	httpClient *httprate.RLClient
+	credits    *credits
 }
 
 func NewIEXCloud(os ...Option) *IEXCloud {
@@ -39,6 +40,7 @@ func NewIEXCloud(os ...Option) *IEXCloud {
 	return &IEXCloud{
 		opts:       opts,
 		httpClient: httpClient,
+		credits:    newCredits(),
 	}
 }
 
@@ -103,6 +105,7 @@ func (c *IEXCloud) pricesURL(
 
 func (c *IEXCloud) httpGet(
 	ctx context.Context,
+	endpoint string,
 	u string,
 ) (*http.Response, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
@@ -113,6 +116,7 @@ func (c *IEXCloud) httpGet(
 	if err != nil {
 		return nil, err
 	}
+	c.credits.record(endpoint, resp)
 
 	if resp.StatusCode < 200 || 299 < resp.StatusCode {
 		defer resp.Body.Close()
@@ -126,6 +130,7 @@ func (c *IEXCloud) httpGet(
 }
 
 func (c *IEXCloud) NewPriceService() divyield.PriceService {
+	c.credits.use(endpointPrices)
 	return &priceService{
 		IEXCloud: c,
 	}
@@ -140,7 +145,7 @@ func (s *priceService) Fetch(
 	in *divyield.PriceFetchInput,
 ) (*divyield.PriceFetchOutput, error) {
 	u := s.pricesURL(in.Symbol, in.From)
-	resp, err := s.httpGet(ctx, u)
+	resp, err := s.httpGet(ctx, endpointPrices, u)
 	if err != nil {
 		return nil, err
 	}
@@ -233,6 +238,7 @@ func (p *price) String() string {
 }
 
 func (c *IEXCloud) NewDividendService() divyield.DividendService {
+	c.credits.use(endpointDividends)
 	return &dividendService{
 		IEXCloud: c,
 	}
@@ -247,7 +253,7 @@ func (s *dividendService) Fetch(
 	in *divyield.DividendFetchInput,
 ) (*divyield.DividendFetchOutput, error) {
 	u := s.dividendsURL(in.Symbol, in.From)
-	resp, err := s.httpGet(ctx, u)
+	resp, err := s.httpGet(ctx, endpointDividends, u)
 	if err != nil {
 		return nil, err
 	}
@@ -390,6 +396,7 @@ func (d *dividend) FrequencyNumber() int {
 }
 
 func (c *IEXCloud) NewSplitService() divyield.SplitService {
+	c.credits.use(endpointSplits)
 	return &splitService{
 		IEXCloud: c,
 	}
@@ -404,7 +411,7 @@ func (s *splitService) Fetch(
 	in *divyield.SplitFetchInput,
 ) (*divyield.SplitFetchOutput, error) {
 	u := s.splitsURL(in.Symbol, in.From)
-	resp, err := s.httpGet(ctx, u)
+	resp, err := s.httpGet(ctx, endpointSplits, u)
 	if err != nil {
 		return nil, err
 	}
@@ -418,7 +425,7 @@ func (s *splitService) Fetch(
 
 	splits, err := s.parseSplits(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("parse splits: %s", in.Symbol, err)
+		return nil, fmt.Errorf("parse splits: %s", err)
 	}
 	sortSplitsDesc(splits)
 
@@ -484,6 +491,7 @@ type split struct {
 }
 
 func (c *IEXCloud) NewProfileService() divyield.ProfileService {
+	c.credits.use(endpointCompany)
 	return &profileService{
 		IEXCloud: c,
 	}
@@ -499,7 +507,7 @@ func (s *profileService) Fetch(
 ) (*divyield.ProfileFetchOutput, error) {
 
 	u := s.companyURL(in.Symbol)
-	resp, err := s.httpGet(ctx, u)
+	resp, err := s.httpGet(ctx, endpointCompany, u)
 	if err != nil {
 		return nil, err
 	}
@@ -585,6 +593,7 @@ type profile struct {
 }
 
 func (c *IEXCloud) NewISINService() divyield.ISINService {
+	c.credits.use(endpointISIN)
 	return &isinService{
 		IEXCloud: c,
 	}
@@ -600,7 +609,7 @@ func (s *isinService) Resolve(
 ) (*divyield.ISINResolveOutput, error) {
 
 	u := s.isinMappingURL(in.ISIN)
-	resp, err := s.httpGet(ctx, u)
+	resp, err := s.httpGet(ctx, endpointISIN, u)
 	if err != nil {
 		return nil, err
 	}
@@ -693,6 +702,7 @@ type symbolISIN struct {
 }
 
 func (c *IEXCloud) NewExchangeService() divyield.ExchangeService {
+	c.credits.use(endpointExchanges)
 	return &exchangeService{
 		IEXCloud: c,
 	}
@@ -708,7 +718,7 @@ func (s *exchangeService) Fetch(
 ) (*divyield.ExchangeFetchOutput, error) {
 
 	u := s.internationalExchangesURL()
-	resp, err := s.httpGet(ctx, u)
+	resp, err := s.httpGet(ctx, endpointExchanges, u)
 	if err != nil {
 		return nil, err
 	}
@@ -842,6 +852,8 @@ type options struct {
 	logger            logger.Logger
 	workers           int
 	db                divyield.DB
+
+	creditsFile string
 }
 
 type Option func(o options) options
@@ -923,6 +935,15 @@ func Force(f bool) Option {
 	}
 }
 
+// CreditsFile is the JSON file of the monthly
+// totals of the consumed message credits.
+func CreditsFile(v string) Option {
+	return func(o options) options {
+		o.creditsFile = v
+		return o
+	}
+}
+
 func Log(l logger.Logger) Option {
 	return func(o options) options {
 		o.logger = l
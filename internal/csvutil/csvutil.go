@@ -0,0 +1,39 @@
+// Package csvutil reads the CSV files of the imports
+// and the file provider.
+package csvutil
+
+import (
+	"bufio"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// ReadDelimited reads the records of a CSV file whose
+// fields are separated by comma or semicolon, the separator
+// of the first line is used. The fieldsPerRecord is like
+// that of csv.Reader, negative if the records may vary.
+func ReadDelimited(
+	in io.Reader,
+	fieldsPerRecord int,
+) ([][]string, error) {
+	br := bufio.NewReader(in)
+	first, err := br.Peek(256)
+	if err != nil && err != io.EOF && err != bufio.ErrBufferFull {
+		return nil, err
+	}
+
+	r := csv.NewReader(br)
+	if line := strings.SplitN(string(first), "\n", 2)[0]; strings.Contains(line, ";") {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = fieldsPerRecord
+	r.TrimLeadingSpace = true
+
+	records, err := r.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %v", err)
+	}
+	return records, nil
+}
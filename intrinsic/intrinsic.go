@@ -0,0 +1,139 @@
+// Package intrinsic estimates the fair price of a share with
+// the dividend discount model, the two-stage discounted cash
+// flow model and the Graham number. The rates are percentages.
+package intrinsic
+
+import (
+	"math"
+)
+
+// Model is the discount and growth rates of the valuations.
+type Model struct {
+	opts options
+}
+
+// New returns a model of the options.
+func New(os ...Option) *Model {
+	opts := defaultOptions
+	for _, o := range os {
+		opts = o(opts)
+	}
+	return &Model{opts: opts}
+}
+
+// DividendDiscount returns the fair price of the forward
+// dividend of the next 12 months growing at the dividend
+// growth rate forever, or NaN if the growth rate is not
+// below the discount rate.
+func (m *Model) DividendDiscount(divFwd float64) float64 {
+	r := m.opts.discountRate / 100
+	g := m.opts.dividendGrowthRate / 100
+	if divFwd <= 0 || r <= g {
+		return math.NaN()
+	}
+	return divFwd / (r - g)
+}
+
+// DiscountedCashFlow returns the fair price of the free cash
+// flow per share growing at the growth rate for the years of
+// the first stage, then at the terminal growth rate forever.
+// It returns NaN if the free cash flow is not positive.
+func (m *Model) DiscountedCashFlow(fcfps float64) float64 {
+	r := m.opts.discountRate / 100
+	g := m.opts.growthRate / 100
+	tg := m.opts.terminalGrowthRate / 100
+	if !(fcfps > 0) || r <= tg {
+		return math.NaN()
+	}
+
+	pv := float64(0)
+	cf := fcfps
+	for t := 1; t <= m.opts.years; t++ {
+		cf *= 1 + g
+		pv += cf / math.Pow(1+r, float64(t))
+	}
+
+	tv := cf * (1 + tg) / (r - tg)
+	pv += tv / math.Pow(1+r, float64(m.opts.years))
+	return pv
+}
+
+// GrahamNumber returns the maximum price a defensive investor
+// pays, the square root of 22.5 times EPS times BVPS, or NaN
+// if the earnings or the book value are not positive.
+func GrahamNumber(eps, bvps float64) float64 {
+	if !(eps > 0) || !(bvps > 0) {
+		return math.NaN()
+	}
+	return math.Sqrt(22.5 * eps * bvps)
+}
+
+// MarginOfSafety returns the discount of the price to the fair
+// price as a percentage, negative if the price is above it.
+func MarginOfSafety(value, price float64) float64 {
+	if !(value > 0) || !(price > 0) {
+		return math.NaN()
+	}
+	return (value - price) / value * 100
+}
+
+var defaultOptions = options{
+	discountRate:       10,
+	dividendGrowthRate: 4,
+	growthRate:         5,
+	terminalGrowthRate: 2.5,
+	years:              10,
+}
+
+type options struct {
+	discountRate       float64
+	dividendGrowthRate float64
+	growthRate         float64
+	terminalGrowthRate float64
+	years              int
+}
+
+type Option func(o options) options
+
+// DiscountRate is the required rate of return.
+func DiscountRate(v float64) Option {
+	return func(o options) options {
+		o.discountRate = v
+		return o
+	}
+}
+
+// DividendGrowthRate is the growth rate of the dividends
+// of the dividend discount model.
+func DividendGrowthRate(v float64) Option {
+	return func(o options) options {
+		o.dividendGrowthRate = v
+		return o
+	}
+}
+
+// GrowthRate is the growth rate of the free cash
+// flow in the first stage.
+func GrowthRate(v float64) Option {
+	return func(o options) options {
+		o.growthRate = v
+		return o
+	}
+}
+
+// TerminalGrowthRate is the growth rate of the free
+// cash flow after the first stage.
+func TerminalGrowthRate(v float64) Option {
+	return func(o options) options {
+		o.terminalGrowthRate = v
+		return o
+	}
+}
+
+// Years is the number of the years of the first stage.
+func Years(v int) Option {
+	return func(o options) options {
+		o.years = v
+		return o
+	}
+}
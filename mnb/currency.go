@@ -0,0 +1,354 @@
+package mnb
+
+// The official exchange rates of the Hungarian National Bank
+// are published by the arfolyamok.asmx SOAP web service.
+// Every rate is the price of the given units of a currency
+// in HUF, the cross rates are calculated via HUF.
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+	"time"
+
+	"szakszon.com/divyield"
+	"szakszon.com/divyield/logger"
+)
+
+const currencyHUF = "HUF"
+
+type currencyService struct {
+	client *http.Client
+	opts   currencyOptions
+}
+
+func NewCurrencyService(os ...CurrencyOption) divyield.CurrencyService {
+	opts := defaultCurrencyOptions
+	for _, o := range os {
+		opts = o(opts)
+	}
+
+	return &currencyService{
+		client: &http.Client{
+			Timeout: opts.timeout,
+		},
+		opts: opts,
+	}
+}
+
+func (s *currencyService) Convert(
+	ctx context.Context,
+	in *divyield.CurrencyConvertInput,
+) (*divyield.CurrencyConvertOutput, error) {
+	from := strings.ToUpper(in.From)
+	to := strings.ToUpper(in.To)
+	if from == to {
+		return &divyield.CurrencyConvertOutput{
+			Amount: in.Amount,
+			Rate:   1,
+		}, nil
+	}
+
+	currencies := make([]string, 0, 2)
+	for _, c := range []string{from, to} {
+		if c != currencyHUF {
+			currencies = append(currencies, c)
+		}
+	}
+
+	// the bank does not publish rates on weekends and holidays
+	date := in.Date
+	days, err := s.fetch(
+		ctx,
+		date.AddDate(0, 0, -s.opts.lookbackDays),
+		date,
+		currencies,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	rate, err := crossRate(days, date, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	return &divyield.CurrencyConvertOutput{
+		Amount: in.Amount * rate,
+		Rate:   rate,
+	}, nil
+}
+
+func (s *currencyService) fetch(
+	ctx context.Context,
+	start time.Time,
+	end time.Time,
+	currencies []string,
+) ([]*dailyRates, error) {
+	body := &bytes.Buffer{}
+	err := exchangeRatesRequestTmpl.Execute(body, map[string]string{
+		"StartDate":  start.Format(divyield.DateFormat),
+		"EndDate":    end.Format(divyield.DateFormat),
+		"Currencies": strings.Join(currencies, ","),
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequestWithContext(
+		ctx,
+		http.MethodPost,
+		s.opts.baseURL,
+		body,
+	)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+	req.Header.Set("Content-Type", "text/xml; charset=utf-8")
+	req.Header.Set(
+		"SOAPAction",
+		"http://www.mnb.hu/webservices/"+
+			"MNBArfolyamServiceSoap/GetExchangeRates",
+	)
+
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	s.logf(
+		"%v %v %v..%v %v",
+		resp.StatusCode,
+		s.opts.baseURL,
+		start.Format(divyield.DateFormat),
+		end.Format(divyield.DateFormat),
+		strings.Join(currencies, ","),
+	)
+
+	if resp.StatusCode < 200 || 299 < resp.StatusCode {
+		return nil, fmt.Errorf(
+			"http error: %d",
+			resp.StatusCode,
+		)
+	}
+
+	return parseExchangeRatesResponse(resp.Body)
+}
+
+type dailyRates struct {
+	Date time.Time
+
+	// Rates are the prices of one unit
+	// of the currencies in HUF.
+	Rates map[string]float64
+}
+
+type exchangeRatesEnvelope struct {
+	Body struct {
+		Response struct {
+			Result string `xml:"GetExchangeRatesResult"`
+		} `xml:"GetExchangeRatesResponse"`
+		Fault *struct {
+			String string `xml:"faultstring"`
+		} `xml:"Fault"`
+	} `xml:"Body"`
+}
+
+type exchangeRates struct {
+	Days []struct {
+		Date  string `xml:"date,attr"`
+		Rates []struct {
+			Unit     string `xml:"unit,attr"`
+			Currency string `xml:"curr,attr"`
+			Value    string `xml:",chardata"`
+		} `xml:"Rate"`
+	} `xml:"Day"`
+}
+
+// parseExchangeRatesResponse parses the SOAP response
+// of the GetExchangeRates operation.
+func parseExchangeRatesResponse(r io.Reader) ([]*dailyRates, error) {
+	env := &exchangeRatesEnvelope{}
+	err := xml.NewDecoder(r).Decode(env)
+	if err != nil {
+		return nil, fmt.Errorf("decode envelope: %v", err)
+	}
+	if env.Body.Fault != nil {
+		return nil, fmt.Errorf("soap fault: %v", env.Body.Fault.String)
+	}
+	return parseExchangeRates(strings.NewReader(env.Body.Response.Result))
+}
+
+// parseExchangeRates parses the MNBExchangeRates document.
+// The values use decimal comma.
+func parseExchangeRates(r io.Reader) ([]*dailyRates, error) {
+	rates := &exchangeRates{}
+	err := xml.NewDecoder(r).Decode(rates)
+	if err != nil {
+		return nil, fmt.Errorf("decode rates: %v", err)
+	}
+
+	days := make([]*dailyRates, 0, len(rates.Days))
+	for _, d := range rates.Days {
+		date, err := time.Parse(divyield.DateFormat, d.Date)
+		if err != nil {
+			return nil, fmt.Errorf("parse date: %v", err)
+		}
+
+		day := &dailyRates{
+			Date:  date,
+			Rates: make(map[string]float64),
+		}
+		for _, v := range d.Rates {
+			value, err := strconv.ParseFloat(
+				strings.ReplaceAll(strings.TrimSpace(v.Value), ",", "."),
+				64,
+			)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"%v %v: parse rate: %v",
+					d.Date,
+					v.Currency,
+					err,
+				)
+			}
+
+			unit := 1.0
+			if v.Unit != "" {
+				unit, err = strconv.ParseFloat(v.Unit, 64)
+				if err != nil || unit == 0 {
+					return nil, fmt.Errorf(
+						"%v %v: invalid unit: %v",
+						d.Date,
+						v.Currency,
+						v.Unit,
+					)
+				}
+			}
+			day.Rates[strings.ToUpper(v.Currency)] = value / unit
+		}
+		days = append(days, day)
+	}
+
+	sort.SliceStable(days, func(i, j int) bool {
+		return days[i].Date.After(days[j].Date)
+	})
+	return days, nil
+}
+
+// crossRate returns the rate of the latest day
+// not after the date that has both currencies.
+func crossRate(
+	days []*dailyRates,
+	date time.Time,
+	from string,
+	to string,
+) (float64, error) {
+	for _, d := range days {
+		if d.Date.After(date) {
+			continue
+		}
+
+		fromHUF, ok := d.huf(from)
+		if !ok {
+			continue
+		}
+		toHUF, ok := d.huf(to)
+		if !ok {
+			continue
+		}
+		return fromHUF / toHUF, nil
+	}
+	return 0, fmt.Errorf(
+		"no rate %v/%v on %v",
+		from,
+		to,
+		date.Format(divyield.DateFormat),
+	)
+}
+
+func (d *dailyRates) huf(currency string) (float64, bool) {
+	if currency == currencyHUF {
+		return 1, true
+	}
+	v, ok := d.Rates[currency]
+	return v, ok && v > 0
+}
+
+func (s *currencyService) logf(
+	format string,
+	v ...interface{},
+) {
+	w := s.opts.logger
+	if w != nil {
+		w.Logf(format, v...)
+	}
+}
+
+var defaultCurrencyOptions = currencyOptions{
+	baseURL:      "http://www.mnb.hu/arfolyamok.asmx",
+	timeout:      30 * time.Second,
+	lookbackDays: 7,
+	logger:       nil,
+}
+
+type currencyOptions struct {
+	baseURL      string
+	timeout      time.Duration
+	lookbackDays int
+	logger       logger.Logger
+}
+
+type CurrencyOption func(o currencyOptions) currencyOptions
+
+func BaseURL(v string) CurrencyOption {
+	return func(o currencyOptions) currencyOptions {
+		o.baseURL = v
+		return o
+	}
+}
+
+func Timeout(v time.Duration) CurrencyOption {
+	return func(o currencyOptions) currencyOptions {
+		o.timeout = v
+		return o
+	}
+}
+
+// LookbackDays sets the number of days before the date
+// searched for the latest published rate.
+func LookbackDays(v int) CurrencyOption {
+	return func(o currencyOptions) currencyOptions {
+		o.lookbackDays = v
+		return o
+	}
+}
+
+func Logger(v logger.Logger) CurrencyOption {
+	return func(o currencyOptions) currencyOptions {
+		o.logger = v
+		return o
+	}
+}
+
+var exchangeRatesRequestTmpl = template.Must(template.New("").Parse(
+	`<?xml version="1.0" encoding="utf-8"?>
+<soap:Envelope xmlns:soap="http://schemas.xmlsoap.org/soap/envelope/" xmlns:web="http://www.mnb.hu/webservices/">
+  <soap:Body>
+    <web:GetExchangeRates>
+      <web:startDate>{{.StartDate}}</web:startDate>
+      <web:endDate>{{.EndDate}}</web:endDate>
+      <web:currencyNames>{{.Currencies}}</web:currencyNames>
+    </web:GetExchangeRates>
+  </soap:Body>
+</soap:Envelope>`,
+))
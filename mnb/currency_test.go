@@ -0,0 +1,216 @@
+package mnb
+
+import (
+	"context"
+	"io/ioutil"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"szakszon.com/divyield"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(divyield.DateFormat, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func equal(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+func loadRates(t *testing.T, name string) []*dailyRates {
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	days, err := parseExchangeRatesResponse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return days
+}
+
+func TestParseExchangeRatesResponse(t *testing.T) {
+	days := loadRates(t, "GetExchangeRates.xml")
+	if len(days) != 5 {
+		t.Fatalf("days: got %v, want 5", len(days))
+	}
+
+	// the latest day first
+	for i, want := range []string{
+		"2021-11-26",
+		"2021-11-25",
+		"2021-11-24",
+		"2021-11-23",
+		"2021-11-22",
+	} {
+		got := days[i].Date.Format(divyield.DateFormat)
+		if got != want {
+			t.Errorf("day %v: got %v, want %v", i, got, want)
+		}
+	}
+
+	tests := []struct {
+		currency string
+		want     float64
+	}{
+		{"EUR", 367.05},
+		{"USD", 327.58},
+		// 100 JPY is 285,61 HUF
+		{"JPY", 2.8561},
+	}
+	for _, tt := range tests {
+		got, ok := days[0].huf(tt.currency)
+		if !ok || !equal(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.currency, got, tt.want)
+		}
+	}
+
+	if _, ok := days[0].huf("GBP"); ok {
+		t.Errorf("GBP: unexpected rate")
+	}
+}
+
+func TestParseExchangeRatesResponseFault(t *testing.T) {
+	f, err := os.Open("testdata/GetExchangeRatesFault.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	_, err = parseExchangeRatesResponse(f)
+	if err == nil || !strings.Contains(err.Error(), "soap fault") {
+		t.Errorf("got %v, want soap fault", err)
+	}
+}
+
+func TestParseExchangeRatesInvalid(t *testing.T) {
+	tests := []struct {
+		name string
+		doc  string
+	}{
+		{
+			"rate",
+			`<MNBExchangeRates><Day date="2021-11-26">` +
+				`<Rate unit="1" curr="USD">abc</Rate>` +
+				`</Day></MNBExchangeRates>`,
+		},
+		{
+			"unit",
+			`<MNBExchangeRates><Day date="2021-11-26">` +
+				`<Rate unit="0" curr="JPY">285,61</Rate>` +
+				`</Day></MNBExchangeRates>`,
+		},
+		{
+			"date",
+			`<MNBExchangeRates><Day date="26.11.2021">` +
+				`<Rate unit="1" curr="USD">327,58</Rate>` +
+				`</Day></MNBExchangeRates>`,
+		},
+	}
+
+	for _, tt := range tests {
+		_, err := parseExchangeRates(strings.NewReader(tt.doc))
+		if err == nil {
+			t.Errorf("%v: expected error", tt.name)
+		}
+	}
+}
+
+func TestCrossRate(t *testing.T) {
+	days := loadRates(t, "GetExchangeRates.xml")
+
+	tests := []struct {
+		name string
+		date string
+		from string
+		to   string
+		want float64
+	}{
+		{"to HUF", "2021-11-26", "USD", "HUF", 327.58},
+		{"from HUF", "2021-11-26", "HUF", "USD", 1 / 327.58},
+		{"cross", "2021-11-26", "USD", "EUR", 327.58 / 367.05},
+		{"unit", "2021-11-26", "JPY", "USD", 2.8561 / 327.58},
+		{"earlier day", "2021-11-24", "EUR", "HUF", 369.15},
+		{"saturday", "2021-11-27", "USD", "HUF", 327.58},
+		{"sunday", "2021-11-28", "EUR", "JPY", 367.05 / 2.8561},
+	}
+
+	for _, tt := range tests {
+		got, err := crossRate(days, date(tt.date), tt.from, tt.to)
+		if err != nil {
+			t.Errorf("%v: %v", tt.name, err)
+			continue
+		}
+		if !equal(got, tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	_, err := crossRate(days, date("2021-11-21"), "USD", "HUF")
+	if err == nil {
+		t.Errorf("before the first day: expected error")
+	}
+	_, err = crossRate(days, date("2021-11-26"), "GBP", "HUF")
+	if err == nil {
+		t.Errorf("missing currency: expected error")
+	}
+}
+
+func TestConvertWeekend(t *testing.T) {
+	resp, err := ioutil.ReadFile("testdata/GetExchangeRates.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reqBody string
+	srv := httptest.NewServer(http.HandlerFunc(
+		func(w http.ResponseWriter, r *http.Request) {
+			b, _ := ioutil.ReadAll(r.Body)
+			reqBody = string(b)
+			w.Header().Set("Content-Type", "text/xml; charset=utf-8")
+			w.Write(resp)
+		},
+	))
+	defer srv.Close()
+
+	s := NewCurrencyService(BaseURL(srv.URL), LookbackDays(7))
+	out, err := s.Convert(
+		context.Background(),
+		&divyield.CurrencyConvertInput{
+			From:   "USD",
+			To:     "HUF",
+			Amount: 2,
+			Date:   date("2021-11-28"),
+		},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"<web:startDate>2021-11-21</web:startDate>",
+		"<web:endDate>2021-11-28</web:endDate>",
+		"<web:currencyNames>USD</web:currencyNames>",
+	} {
+		if !strings.Contains(reqBody, want) {
+			t.Errorf("request: missing %v", want)
+		}
+	}
+	if !equal(out.Rate, 327.58) {
+		t.Errorf("rate: got %v, want 327.58", out.Rate)
+	}
+	if !equal(out.Amount, 655.16) {
+		t.Errorf("amount: got %v, want 655.16", out.Amount)
+	}
+}
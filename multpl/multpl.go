@@ -6,9 +6,11 @@ import (
 	"io"
 	"net/http"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"szakszon.com/divyield"
 )
@@ -29,6 +31,10 @@ func (s *sp500Service) DividendYield(
 	ctx context.Context,
 	in *divyield.SP500DividendYieldInput,
 ) (*divyield.SP500DividendYieldOutput, error) {
+	if !in.Date.IsZero() {
+		return s.dividendYieldOn(ctx, in.Date)
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -46,41 +52,162 @@ func (s *sp500Service) DividendYield(
 	return out, nil
 }
 
-func (s *sp500Service) dividendYield(
+func (s *sp500Service) dividendYieldOn(
 	ctx context.Context,
-) (*divyield.SP500DividendYield, error) {
+	date time.Time,
+) (*divyield.SP500DividendYieldOutput, error) {
+	yields, err := s.dividendYields(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range yields {
+		if !v.Date.After(date) {
+			return &divyield.SP500DividendYieldOutput{
+				SP500DividendYield: *v,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf(
+		"no S&P 500 dividend yield on %v",
+		date.Format(divyield.DateFormat),
+	)
+}
+
+// DividendYields returns the monthly series of the table page.
+// The value of the current month is an estimate.
+func (s *sp500Service) DividendYields(
+	ctx context.Context,
+	in *divyield.SP500DividendYieldsInput,
+) (*divyield.SP500DividendYieldsOutput, error) {
+	yields, err := s.dividendYields(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := make([]*divyield.SP500DividendYield, 0, len(yields))
+	for _, v := range yields {
+		if !in.Since.IsZero() && v.Date.Before(in.Since) {
+			continue
+		}
+		if !in.Until.IsZero() && v.Date.After(in.Until) {
+			continue
+		}
+		filtered = append(filtered, v)
+	}
+	return &divyield.SP500DividendYieldsOutput{
+		DividendYields: filtered,
+	}, nil
+}
+
+func (s *sp500Service) dividendYields(
+	ctx context.Context,
+) ([]*divyield.SP500DividendYield, error) {
+	body, err := s.get(
+		ctx,
+		"https://www.multpl.com/s-p-500-dividend-yield/table/by-month",
+	)
+	if err != nil {
+		return nil, err
+	}
+	return parseDividendYieldTable(body)
+}
+
+// parseDividendYieldTable parses the rows of the table page,
+// e.g. <td>Sep 1, 2021</td><td>1.31%</td>. The dates are
+// normalized to the first day of the month.
+func parseDividendYieldTable(
+	body string,
+) ([]*divyield.SP500DividendYield, error) {
+	yields := make([]*divyield.SP500DividendYield, 0)
+	for _, m := range tableRowRE.FindAllStringSubmatch(body, -1) {
+		date, err := time.Parse("Jan 2, 2006", strings.TrimSpace(m[1]))
+		if err != nil {
+			return nil, fmt.Errorf("parse date: %v", err)
+		}
+		date = time.Date(
+			date.Year(), date.Month(), 1,
+			0, 0, 0, 0, time.UTC,
+		)
+
+		rm := tableRateRE.FindStringSubmatch(m[2])
+		if rm == nil {
+			return nil, fmt.Errorf(
+				"%v: rate not found",
+				date.Format(divyield.DateFormat),
+			)
+		}
+		rate, err := strconv.ParseFloat(rm[1], 64)
+		if err != nil {
+			return nil, err
+		}
+
+		yields = append(yields, &divyield.SP500DividendYield{
+			Date:      date,
+			Rate:      rate,
+			Timestamp: date.Format(divyield.DateFormat),
+		})
+	}
+	if len(yields) == 0 {
+		return nil, fmt.Errorf("dividend yield table not found")
+	}
+
+	sort.SliceStable(yields, func(i, j int) bool {
+		return yields[i].Date.After(yields[j].Date)
+	})
+	return yields, nil
+}
+
+func (s *sp500Service) get(
+	ctx context.Context,
+	url string,
+) (string, error) {
 	req, err := http.NewRequestWithContext(
 		ctx,
 		http.MethodGet,
-		"https://www.multpl.com/s-p-500-dividend-yield",
+		url,
 		nil,
 	)
-	req.Header.Set("User-Agent", userAgent)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
+	req.Header.Set("User-Agent", userAgent)
 
 	client := &http.Client{}
 	resp, err := client.Do(req)
 	if err != nil {
-		return nil, err
+		return "", err
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode < 200 || 299 < resp.StatusCode {
-		return nil, fmt.Errorf(
+		return "", fmt.Errorf(
 			"http error: %d",
 			resp.StatusCode,
 		)
 	}
 
 	b, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func (s *sp500Service) dividendYield(
+	ctx context.Context,
+) (*divyield.SP500DividendYield, error) {
+	body, err := s.get(
+		ctx,
+		"https://www.multpl.com/s-p-500-dividend-yield",
+	)
 	if err != nil {
 		return nil, err
 	}
 
-	body := string(b)
 	matches := rateRE.FindStringSubmatch(body)
+	if matches == nil {
+		return nil, fmt.Errorf("dividend yield not found")
+	}
 	rateStr := strings.ReplaceAll(matches[1], ",", ".")
 	rateStr = strings.TrimSpace(rateStr)
 	rate, err := strconv.ParseFloat(rateStr, 64)
@@ -88,10 +215,14 @@ func (s *sp500Service) dividendYield(
 		return nil, err
 	}
 
+	timestamp := ""
 	matches = timestampRE.FindStringSubmatch(body)
-	timestamp := strings.TrimSpace(matches[1])
+	if matches != nil {
+		timestamp = strings.TrimSpace(matches[1])
+	}
 
 	return &divyield.SP500DividendYield{
+		Date:      time.Now().UTC(),
 		Rate:      rate,
 		Timestamp: timestamp,
 	}, nil
@@ -105,4 +236,12 @@ var timestampRE = regexp.MustCompile(
 	`(?s)id="timestamp">([^<>]+)<`,
 )
 
+var tableRowRE = regexp.MustCompile(
+	`(?s)<td>\s*([A-Z][a-z]{2} \d{1,2}, \d{4})\s*</td>\s*<td>(.*?)</td>`,
+)
+
+var tableRateRE = regexp.MustCompile(
+	`([0-9]+(?:\.[0-9]+)?)%`,
+)
+
 const userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.93 Safari/537.36 OPR/76.0.4017.123"
@@ -0,0 +1,336 @@
+package postgres
+
+import (
+	"context"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+
+	"szakszon.com/divyield"
+	"szakszon.com/divyield/adjust"
+)
+
+// adjust recalculates the adjusted prices and dividends
+// of the ticker after its prices, dividends or splits
+// have changed.
+func (db *DB) adjust(
+	ctx context.Context,
+	runner runner,
+	ticker string,
+) error {
+	return adjustSymbol(
+		ctx,
+		runner,
+		db.schema(ticker),
+		ticker,
+		db.shared(),
+	)
+}
+
+func adjustSymbol(
+	ctx context.Context,
+	runner runner,
+	schema string,
+	ticker string,
+	shared bool,
+) error {
+	where := sq.Eq{}
+	if shared {
+		where["symbol"] = ticker
+	}
+
+	splits, err := adjustSplits(ctx, runner, schema, where)
+	if err != nil {
+		return err
+	}
+	dividends, err := adjustDividends(ctx, runner, schema, where)
+	if err != nil {
+		return err
+	}
+	prices, err := adjustPrices(ctx, runner, schema, where)
+	if err != nil {
+		return err
+	}
+
+	adjust.Dividends(dividends, splits)
+	adjust.Prices(prices, dividends, splits)
+
+	err = saveDividendAdj(ctx, runner, schema, where, dividends)
+	if err != nil {
+		return err
+	}
+	return savePriceAdj(ctx, runner, schema, where, prices)
+}
+
+func adjustSplits(
+	ctx context.Context,
+	runner runner,
+	schema string,
+	where sq.Eq,
+) ([]*divyield.Split, error) {
+	s, args, err := sq.
+		Select("ex_date", "to_factor", "from_factor").
+		From(schema + ".split").
+		Where(where).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := runner.QueryContext(ctx, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	splits := make([]*divyield.Split, 0)
+	for rows.Next() {
+		v := &divyield.Split{}
+		err = rows.Scan(&v.ExDate, &v.ToFactor, &v.FromFactor)
+		if err != nil {
+			return nil, err
+		}
+		splits = append(splits, v)
+	}
+	return splits, rows.Err()
+}
+
+func adjustDividends(
+	ctx context.Context,
+	runner runner,
+	schema string,
+	where sq.Eq,
+) ([]*divyield.Dividend, error) {
+	s, args, err := sq.
+		Select("id", "ex_date", "amount", "payment_type").
+		From(schema + ".dividend").
+		Where(where).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := runner.QueryContext(ctx, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	dividends := make([]*divyield.Dividend, 0)
+	for rows.Next() {
+		v := &divyield.Dividend{}
+		err = rows.Scan(&v.ID, &v.ExDate, &v.Amount, &v.PaymentType)
+		if err != nil {
+			return nil, err
+		}
+		dividends = append(dividends, v)
+	}
+	return dividends, rows.Err()
+}
+
+func adjustPrices(
+	ctx context.Context,
+	runner runner,
+	schema string,
+	where sq.Eq,
+) ([]*divyield.Price, error) {
+	s, args, err := sq.
+		Select("date", "close").
+		From(schema + ".price").
+		Where(where).
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := runner.QueryContext(ctx, s, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	prices := make([]*divyield.Price, 0)
+	for rows.Next() {
+		v := &divyield.Price{}
+		err = rows.Scan(&v.Date, &v.Close)
+		if err != nil {
+			return nil, err
+		}
+		prices = append(prices, v)
+	}
+	return prices, rows.Err()
+}
+
+// saveDividendAdj copies the adjusted dividends into
+// a temporary table and updates the dividends from it.
+func saveDividendAdj(
+	ctx context.Context,
+	runner runner,
+	schema string,
+	where sq.Eq,
+	dividends []*divyield.Dividend,
+) error {
+	err := createTempTable(
+		ctx,
+		runner,
+		"dividend_adj",
+		`id         bigint not null,
+        factor_adj numeric not null,
+        amount_adj numeric not null`,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = copyIn(
+		ctx,
+		runner,
+		pq.CopyIn("dividend_adj", "id", "factor_adj", "amount_adj"),
+		len(dividends),
+		func(i int) []interface{} {
+			d := dividends[i]
+			return []interface{}{d.ID, d.FactorAdj, d.AmountAdj}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	s, args, err := sq.
+		Update(schema+".dividend d").
+		Set("factor_adj", sq.Expr("a.factor_adj")).
+		Set("amount_adj", sq.Expr("a.amount_adj")).
+		Suffix("from dividend_adj a where d.id = a.id").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if sym, ok := where["symbol"]; ok {
+		s += " and d.symbol = $1"
+		args = append(args, sym)
+	}
+	_, err = runner.ExecContext(ctx, s, args...)
+	return err
+}
+
+// savePriceAdj copies the adjusted prices into
+// a temporary table and updates the prices from it.
+func savePriceAdj(
+	ctx context.Context,
+	runner runner,
+	schema string,
+	where sq.Eq,
+	prices []*divyield.Price,
+) error {
+	err := createTempTable(
+		ctx,
+		runner,
+		"price_adj",
+		`date              date not null,
+        factor_adj        numeric not null,
+        close_adj         numeric not null,
+        factor_adj_splits numeric not null,
+        close_adj_splits  numeric not null`,
+	)
+	if err != nil {
+		return err
+	}
+
+	err = copyIn(
+		ctx,
+		runner,
+		pq.CopyIn(
+			"price_adj",
+			"date",
+			"factor_adj",
+			"close_adj",
+			"factor_adj_splits",
+			"close_adj_splits",
+		),
+		len(prices),
+		func(i int) []interface{} {
+			p := prices[i]
+			return []interface{}{
+				p.Date.Format(divyield.DateFormat),
+				p.FactorAdj,
+				p.CloseAdj,
+				p.FactorAdjSplits,
+				p.CloseAdjSplits,
+			}
+		},
+	)
+	if err != nil {
+		return err
+	}
+
+	s, args, err := sq.
+		Update(schema+".price p").
+		Set("factor_adj", sq.Expr("a.factor_adj")).
+		Set("close_adj", sq.Expr("a.close_adj")).
+		Set("factor_adj_splits", sq.Expr("a.factor_adj_splits")).
+		Set("close_adj_splits", sq.Expr("a.close_adj_splits")).
+		Suffix("from price_adj a where p.date = a.date").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return err
+	}
+	if sym, ok := where["symbol"]; ok {
+		s += " and p.symbol = $1"
+		args = append(args, sym)
+	}
+	_, err = runner.ExecContext(ctx, s, args...)
+	return err
+}
+
+// createTempTable creates an empty temporary table
+// that is dropped at the end of the transaction.
+func createTempTable(
+	ctx context.Context,
+	runner runner,
+	name string,
+	columns string,
+) error {
+	_, err := runner.ExecContext(
+		ctx,
+		"create temporary table if not exists "+
+			name+" ("+columns+") on commit drop",
+	)
+	if err != nil {
+		return err
+	}
+	_, err = runner.ExecContext(ctx, "truncate "+name)
+	return err
+}
+
+func copyIn(
+	ctx context.Context,
+	runner runner,
+	query string,
+	n int,
+	values func(i int) []interface{},
+) error {
+	stmt, err := runner.PrepareContext(ctx, query)
+	if err != nil {
+		return err
+	}
+
+	for i := 0; i < n; i++ {
+		_, err = stmt.ExecContext(ctx, values(i)...)
+		if err != nil {
+			stmt.Close()
+			return err
+		}
+	}
+
+	_, err = stmt.ExecContext(ctx)
+	if err != nil {
+		stmt.Close()
+		return err
+	}
+	return stmt.Close()
+}
@@ -0,0 +1,187 @@
+package postgres
+
+import (
+	"context"
+	"strings"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"szakszon.com/divyield"
+)
+
+// LatestDividendYields returns the latest dividend yield
+// of every symbol with a single query.
+func (db *DB) LatestDividendYields(
+	ctx context.Context,
+	in *divyield.DBLatestDividendYieldsInput,
+) (*divyield.DBLatestDividendYieldsOutput, error) {
+	out := &divyield.DBLatestDividendYieldsOutput{
+		DividendYields: make(map[string]*divyield.DividendYield),
+	}
+
+	err := execNonTx(ctx, db.DB, func(runner runner) error {
+		tickers, err := db.existingTickers(ctx, runner, in.Symbols)
+		if err != nil {
+			return err
+		}
+
+		f := &divyield.DividendYieldFilter{
+			To:    in.Date,
+			Limit: 1,
+		}
+		s, args, err := unionByTicker(
+			tickers,
+			func(ticker string) sq.SelectBuilder {
+				return db.dividendYieldsQuery(ticker, f)
+			},
+			"",
+		)
+		if err != nil || s == "" {
+			return err
+		}
+
+		rows, err := runner.QueryContext(ctx, s, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var ticker string
+			v, err := scanDividendYield(rows, &ticker)
+			if err != nil {
+				return err
+			}
+			out.DividendYields[ticker] = v
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// DividendsBySymbol returns the dividends of every symbol
+// with a single query. The limit of the filter applies
+// to each symbol.
+func (db *DB) DividendsBySymbol(
+	ctx context.Context,
+	in *divyield.DBDividendsBySymbolInput,
+) (*divyield.DBDividendsBySymbolOutput, error) {
+	out := &divyield.DBDividendsBySymbolOutput{
+		Dividends: make(map[string][]*divyield.Dividend),
+	}
+
+	f := in.Filter
+	if f == nil {
+		f = &divyield.DividendFilter{}
+	}
+
+	err := execNonTx(ctx, db.DB, func(runner runner) error {
+		tickers, err := db.existingTickers(ctx, runner, in.Symbols)
+		if err != nil {
+			return err
+		}
+
+		s, args, err := unionByTicker(
+			tickers,
+			func(ticker string) sq.SelectBuilder {
+				return db.dividendsQuery(ticker, f)
+			},
+			"ex_date desc",
+		)
+		if err != nil || s == "" {
+			return err
+		}
+
+		rows, err := runner.QueryContext(ctx, s, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var ticker string
+			v, err := scanDividend(rows, &ticker)
+			if err != nil {
+				return err
+			}
+			out.Dividends[ticker] = append(out.Dividends[ticker], v)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+// existingTickers drops the tickers without a schema
+// in the per-ticker layout.
+func (db *DB) existingTickers(
+	ctx context.Context,
+	runner runner,
+	tickers []string,
+) ([]string, error) {
+	if db.shared() {
+		return tickers, nil
+	}
+
+	schemas, err := stockSchemas(ctx, runner)
+	if err != nil {
+		return nil, err
+	}
+
+	existing := make(map[string]struct{}, len(schemas))
+	for _, schema := range schemas {
+		existing[schema] = struct{}{}
+	}
+
+	found := make([]string, 0, len(tickers))
+	for _, t := range tickers {
+		if _, ok := existing[schemaStock(t)]; ok {
+			found = append(found, t)
+		}
+	}
+	return found, nil
+}
+
+// unionByTicker combines the queries of the tickers with
+// union all. Every row ends with a ticker column that
+// identifies the query the row comes from.
+func unionByTicker(
+	tickers []string,
+	query func(ticker string) sq.SelectBuilder,
+	orderBy string,
+) (string, []interface{}, error) {
+	if len(tickers) == 0 {
+		return "", nil, nil
+	}
+
+	parts := make([]string, 0, len(tickers))
+	args := make([]interface{}, 0)
+	for _, t := range tickers {
+		s, a, err := query(t).
+			Column(sq.Expr("?::varchar as ticker", t)).
+			ToSql()
+		if err != nil {
+			return "", nil, err
+		}
+		parts = append(parts, "("+s+")")
+		args = append(args, a...)
+	}
+
+	s := "select * from (" +
+		strings.Join(parts, " union all ") +
+		") t order by ticker asc"
+	if orderBy != "" {
+		s += ", " + orderBy
+	}
+
+	s, err := sq.Dollar.ReplacePlaceholders(s)
+	if err != nil {
+		return "", nil, err
+	}
+	return s, args, nil
+}
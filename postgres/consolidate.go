@@ -0,0 +1,164 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+
+	"szakszon.com/divyield"
+)
+
+// Consolidate copies the per-ticker schemas of the symbols
+// into the tables of the shared layout. Rows that already
+// exist in the shared tables are kept.
+func (db *DB) Consolidate(
+	ctx context.Context,
+	in *divyield.DBConsolidateInput,
+) (*divyield.DBConsolidateOutput, error) {
+	out := &divyield.DBConsolidateOutput{
+		Consolidated: make([]*divyield.DBConsolidated, 0),
+		Skipped:      make([]string, 0),
+	}
+
+	existing := make(map[string]struct{})
+	err := execNonTx(ctx, db.DB, func(runner runner) error {
+		schemas, err := stockSchemas(ctx, runner)
+		if err != nil {
+			return err
+		}
+		for _, schema := range schemas {
+			existing[schema] = struct{}{}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, symbol := range in.Symbols {
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("interrupted")
+		default:
+			// noop
+		}
+
+		schema := schemaStock(symbol)
+		if _, ok := existing[schema]; !ok {
+			out.Skipped = append(out.Skipped, symbol)
+			continue
+		}
+
+		c := &divyield.DBConsolidated{
+			Symbol: symbol,
+		}
+		err := execTx(ctx, db.DB, func(runner runner) error {
+			var err error
+			c.Prices, err = copyShared(
+				ctx,
+				runner,
+				symbol,
+				schema,
+				"price",
+				"date",
+				"currency",
+				"close",
+				"high",
+				"low",
+				"open",
+				"volume",
+				"factor_adj",
+				"close_adj",
+				"factor_adj_splits",
+				"close_adj_splits",
+				"created",
+			)
+			if err != nil {
+				return fmt.Errorf("copy prices: %v", err)
+			}
+
+			c.Dividends, err = copyShared(
+				ctx,
+				runner,
+				symbol,
+				schema,
+				"dividend",
+				"id",
+				"ex_date",
+				"amount",
+				"currency",
+				"frequency",
+				"payment_type",
+				"factor_adj",
+				"amount_adj",
+				"created",
+				"source",
+			)
+			if err != nil {
+				return fmt.Errorf("copy dividends: %v", err)
+			}
+
+			c.Splits, err = copyShared(
+				ctx,
+				runner,
+				symbol,
+				schema,
+				"split",
+				"ex_date",
+				"to_factor",
+				"from_factor",
+				"created",
+				"source",
+			)
+			if err != nil {
+				return fmt.Errorf("copy splits: %v", err)
+			}
+
+			return adjustSymbol(
+				ctx,
+				runner,
+				schemaShared,
+				symbol,
+				true,
+			)
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", symbol, err)
+		}
+		out.Consolidated = append(out.Consolidated, c)
+	}
+	return out, nil
+}
+
+func copyShared(
+	ctx context.Context,
+	runner runner,
+	symbol string,
+	schema string,
+	table string,
+	columns ...string,
+) (int64, error) {
+	s, args, err := sq.
+		Insert(schemaShared + "." + table).
+		Columns(append([]string{"symbol"}, columns...)...).
+		Select(
+			sq.Select().
+				Column(sq.Expr("?::varchar", symbol)).
+				Columns(columns...).
+				From(pq.QuoteIdentifier(schema) + "." + table),
+		).
+		Suffix("on conflict do nothing").
+		PlaceholderFormat(sq.Dollar).
+		ToSql()
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := runner.ExecContext(ctx, s, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
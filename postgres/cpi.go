@@ -0,0 +1,129 @@
+package postgres
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"szakszon.com/divyield"
+)
+
+func (db *DB) CPI(
+	ctx context.Context,
+	in *divyield.DBCPIInput,
+) (*divyield.DBCPIOutput, error) {
+	series := make([]*divyield.CPI, 0)
+
+	err := execNonTx(ctx, db.DB, func(runner runner) error {
+		q := sq.Select(
+			"country",
+			"month",
+			"index",
+			"yoy",
+		).
+			From("public.cpi").
+			Where("country = ?", in.Country).
+			OrderBy("month asc").
+			PlaceholderFormat(sq.Dollar)
+
+		if !in.Since.IsZero() {
+			q = q.Where("month >= ?", in.Since)
+		}
+
+		if !in.Until.IsZero() {
+			q = q.Where("month <= ?", in.Until)
+		}
+
+		s, args, err := q.ToSql()
+		if err != nil {
+			return err
+		}
+
+		rows, err := runner.QueryContext(ctx, s, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			v := &divyield.CPI{}
+			var index sql.NullFloat64
+			var yoy sql.NullFloat64
+			err = rows.Scan(&v.Country, &v.Month, &index, &yoy)
+			if err != nil {
+				return err
+			}
+			v.Index = index.Float64
+			v.YoY = yoy.Float64
+			series = append(series, v)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.DBCPIOutput{
+		Series: series,
+	}, nil
+}
+
+func (db *DB) SaveCPI(
+	ctx context.Context,
+	in *divyield.DBSaveCPIInput,
+) (*divyield.DBSaveCPIOutput, error) {
+	if len(in.Series) == 0 {
+		return &divyield.DBSaveCPIOutput{}, nil
+	}
+
+	now := time.Now()
+	err := execTx(ctx, db.DB, func(runner runner) error {
+		for _, v := range in.Series {
+			var index interface{}
+			if v.Index != 0 {
+				index = v.Index
+			}
+			var yoy interface{}
+			if v.Index == 0 {
+				yoy = v.YoY
+			}
+
+			s, args, err := sq.Insert("public.cpi").
+				Columns(
+					"country",
+					"month",
+					"index",
+					"yoy",
+					"created",
+				).
+				Values(
+					v.Country,
+					v.Month.Format(divyield.DateFormat),
+					index,
+					yoy,
+					now,
+				).
+				Suffix(`on conflict (country, month)
+                    do update set
+                        index = excluded.index,
+                        yoy = excluded.yoy,
+                        created = excluded.created`).
+				PlaceholderFormat(sq.Dollar).
+				ToSql()
+			if err != nil {
+				return err
+			}
+
+			_, err = runner.ExecContext(ctx, s, args...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.DBSaveCPIOutput{}, nil
+}
@@ -0,0 +1,146 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"szakszon.com/divyield"
+)
+
+func (db *DB) FXRates(
+	ctx context.Context,
+	in *divyield.DBFXRatesInput,
+) (*divyield.DBFXRatesOutput, error) {
+	rates := make([]*divyield.FXRate, 0)
+
+	err := execNonTx(ctx, db.DB, func(runner runner) error {
+		q := sq.Select(
+			"from_currency",
+			"to_currency",
+			"date",
+			"rate",
+		).
+			From("public.fx_rate").
+			Where(sq.Eq{
+				"from_currency": in.From,
+				"to_currency":   in.To,
+			}).
+			OrderBy("date desc").
+			PlaceholderFormat(sq.Dollar)
+
+		if !in.Since.IsZero() {
+			q = q.Where("date >= ?", in.Since)
+		}
+
+		if !in.Until.IsZero() {
+			q = q.Where("date <= ?", in.Until)
+		}
+
+		if in.Limit > 0 {
+			q = q.Limit(in.Limit)
+		}
+
+		s, args, err := q.ToSql()
+		if err != nil {
+			return err
+		}
+
+		rows, err := runner.QueryContext(ctx, s, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			v := &divyield.FXRate{}
+			err = rows.Scan(&v.From, &v.To, &v.Date, &v.Rate)
+			if err != nil {
+				return err
+			}
+			rates = append(rates, v)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.DBFXRatesOutput{
+		Rates: rates,
+	}, nil
+}
+
+// fxRatesChunkSize keeps the number of
+// the query parameters under the limit.
+const fxRatesChunkSize = 1000
+
+func (db *DB) SaveFXRates(
+	ctx context.Context,
+	in *divyield.DBSaveFXRatesInput,
+) (*divyield.DBSaveFXRatesOutput, error) {
+	if len(in.Rates) == 0 {
+		return &divyield.DBSaveFXRatesOutput{}, nil
+	}
+
+	// a statement cannot update the same row twice
+	rates := make([]*divyield.FXRate, 0, len(in.Rates))
+	idx := make(map[string]int)
+	for _, v := range in.Rates {
+		k := v.From + v.To + v.Date.Format(divyield.DateFormat)
+		if i, ok := idx[k]; ok {
+			rates[i] = v
+			continue
+		}
+		idx[k] = len(rates)
+		rates = append(rates, v)
+	}
+
+	now := time.Now()
+	err := execTx(ctx, db.DB, func(runner runner) error {
+		for i := 0; i < len(rates); i += fxRatesChunkSize {
+			end := i + fxRatesChunkSize
+			if end > len(rates) {
+				end = len(rates)
+			}
+
+			q := sq.Insert("public.fx_rate").
+				Columns(
+					"from_currency",
+					"to_currency",
+					"date",
+					"rate",
+					"created",
+				).
+				Suffix(`on conflict (from_currency, to_currency, date)
+                    do update set
+                        rate = excluded.rate,
+                        created = excluded.created`).
+				PlaceholderFormat(sq.Dollar)
+
+			for _, v := range rates[i:end] {
+				q = q.Values(
+					v.From,
+					v.To,
+					v.Date.Format(divyield.DateFormat),
+					v.Rate,
+					now,
+				)
+			}
+
+			s, args, err := q.ToSql()
+			if err != nil {
+				return err
+			}
+			_, err = runner.ExecContext(ctx, s, args...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.DBSaveFXRatesOutput{}, nil
+}
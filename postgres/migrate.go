@@ -0,0 +1,331 @@
+package postgres
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+
+	"szakszon.com/divyield"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migration changes the public schema once and every
+// per-ticker schema with the same SQL or Go code.
+// The search path points to the per-ticker schema
+// when the stock part runs.
+type migration struct {
+	Version  int
+	Name     string
+	Public   string
+	Stock    string
+	PublicFn func(ctx context.Context, runner runner) error
+	StockFn  func(ctx context.Context, runner runner, schema string) error
+}
+
+// goMigrations are merged with the SQL migrations
+// in the migrations directory by version.
+var goMigrations = []*migration{
+	{
+		Version: 3,
+		Name:    "recalculate_adjustments",
+		StockFn: func(
+			ctx context.Context,
+			runner runner,
+			schema string,
+		) error {
+			return adjustSymbol(ctx, runner, schema, "", false)
+		},
+	},
+}
+
+func (db *DB) Migrate(
+	ctx context.Context,
+	in *divyield.DBMigrateInput,
+) (*divyield.DBMigrateOutput, error) {
+	migrations, err := loadMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	out := &divyield.DBMigrateOutput{}
+
+	err = execNonTx(ctx, db.DB, func(runner runner) error {
+		_, err := runner.ExecContext(
+			ctx,
+			`create table if not exists public.schema_migration (
+                version integer not null,
+                name    text not null,
+                applied timestamp with time zone not null,
+                PRIMARY KEY(version)
+            )`,
+		)
+		return err
+	})
+	if err != nil {
+		return nil, fmt.Errorf("create migration table: %v", err)
+	}
+
+	err = execNonTx(ctx, db.DB, func(runner runner) error {
+		out.FromVersion, err = migrationVersion(ctx, runner)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, m := range migrations {
+		if in.Version > 0 && m.Version > in.Version {
+			break
+		}
+
+		applied := false
+		err := execTx(ctx, db.DB, func(runner runner) error {
+			_, err := runner.ExecContext(
+				ctx,
+				"lock table public.schema_migration "+
+					"in exclusive mode",
+			)
+			if err != nil {
+				return err
+			}
+
+			version, err := migrationVersion(ctx, runner)
+			if err != nil {
+				return err
+			}
+			if m.Version <= version {
+				return nil
+			}
+
+			err = db.applyMigration(ctx, runner, m)
+			if err != nil {
+				return err
+			}
+
+			s, args, err := sq.
+				Insert("public.schema_migration").
+				Columns("version", "name", "applied").
+				Values(m.Version, m.Name, time.Now()).
+				PlaceholderFormat(sq.Dollar).
+				ToSql()
+			if err != nil {
+				return err
+			}
+			_, err = runner.ExecContext(ctx, s, args...)
+			if err != nil {
+				return err
+			}
+			applied = true
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf(
+				"migration %04d_%v: %v",
+				m.Version,
+				m.Name,
+				err,
+			)
+		}
+		if applied {
+			out.Applied = append(out.Applied, &divyield.Migration{
+				Version: m.Version,
+				Name:    m.Name,
+			})
+		}
+	}
+
+	err = execNonTx(ctx, db.DB, func(runner runner) error {
+		out.ToVersion, err = migrationVersion(ctx, runner)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (db *DB) applyMigration(
+	ctx context.Context,
+	runner runner,
+	m *migration,
+) error {
+	if m.Public != "" {
+		_, err := runner.ExecContext(ctx, m.Public)
+		if err != nil {
+			return fmt.Errorf("public: %v", err)
+		}
+	}
+	if m.PublicFn != nil {
+		err := m.PublicFn(ctx, runner)
+		if err != nil {
+			return fmt.Errorf("public: %v", err)
+		}
+	}
+
+	if m.Stock == "" && m.StockFn == nil {
+		return nil
+	}
+
+	schemas, err := stockSchemas(ctx, runner)
+	if err != nil {
+		return err
+	}
+
+	for _, schema := range schemas {
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("interrupted")
+		default:
+			// noop
+		}
+
+		_, err := runner.ExecContext(
+			ctx,
+			"set local search_path to "+
+				pq.QuoteIdentifier(schema)+", public",
+		)
+		if err != nil {
+			return fmt.Errorf("%v: %v", schema, err)
+		}
+
+		if m.Stock != "" {
+			_, err = runner.ExecContext(ctx, m.Stock)
+			if err != nil {
+				return fmt.Errorf("%v: %v", schema, err)
+			}
+		}
+		if m.StockFn != nil {
+			err = m.StockFn(ctx, runner, schema)
+			if err != nil {
+				return fmt.Errorf("%v: %v", schema, err)
+			}
+		}
+	}
+
+	_, err = runner.ExecContext(ctx, "set local search_path to default")
+	return err
+}
+
+func migrationVersion(
+	ctx context.Context,
+	runner runner,
+) (int, error) {
+	var version int
+	err := runner.QueryRowContext(
+		ctx,
+		"select coalesce(max(version), 0) "+
+			"from public.schema_migration",
+	).Scan(&version)
+	return version, err
+}
+
+func stockSchemas(
+	ctx context.Context,
+	runner runner,
+) ([]string, error) {
+	rows, err := runner.QueryContext(
+		ctx,
+		`select schema_name
+        from information_schema.schemata
+        where schema_name like 's\_%'
+        order by schema_name asc`,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	schemas := make([]string, 0)
+	for rows.Next() {
+		var schema string
+		err = rows.Scan(&schema)
+		if err != nil {
+			return nil, err
+		}
+		schemas = append(schemas, schema)
+	}
+	return schemas, rows.Err()
+}
+
+// loadMigrations reads the SQL migrations named like
+// 0002_columns.public.sql and 0002_columns.stock.sql
+// and merges them with the Go migrations.
+func loadMigrations() ([]*migration, error) {
+	byVersion := make(map[int]*migration)
+	for _, m := range goMigrations {
+		byVersion[m.Version] = &migration{
+			Version:  m.Version,
+			Name:     m.Name,
+			PublicFn: m.PublicFn,
+			StockFn:  m.StockFn,
+		}
+	}
+
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, e := range entries {
+		name := e.Name()
+		parts := strings.SplitN(
+			strings.TrimSuffix(name, ".sql"),
+			".",
+			2,
+		)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid migration file: %v", name)
+		}
+
+		idx := strings.IndexByte(parts[0], '_')
+		if idx == -1 {
+			return nil, fmt.Errorf("invalid migration file: %v", name)
+		}
+		version, err := strconv.Atoi(parts[0][:idx])
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration file: %v", name)
+		}
+
+		b, err := migrationFiles.ReadFile(path.Join("migrations", name))
+		if err != nil {
+			return nil, err
+		}
+
+		m := byVersion[version]
+		if m == nil {
+			m = &migration{
+				Version: version,
+				Name:    parts[0][idx+1:],
+			}
+			byVersion[version] = m
+		}
+
+		switch parts[1] {
+		case "public":
+			m.Public = string(b)
+		case "stock":
+			m.Stock = string(b)
+		default:
+			return nil, fmt.Errorf("invalid migration file: %v", name)
+		}
+	}
+
+	migrations := make([]*migration, 0, len(byVersion))
+	for _, m := range byVersion {
+		migrations = append(migrations, m)
+	}
+	sort.SliceStable(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
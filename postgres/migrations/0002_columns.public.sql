@@ -0,0 +1,2 @@
+alter table public.profile
+    add column if not exists pulled timestamp with time zone;
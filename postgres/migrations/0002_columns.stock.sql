@@ -0,0 +1,15 @@
+-- Columns that were added after the first per-ticker schemas
+-- had been created. The search path points to the per-ticker
+-- schema, so the table names are not qualified.
+
+alter table price
+    add column if not exists factor_adj_splits numeric not null default 1;
+
+alter table price
+    add column if not exists close_adj_splits numeric not null default 0;
+
+alter table dividend
+    add column if not exists created timestamp with time zone;
+
+alter table split
+    add column if not exists created timestamp with time zone;
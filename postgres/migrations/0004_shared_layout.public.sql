@@ -0,0 +1,148 @@
+-- Tables of the shared layout. Every symbol is stored in
+-- the same tables of the market schema, so cross-sectional
+-- queries do not have to visit the per-ticker schemas.
+
+create schema if not exists market;
+
+create table if not exists market.price (
+    symbol      varchar(10) not null,
+    date        date not null,
+    currency    char(3) not null,
+    close       numeric not null,
+    high        numeric not null,
+    low         numeric not null,
+    open        numeric not null,
+    volume      numeric not null,
+    factor_adj  numeric not null default 1,
+    close_adj   numeric not null default 0,
+    factor_adj_splits  numeric not null default 1,
+    close_adj_splits   numeric not null default 0,
+    created     timestamp with time zone,
+    PRIMARY KEY(symbol, date)
+);
+
+create index if not exists price_date_idx
+    on market.price (date);
+
+create table if not exists market.dividend (
+    symbol       varchar(10) not null,
+    id           bigint not null,
+    ex_date      date not null,
+    amount       numeric not null,
+    currency     char(3) not null,
+    frequency    smallint not null,
+    payment_type text not null,
+    factor_adj   numeric not null default 1,
+    amount_adj   numeric not null default 0,
+    created      timestamp with time zone,
+    PRIMARY KEY(symbol, id)
+);
+
+create index if not exists dividend_ex_date_idx
+    on market.dividend (ex_date);
+
+create table if not exists market.split (
+    symbol       varchar(10) not null,
+    ex_date      date not null,
+    to_factor    numeric not null,
+    from_factor  numeric not null,
+    created      timestamp with time zone,
+    PRIMARY KEY(symbol, ex_date)
+);
+
+create or replace view market.dividend_view as
+    select
+        ex_date,
+        symbol,
+        sum(amount) amount,
+        currency,
+        frequency,
+        payment_type,
+        factor_adj,
+        sum(amount_adj) amount_adj,
+        max(created) created
+    from market.dividend
+    group by
+        ex_date,
+        symbol,
+        currency,
+        frequency,
+        payment_type,
+        factor_adj
+    order by symbol asc, ex_date desc;
+
+create or replace procedure market.update_dividend_adj(sym text)
+language plpgsql
+as $$
+declare
+    r record;
+    factor numeric;
+begin
+    update market.dividend set
+        factor_adj = 1, amount_adj = amount
+    where symbol = sym;
+
+    for r in select * from market.split
+        where symbol = sym
+        order by ex_date desc
+    loop
+        factor := 1.0 / (r.to_factor / r.from_factor);
+        update market.dividend set
+            factor_adj = factor_adj * factor
+        where symbol = sym and ex_date < r.ex_date;
+    end loop;
+
+    update market.dividend set
+        factor_adj = round(factor_adj, 4),
+        amount_adj = round(amount * factor_adj, 4)
+    where symbol = sym;
+end $$;
+
+create or replace procedure market.update_price_adj(sym text)
+language plpgsql
+as $$
+declare
+    r record;
+    factor numeric;
+begin
+    update market.price set
+        factor_adj = 1, close_adj = close,
+        factor_adj_splits = 1, close_adj_splits = close
+    where symbol = sym;
+
+    for r in select * from market.split
+        where symbol = sym
+        order by ex_date desc
+    loop
+        factor := 1.0 / (r.to_factor / r.from_factor);
+        update market.price set
+            factor_adj_splits = factor_adj_splits * factor,
+            factor_adj = factor_adj * factor
+        where symbol = sym and date < r.ex_date;
+    end loop;
+
+    for r in
+        select d.ex_date, d.amount, coalesce(p.close, 0) as close
+        from market.dividend d
+        left join market.price p
+            on p.symbol = d.symbol and p.date = d.ex_date
+        where
+            d.symbol = sym and
+            d.payment_type in ('Cash', 'Cash&Stock')
+        order by d.ex_date desc
+    loop
+        if r.close > 0 then
+            factor := r.close / (r.close + r.amount);
+            update market.price set
+                factor_adj = factor_adj * factor
+            where symbol = sym and date < r.ex_date;
+        end if;
+    end loop;
+
+    update market.price set
+        factor_adj = round(factor_adj, 4),
+        close_adj = round(close * factor_adj, 4),
+        factor_adj_splits = round(factor_adj_splits, 4),
+        close_adj_splits = round(close * factor_adj_splits, 4)
+    where symbol = sym;
+end $$;
@@ -0,0 +1,8 @@
+create table if not exists public.fx_rate (
+    from_currency char(3) not null,
+    to_currency   char(3) not null,
+    date          date not null,
+    rate          numeric not null,
+    created       timestamp with time zone,
+    PRIMARY KEY(from_currency, to_currency, date)
+);
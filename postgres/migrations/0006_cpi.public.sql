@@ -0,0 +1,11 @@
+-- Monthly consumer price index series by country. A series
+-- stores either the index level or the year-over-year change
+-- as a percentage, depending on its source.
+create table if not exists public.cpi (
+    country  char(2) not null,
+    month    date not null,
+    index    numeric,
+    yoy      numeric,
+    created  timestamp with time zone,
+    PRIMARY KEY(country, month)
+);
@@ -0,0 +1,7 @@
+-- Monthly S&P 500 dividend yield series as a percentage.
+create table if not exists public.sp500_dividend_yield (
+    date     date not null,
+    rate     numeric not null,
+    created  timestamp with time zone,
+    PRIMARY KEY(date)
+);
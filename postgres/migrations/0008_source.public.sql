@@ -0,0 +1,64 @@
+-- The provider of every dividend and split, e.g. iexcloud
+-- or yahoo, so the reconciled rows can be traced back.
+
+alter table market.dividend
+    add column if not exists source text;
+
+alter table market.split
+    add column if not exists source text;
+
+-- The new per-ticker schemas are created with the column.
+create or replace procedure
+    public.init_schema_tables(schema_name text)
+language plpgsql
+as $$
+declare
+begin
+    execute 'create schema if not exists ' ||
+        quote_ident(schema_name);
+
+    execute 'create table if not exists ' ||
+        quote_ident(schema_name) || '.price (
+        date        date not null,
+        symbol      varchar(10) not null,
+        currency    char(3) not null,
+        close       numeric not null,
+        high        numeric not null,
+        low         numeric not null,
+        open        numeric not null,
+        volume      numeric not null,
+        factor_adj  numeric not null default 1,
+        close_adj   numeric not null default 0,
+        created     timestamp with time zone,
+        factor_adj_splits  numeric not null default 1,
+        close_adj_splits   numeric not null default 0,
+        PRIMARY KEY(date)
+    )';
+
+    execute 'create table if not exists ' ||
+        quote_ident(schema_name) || '.dividend (
+        id           bigint not null,
+        ex_date      date not null,
+        symbol       varchar(10) not null,
+        amount       numeric not null,
+        currency     char(3) not null,
+        frequency    smallint not null,
+        payment_type text not null,
+        factor_adj   numeric not null default 1,
+        amount_adj   numeric not null default 0,
+        created      timestamp with time zone,
+        source       text,
+        PRIMARY KEY(id)
+    )';
+
+    execute 'create table if not exists ' ||
+        quote_ident(schema_name) || '.split (
+        ex_date      date not null,
+        to_factor     numeric not null,
+        from_factor   numeric not null,
+        created      timestamp with time zone,
+        source       text,
+        PRIMARY KEY(ex_date)
+    )';
+
+end $$;
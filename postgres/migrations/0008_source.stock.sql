@@ -0,0 +1,8 @@
+-- The search path points to the per-ticker schema,
+-- so the table names are not qualified.
+
+alter table dividend
+    add column if not exists source text;
+
+alter table split
+    add column if not exists source text;
@@ -0,0 +1,14 @@
+-- Line items of the Morningstar financial statements,
+-- every pull is kept as a snapshot.
+create table if not exists public.statement_item (
+    exchange         text not null,
+    symbol           text not null,
+    pulled           timestamp with time zone not null,
+    statement        text not null,
+    period           text not null,
+    label            text not null,
+    value            double precision not null,
+    currency         text,
+    fiscal_year_end  text,
+    PRIMARY KEY(exchange, symbol, pulled, statement, period, label)
+);
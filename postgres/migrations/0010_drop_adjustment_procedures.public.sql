@@ -0,0 +1,6 @@
+-- The adjusted prices and dividends are calculated
+-- by the adjust package of every DB backend.
+drop procedure if exists public.update_dividend_adj(text);
+drop procedure if exists public.update_price_adj(text);
+drop procedure if exists market.update_dividend_adj(text);
+drop procedure if exists market.update_price_adj(text);
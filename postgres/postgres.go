@@ -13,14 +13,31 @@ import (
 	"szakszon.com/divyield"
 )
 
+const (
+	// LayoutSchema stores every ticker in its own schema.
+	LayoutSchema = "schema"
+
+	// LayoutShared stores every ticker in the tables
+	// of the market schema keyed by symbol.
+	LayoutShared = "shared"
+)
+
+const schemaShared = "market"
+
 type DB struct {
-	DB *sql.DB
+	DB     *sql.DB
+	Layout string
 }
 
 func (db *DB) InitSchema(
 	ctx context.Context,
 	tickers []string,
 ) error {
+	if db.shared() {
+		// the shared tables are created by the migrations
+		return nil
+	}
+
 	schemas := make([]string, 0, len(tickers))
 	schemasExists := make([]string, 0, len(tickers))
 	schemasMissing := make([]string, 0, len(tickers))
@@ -92,6 +109,14 @@ OUTER_LOOP:
 	return err
 }
 
+// nullString stores the empty strings as null.
+func nullString(s string) interface{} {
+	if s == "" {
+		return nil
+	}
+	return s
+}
+
 func schemaStock(ticker string) string {
 	s := strings.ToLower(ticker)
 	s = strings.ReplaceAll(s, "-", "_")
@@ -99,6 +124,17 @@ func schemaStock(ticker string) string {
 	return "s_" + s
 }
 
+func (db *DB) shared() bool {
+	return db.Layout == LayoutShared
+}
+
+func (db *DB) schema(ticker string) string {
+	if db.shared() {
+		return schemaShared
+	}
+	return schemaStock(ticker)
+}
+
 func (db *DB) Prices(
 	ctx context.Context,
 	ticker string,
@@ -107,7 +143,7 @@ func (db *DB) Prices(
 	prices := make([]*divyield.Price, 0)
 
 	err := execNonTx(ctx, db.DB, func(runner runner) error {
-		schema := schemaStock(ticker)
+		schema := db.schema(ticker)
 
 		q := sq.Select(
 			"date",
@@ -124,6 +160,10 @@ func (db *DB) Prices(
 			OrderBy("date desc").
 			PlaceholderFormat(sq.Dollar)
 
+		if db.shared() {
+			q = q.Where("symbol = ?", ticker)
+		}
+
 		if !f.From.IsZero() {
 			q = q.Where("date >= ?", f.From)
 		}
@@ -194,13 +234,17 @@ func (db *DB) SavePrices(
 	in *divyield.DBSavePricesInput,
 ) (*divyield.DBSavePricesOutput, error) {
 	err := execTx(ctx, db.DB, func(runner runner) error {
-		schema := schemaStock(in.Symbol)
+		schema := db.schema(in.Symbol)
 
 		if in.Reset {
-			sql, args, err := sq.
+			q := sq.
 				Delete("").
 				From(schema + ".price").
-				ToSql()
+				PlaceholderFormat(sq.Dollar)
+			if db.shared() {
+				q = q.Where("symbol = ?", in.Symbol)
+			}
+			sql, args, err := q.ToSql()
 
 			_, err = runner.ExecContext(ctx, sql, args...)
 			if err != nil {
@@ -239,7 +283,7 @@ func (db *DB) SavePrices(
 			_, err = stmt.ExecContext(
 				ctx,
 				v.Date,
-				v.Symbol,
+				db.symbol(in.Symbol, v.Symbol),
 				v.Close,
 				v.High,
 				v.Low,
@@ -263,8 +307,7 @@ func (db *DB) SavePrices(
 			return err
 		}
 
-		err = updateCloseAdj(ctx, runner, schema)
-		return err
+		return db.adjust(ctx, runner, in.Symbol)
 	})
 	if err != nil {
 		return nil, err
@@ -280,40 +323,9 @@ func (db *DB) Dividends(
 	dividends := make([]*divyield.Dividend, 0)
 
 	err := execNonTx(ctx, db.DB, func(runner runner) error {
-		schema := schemaStock(ticker)
-
-		q := sq.Select(
-			"ex_date",
-			"amount",
-			"amount_adj",
-			"currency",
-			"frequency",
-			"symbol",
-			"payment_type",
-			"created",
-		).
-			From(schema + ".dividend_view").
-			OrderBy("ex_date desc").
-			PlaceholderFormat(sq.Dollar)
-
-		if !f.From.IsZero() {
-			q = q.Where("ex_date >= ?", f.From)
-		}
-
-		if f.Limit > 0 {
-			q = q.Limit(f.Limit)
-		}
-
-		if f.CashOnly {
-			q = q.Where(sq.Eq{"payment_type": []string{"Cash", "Cash&Stock"}})
-			q = q.Where("frequency > ?", 0)
-		}
-
-		if f.Regular {
-			q = q.Where("frequency > ?", 0)
-		}
-
-		sql, args, err := q.ToSql()
+		sql, args, err := db.dividendsQuery(ticker, f).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
 		if err != nil {
 			return err
 		}
@@ -325,38 +337,10 @@ func (db *DB) Dividends(
 		defer rows.Close()
 
 		for rows.Next() {
-			var exDate time.Time
-			var amount float64
-			var amountAdj float64
-			var currency string
-			var frequency int
-			var symbol string
-			var paymentType string
-			var created time.Time
-
-			err = rows.Scan(
-				&exDate,
-				&amount,
-				&amountAdj,
-				&currency,
-				&frequency,
-				&symbol,
-				&paymentType,
-				&created,
-			)
+			v, err := scanDividend(rows)
 			if err != nil {
 				return err
 			}
-			v := &divyield.Dividend{
-				ExDate:      exDate,
-				Amount:      amount,
-				AmountAdj:   amountAdj,
-				Currency:    currency,
-				Frequency:   frequency,
-				Symbol:      symbol,
-				PaymentType: paymentType,
-				Created:     created,
-			}
 			dividends = append(dividends, v)
 		}
 		return nil
@@ -367,18 +351,105 @@ func (db *DB) Dividends(
 	return dividends, nil
 }
 
+func (db *DB) dividendsQuery(
+	ticker string,
+	f *divyield.DividendFilter,
+) sq.SelectBuilder {
+	schema := db.schema(ticker)
+
+	q := sq.Select(
+		"ex_date",
+		"amount",
+		"amount_adj",
+		"currency",
+		"frequency",
+		"symbol",
+		"payment_type",
+		"created",
+	).
+		From(schema + ".dividend_view").
+		OrderBy("ex_date desc")
+
+	if db.shared() {
+		q = q.Where("symbol = ?", ticker)
+	}
+
+	if !f.From.IsZero() {
+		q = q.Where("ex_date >= ?", f.From)
+	}
+
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+
+	if f.CashOnly {
+		q = q.Where(sq.Eq{"payment_type": []string{"Cash", "Cash&Stock"}})
+		q = q.Where("frequency > ?", 0)
+	}
+
+	if f.Regular {
+		q = q.Where("frequency > ?", 0)
+	}
+	return q
+}
+
+// scanDividend scans the columns of dividendsQuery
+// followed by the optional extra columns.
+func scanDividend(
+	rows *sql.Rows,
+	extra ...interface{},
+) (*divyield.Dividend, error) {
+	var exDate time.Time
+	var amount float64
+	var amountAdj float64
+	var currency string
+	var frequency int
+	var symbol string
+	var paymentType string
+	var created time.Time
+
+	dest := []interface{}{
+		&exDate,
+		&amount,
+		&amountAdj,
+		&currency,
+		&frequency,
+		&symbol,
+		&paymentType,
+		&created,
+	}
+	err := rows.Scan(append(dest, extra...)...)
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.Dividend{
+		ExDate:      exDate,
+		Amount:      amount,
+		AmountAdj:   amountAdj,
+		Currency:    currency,
+		Frequency:   frequency,
+		Symbol:      symbol,
+		PaymentType: paymentType,
+		Created:     created,
+	}, nil
+}
+
 func (db *DB) SaveDividends(
 	ctx context.Context,
 	in *divyield.DBSaveDividendsInput,
 ) (*divyield.DBSaveDividendsOutput, error) {
 	err := execTx(ctx, db.DB, func(runner runner) error {
-		schema := schemaStock(in.Symbol)
+		schema := db.schema(in.Symbol)
 
 		if in.Reset {
-			sql, args, err := sq.
+			q := sq.
 				Delete("").
 				From(schema + ".dividend").
-				ToSql()
+				PlaceholderFormat(sq.Dollar)
+			if db.shared() {
+				q = q.Where("symbol = ?", in.Symbol)
+			}
+			sql, args, err := q.ToSql()
 
 			_, err = runner.ExecContext(ctx, sql, args...)
 			if err != nil {
@@ -404,6 +475,7 @@ func (db *DB) SaveDividends(
 				"frequency",
 				"payment_type",
 				"created",
+				"source",
 			),
 		)
 		if err != nil {
@@ -427,12 +499,13 @@ func (db *DB) SaveDividends(
 				ctx,
 				v.ID,
 				v.ExDate,
-				v.Symbol,
+				db.symbol(in.Symbol, v.Symbol),
 				v.Amount,
 				v.Currency,
 				v.Frequency,
 				v.PaymentType,
 				time.Now(),
+				nullString(v.Source),
 			)
 			if err != nil {
 				return fmt.Errorf("%v: %v", v, err)
@@ -449,13 +522,7 @@ func (db *DB) SaveDividends(
 			return err
 		}
 
-		err = updateDividendAdj(ctx, runner, schema)
-		if err != nil {
-			return err
-		}
-
-		err = updateCloseAdj(ctx, runner, schema)
-		return err
+		return db.adjust(ctx, runner, in.Symbol)
 	})
 	if err != nil {
 		return nil, fmt.Errorf("%v: %v", in.Symbol, err)
@@ -469,12 +536,16 @@ func (db *DB) dividendIDs(
 	symbol string,
 ) (map[int64]struct{}, error) {
 	ids := make(map[int64]struct{})
-	schema := schemaStock(symbol)
+	schema := db.schema(symbol)
 
-	s, args, err := sq.Select("id").
+	q := sq.Select("id").
 		From(schema + ".dividend").
-		PlaceholderFormat(sq.Dollar).
-		ToSql()
+		PlaceholderFormat(sq.Dollar)
+	if db.shared() {
+		q = q.Where("symbol = ?", symbol)
+	}
+
+	s, args, err := q.ToSql()
 	if err != nil {
 		return nil, err
 	}
@@ -504,34 +575,73 @@ func (db *DB) DividendYields(
 	yields := make([]*divyield.DividendYield, 0)
 
 	err := execNonTx(ctx, db.DB, func(runner runner) error {
-		schema := schemaStock(ticker)
+		sql, args, err := db.dividendYieldsQuery(ticker, f).
+			PlaceholderFormat(sq.Dollar).
+			ToSql()
+		if err != nil {
+			return err
+		}
 
-		q := sq.Select(
-			"date",
-			"close",
-			"close_adj_splits",
-			`coalesce(
+		rows, err := runner.QueryContext(ctx, sql, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			v, err := scanDividendYield(rows)
+			if err != nil {
+				return err
+			}
+			yields = append(yields, v)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return yields, nil
+}
+
+func (db *DB) dividendYieldsQuery(
+	ticker string,
+	f *divyield.DividendYieldFilter,
+) sq.SelectBuilder {
+	schema := db.schema(ticker)
+
+	// correlates the dividends with the price
+	// of the same symbol in the shared layout
+	symbolCond := ""
+	if db.shared() {
+		symbolCond = "d.symbol = p.symbol and "
+	}
+
+	q := sq.Select(
+		"date",
+		"close",
+		"close_adj_splits",
+		`coalesce(
                 (select amount_adj from `+
-				schema+`.dividend_view 
-                where 
+			schema+`.dividend_view d
+                where `+symbolCond+`
                     ex_date <= date and 
                     payment_type in ('Cash', 'Cash&Stock') and 
                     frequency > 0 
                 order by ex_date desc limit 1), 0) 
                 as div_amount_adj`,
-			`coalesce(
+		`coalesce(
                 (select frequency from `+
-				schema+`.dividend_view 
-                where 
+			schema+`.dividend_view d
+                where `+symbolCond+`
                     ex_date <= date and 
                     payment_type in ('Cash', 'Cash&Stock') and 
                     frequency > 0 
                 order by ex_date desc limit 1), 0) 
                 as div_freq`,
-			`coalesce(
+		`coalesce(
                 (select sum(amount_adj) from `+
-				schema+`.dividend_view 
-                where 
+			schema+`.dividend_view d
+                where `+symbolCond+`
                     ex_date >= (
                         date_trunc('month', CURRENT_DATE) 
                         - INTERVAL '12 months'
@@ -542,69 +652,61 @@ func (db *DB) DividendYields(
                     payment_type in ('Cash', 'Cash&Stock') and 
                     frequency > 0), 0) 
                 as div_trail_ttm`,
-		).
-			From(schema + ".price").
-			OrderBy("date desc").
-			PlaceholderFormat(sq.Dollar)
-
-		if !f.From.IsZero() {
-			q = q.Where("date >= ?", f.From)
-		}
-
-		if f.Limit > 0 {
-			q = q.Limit(f.Limit)
-		}
-
-		sql, args, err := q.ToSql()
-		if err != nil {
-			return err
-		}
+	).
+		From(schema + ".price p").
+		OrderBy("date desc")
 
-		//fmt.Println(sql)
+	if db.shared() {
+		q = q.Where("p.symbol = ?", ticker)
+	}
 
-		rows, err := runner.QueryContext(ctx, sql, args...)
-		if err != nil {
-			return err
-		}
-		defer rows.Close()
+	if !f.From.IsZero() {
+		q = q.Where("date >= ?", f.From)
+	}
 
-		for rows.Next() {
-			var date time.Time
-			var close float64
-			var closeAdjSplits float64
-			var divAdj float64
-			var frequency int
-			var divTrailTTM float64
+	if !f.To.IsZero() {
+		q = q.Where("date <= ?", f.To)
+	}
 
-			err = rows.Scan(
-				&date,
-				&close,
-				&closeAdjSplits,
-				&divAdj,
-				&frequency,
-				&divTrailTTM,
-			)
-			if err != nil {
+	if f.Limit > 0 {
+		q = q.Limit(f.Limit)
+	}
+	return q
+}
 
-				fmt.Println("err: ", date.Format(divyield.DateFormat))
-				return err
-			}
-			v := &divyield.DividendYield{
-				Date:                   date,
-				Close:                  close,
-				CloseAdjSplits:         closeAdjSplits,
-				DividendAdj:            divAdj,
-				Frequency:              frequency,
-				DividendAdjTrailingTTM: divTrailTTM,
-			}
-			yields = append(yields, v)
-		}
-		return nil
-	})
+// scanDividendYield scans the columns of dividendYieldsQuery
+// followed by the optional extra columns.
+func scanDividendYield(
+	rows *sql.Rows,
+	extra ...interface{},
+) (*divyield.DividendYield, error) {
+	var date time.Time
+	var close float64
+	var closeAdjSplits float64
+	var divAdj float64
+	var frequency int
+	var divTrailTTM float64
+
+	dest := []interface{}{
+		&date,
+		&close,
+		&closeAdjSplits,
+		&divAdj,
+		&frequency,
+		&divTrailTTM,
+	}
+	err := rows.Scan(append(dest, extra...)...)
 	if err != nil {
 		return nil, err
 	}
-	return yields, nil
+	return &divyield.DividendYield{
+		Date:                   date,
+		Close:                  close,
+		CloseAdjSplits:         closeAdjSplits,
+		DividendAdj:            divAdj,
+		Frequency:              frequency,
+		DividendAdjTrailingTTM: divTrailTTM,
+	}, nil
 }
 
 func (db *DB) SaveSplits(
@@ -612,13 +714,17 @@ func (db *DB) SaveSplits(
 	in *divyield.DBSaveSplitsInput,
 ) (*divyield.DBSaveSplitsOutput, error) {
 	err := execTx(ctx, db.DB, func(runner runner) error {
-		schema := schemaStock(in.Symbol)
+		schema := db.schema(in.Symbol)
 
 		if in.Reset {
-			sql, args, err := sq.
+			q := sq.
 				Delete("").
 				From(schema + ".split").
-				ToSql()
+				PlaceholderFormat(sq.Dollar)
+			if db.shared() {
+				q = q.Where("symbol = ?", in.Symbol)
+			}
+			sql, args, err := q.ToSql()
 
 			_, err = runner.ExecContext(ctx, sql, args...)
 			if err != nil {
@@ -626,16 +732,20 @@ func (db *DB) SaveSplits(
 			}
 		}
 
+		columns := []string{
+			"ex_date",
+			"to_factor",
+			"from_factor",
+			"created",
+			"source",
+		}
+		if db.shared() {
+			columns = append(columns, "symbol")
+		}
+
 		stmt, err := runner.PrepareContext(
 			ctx,
-			pq.CopyInSchema(
-				schema,
-				"split",
-				"ex_date",
-				"to_factor",
-				"from_factor",
-				"created",
-			),
+			pq.CopyInSchema(schema, "split", columns...),
 		)
 		if err != nil {
 			return err
@@ -649,13 +759,18 @@ func (db *DB) SaveSplits(
 				// noop
 			}
 
-			_, err = stmt.ExecContext(
-				ctx,
+			values := []interface{}{
 				v.ExDate,
 				v.ToFactor,
 				v.FromFactor,
 				time.Now(),
-			)
+				nullString(v.Source),
+			}
+			if db.shared() {
+				values = append(values, in.Symbol)
+			}
+
+			_, err = stmt.ExecContext(ctx, values...)
 			if err != nil {
 				return err
 			}
@@ -671,19 +786,7 @@ func (db *DB) SaveSplits(
 			return err
 		}
 
-		err = updateDividendAdj(ctx, runner, schema)
-		if err != nil {
-			fmt.Println("ERROR", err)
-			return err
-		}
-
-		err = updateCloseAdj(ctx, runner, schema)
-		if err != nil {
-			fmt.Println("ERROR", err)
-			return err
-		}
-
-		return nil
+		return db.adjust(ctx, runner, in.Symbol)
 	})
 	if err != nil {
 		return nil, err
@@ -699,7 +802,7 @@ func (db *DB) Splits(
 	splits := make([]*divyield.Split, 0)
 
 	err := execNonTx(ctx, db.DB, func(runner runner) error {
-		schema := schemaStock(ticker)
+		schema := db.schema(ticker)
 
 		q := sq.Select(
 			"ex_date",
@@ -710,6 +813,10 @@ func (db *DB) Splits(
 			OrderBy("ex_date desc").
 			PlaceholderFormat(sq.Dollar)
 
+		if db.shared() {
+			q = q.Where("symbol = ?", ticker)
+		}
+
 		if f.Limit > 0 {
 			q = q.Limit(f.Limit)
 		}
@@ -956,30 +1063,13 @@ func (db *DB) Profiles(
 	}, nil
 }
 
-func updateDividendAdj(
-	ctx context.Context,
-	runner runner,
-	schema string,
-) error {
-	_, err := runner.ExecContext(
-		ctx,
-		"call public.update_dividend_adj($1);",
-		schema,
-	)
-	return err
-}
-
-func updateCloseAdj(
-	ctx context.Context,
-	runner runner,
-	schema string,
-) error {
-	_, err := runner.ExecContext(
-		ctx,
-		"call public.update_price_adj($1);",
-		schema,
-	)
-	return err
+// symbol returns the symbol stored in the rows of the ticker.
+// The shared layout keys the rows by the ticker itself.
+func (db *DB) symbol(ticker, symbol string) string {
+	if db.shared() {
+		return ticker
+	}
+	return symbol
 }
 
 type runner interface {
@@ -0,0 +1,133 @@
+package postgres
+
+import (
+	"context"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+
+	"szakszon.com/divyield"
+)
+
+func (db *DB) SP500DividendYields(
+	ctx context.Context,
+	in *divyield.DBSP500DividendYieldsInput,
+) (*divyield.DBSP500DividendYieldsOutput, error) {
+	yields := make([]*divyield.SP500DividendYield, 0)
+
+	err := execNonTx(ctx, db.DB, func(runner runner) error {
+		q := sq.Select(
+			"date",
+			"rate",
+		).
+			From("public.sp500_dividend_yield").
+			OrderBy("date desc").
+			PlaceholderFormat(sq.Dollar)
+
+		if !in.Since.IsZero() {
+			q = q.Where("date >= ?", in.Since)
+		}
+
+		if !in.Until.IsZero() {
+			q = q.Where("date <= ?", in.Until)
+		}
+
+		if in.Limit > 0 {
+			q = q.Limit(in.Limit)
+		}
+
+		s, args, err := q.ToSql()
+		if err != nil {
+			return err
+		}
+
+		rows, err := runner.QueryContext(ctx, s, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			v := &divyield.SP500DividendYield{}
+			err = rows.Scan(&v.Date, &v.Rate)
+			if err != nil {
+				return err
+			}
+			v.Timestamp = v.Date.Format(divyield.DateFormat)
+			yields = append(yields, v)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.DBSP500DividendYieldsOutput{
+		DividendYields: yields,
+	}, nil
+}
+
+func (db *DB) SaveSP500DividendYields(
+	ctx context.Context,
+	in *divyield.DBSaveSP500DividendYieldsInput,
+) (*divyield.DBSaveSP500DividendYieldsOutput, error) {
+	if len(in.DividendYields) == 0 {
+		return &divyield.DBSaveSP500DividendYieldsOutput{}, nil
+	}
+
+	// a statement cannot update the same row twice
+	yields := make([]*divyield.SP500DividendYield, 0, len(in.DividendYields))
+	idx := make(map[string]int)
+	for _, v := range in.DividendYields {
+		k := v.Date.Format(divyield.DateFormat)
+		if i, ok := idx[k]; ok {
+			yields[i] = v
+			continue
+		}
+		idx[k] = len(yields)
+		yields = append(yields, v)
+	}
+
+	now := time.Now()
+	err := execTx(ctx, db.DB, func(runner runner) error {
+		for i := 0; i < len(yields); i += fxRatesChunkSize {
+			end := i + fxRatesChunkSize
+			if end > len(yields) {
+				end = len(yields)
+			}
+
+			q := sq.Insert("public.sp500_dividend_yield").
+				Columns(
+					"date",
+					"rate",
+					"created",
+				).
+				Suffix(`on conflict (date)
+                    do update set
+                        rate = excluded.rate,
+                        created = excluded.created`).
+				PlaceholderFormat(sq.Dollar)
+
+			for _, v := range yields[i:end] {
+				q = q.Values(
+					v.Date.Format(divyield.DateFormat),
+					v.Rate,
+					now,
+				)
+			}
+
+			s, args, err := q.ToSql()
+			if err != nil {
+				return err
+			}
+			_, err = runner.ExecContext(ctx, s, args...)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.DBSaveSP500DividendYieldsOutput{}, nil
+}
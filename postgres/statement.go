@@ -0,0 +1,264 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+
+	sq "github.com/Masterminds/squirrel"
+	"github.com/lib/pq"
+
+	"szakszon.com/divyield"
+)
+
+func (db *DB) StatementItems(
+	ctx context.Context,
+	in *divyield.DBStatementItemsInput,
+) (*divyield.DBStatementItemsOutput, error) {
+	items := make([]*divyield.StatementItem, 0)
+
+	err := execNonTx(ctx, db.DB, func(runner runner) error {
+		latest := sq.Select(
+			"exchange",
+			"symbol",
+			"max(pulled) as pulled",
+		).
+			From("public.statement_item").
+			GroupBy("exchange", "symbol")
+
+		if len(in.Symbols) > 0 {
+			or := sq.Or{}
+			for _, v := range in.Symbols {
+				or = append(or, sq.Eq{
+					"exchange": v.Exchange,
+					"symbol":   v.Symbol,
+				})
+			}
+			latest = latest.Where(or)
+		}
+
+		if !in.AsOf.IsZero() {
+			latest = latest.Where("pulled <= ?", in.AsOf)
+		}
+
+		q := sq.Select(
+			"i.exchange",
+			"i.symbol",
+			"i.statement",
+			"i.period",
+			"i.label",
+			"i.value",
+			"coalesce(i.currency, '')",
+			"coalesce(i.fiscal_year_end, '')",
+			"i.pulled",
+		).
+			FromSelect(latest, "l").
+			Join(`public.statement_item i on
+                i.exchange = l.exchange and
+                i.symbol = l.symbol and
+                i.pulled = l.pulled`).
+			OrderBy("i.exchange", "i.symbol", "i.statement", "i.period").
+			PlaceholderFormat(sq.Dollar)
+
+		s, args, err := q.ToSql()
+		if err != nil {
+			return err
+		}
+
+		rows, err := runner.QueryContext(ctx, s, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			v := &divyield.StatementItem{}
+			err = rows.Scan(
+				&v.Exchange,
+				&v.Symbol,
+				&v.Statement,
+				&v.Period,
+				&v.Label,
+				&v.Value,
+				&v.Currency,
+				&v.FiscalYearEnd,
+				&v.Pulled,
+			)
+			if err != nil {
+				return err
+			}
+			items = append(items, v)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.DBStatementItemsOutput{
+		Items: items,
+	}, nil
+}
+
+func (db *DB) SaveStatementItems(
+	ctx context.Context,
+	in *divyield.DBSaveStatementItemsInput,
+) (*divyield.DBSaveStatementItemsOutput, error) {
+	if len(in.Items) == 0 {
+		return &divyield.DBSaveStatementItemsOutput{}, nil
+	}
+
+	err := execTx(ctx, db.DB, func(runner runner) error {
+		// a snapshot saved again replaces the old one
+		deleted := make(map[divyield.StatementItem]bool)
+		for _, v := range in.Items {
+			k := divyield.StatementItem{
+				Exchange: v.Exchange,
+				Symbol:   v.Symbol,
+				Pulled:   v.Pulled,
+			}
+			if deleted[k] {
+				continue
+			}
+			deleted[k] = true
+
+			q := sq.Delete("public.statement_item").
+				Where(sq.Eq{
+					"exchange": v.Exchange,
+					"symbol":   v.Symbol,
+					"pulled":   v.Pulled,
+				}).
+				PlaceholderFormat(sq.Dollar)
+			s, args, err := q.ToSql()
+			if err != nil {
+				return err
+			}
+			_, err = runner.ExecContext(ctx, s, args...)
+			if err != nil {
+				return err
+			}
+		}
+
+		stmt, err := runner.PrepareContext(
+			ctx,
+			pq.CopyInSchema(
+				"public",
+				"statement_item",
+				"exchange",
+				"symbol",
+				"pulled",
+				"statement",
+				"period",
+				"label",
+				"value",
+				"currency",
+				"fiscal_year_end",
+			),
+		)
+		if err != nil {
+			return err
+		}
+
+		for _, v := range in.Items {
+			select {
+			case <-ctx.Done():
+				return fmt.Errorf("interrupted")
+			default:
+				// noop
+			}
+
+			_, err = stmt.ExecContext(
+				ctx,
+				v.Exchange,
+				v.Symbol,
+				v.Pulled,
+				v.Statement,
+				v.Period,
+				v.Label,
+				v.Value,
+				nullString(v.Currency),
+				nullString(v.FiscalYearEnd),
+			)
+			if err != nil {
+				return fmt.Errorf(
+					"%v/%v: %v %v %v: %v",
+					v.Exchange,
+					v.Symbol,
+					v.Statement,
+					v.Period,
+					v.Label,
+					err,
+				)
+			}
+		}
+
+		_, err = stmt.ExecContext(ctx)
+		if err != nil {
+			return err
+		}
+		return stmt.Close()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.DBSaveStatementItemsOutput{}, nil
+}
+
+func (db *DB) StatementPulls(
+	ctx context.Context,
+	in *divyield.DBStatementPullsInput,
+) (*divyield.DBStatementPullsOutput, error) {
+	pulls := make([]*divyield.StatementPull, 0)
+
+	err := execNonTx(ctx, db.DB, func(runner runner) error {
+		q := sq.Select(
+			"exchange",
+			"symbol",
+			"max(pulled)",
+		).
+			From("public.statement_item").
+			GroupBy("exchange", "symbol").
+			OrderBy("exchange", "symbol").
+			PlaceholderFormat(sq.Dollar)
+
+		if len(in.Symbols) > 0 {
+			or := sq.Or{}
+			for _, v := range in.Symbols {
+				or = append(or, sq.Eq{
+					"exchange": v.Exchange,
+					"symbol":   v.Symbol,
+				})
+			}
+			q = q.Where(or)
+		}
+
+		s, args, err := q.ToSql()
+		if err != nil {
+			return err
+		}
+
+		rows, err := runner.QueryContext(ctx, s, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			v := &divyield.StatementPull{}
+			err = rows.Scan(
+				&v.Exchange,
+				&v.Symbol,
+				&v.Pulled,
+			)
+			if err != nil {
+				return err
+			}
+			pulls = append(pulls, v)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.DBStatementPullsOutput{
+		Pulls: pulls,
+	}, nil
+}
@@ -0,0 +1,522 @@
+// Package reconcile combines the dividends and splits of
+// a primary and a secondary provider. If a provider fails,
+// the other one is used. The rows of the providers are
+// matched by ex-date, the disagreements are reported and
+// the rows missing from the primary are filled in from
+// the secondary. The dividends matching the stored ones
+// keep the IDs of the stored dividends.
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"szakszon.com/divyield"
+	"szakszon.com/divyield/logger"
+)
+
+type dividendService struct {
+	primary   divyield.DividendService
+	secondary divyield.DividendService
+	opts      options
+}
+
+// NewDividendService returns a dividend service that
+// reconciles the dividends of the primary and
+// the secondary service.
+func NewDividendService(
+	primary divyield.DividendService,
+	secondary divyield.DividendService,
+	os ...Option,
+) divyield.DividendService {
+	opts := defaultOptions
+	for _, o := range os {
+		opts = o(opts)
+	}
+
+	return &dividendService{
+		primary:   primary,
+		secondary: secondary,
+		opts:      opts,
+	}
+}
+
+func (s *dividendService) Fetch(
+	ctx context.Context,
+	in *divyield.DividendFetchInput,
+) (*divyield.DividendFetchOutput, error) {
+	out, err := s.fetch(ctx, in)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.reuseStoredIDs(ctx, in, out.Dividends)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (s *dividendService) fetch(
+	ctx context.Context,
+	in *divyield.DividendFetchInput,
+) (*divyield.DividendFetchOutput, error) {
+	pout, perr := s.primary.Fetch(ctx, in)
+	sout, serr := s.secondary.Fetch(ctx, in)
+
+	if perr != nil && serr != nil {
+		return nil, perr
+	}
+	if perr != nil {
+		s.opts.logf(
+			"%v: dividends: %v: %v, use %v",
+			in.Symbol,
+			s.opts.primaryName,
+			perr,
+			s.opts.secondaryName,
+		)
+		setDividendSource(sout.Dividends, s.opts.secondaryName)
+		return sout, nil
+	}
+	setDividendSource(pout.Dividends, s.opts.primaryName)
+	if serr != nil {
+		s.opts.logf(
+			"%v: dividends: %v: %v, not reconciled",
+			in.Symbol,
+			s.opts.secondaryName,
+			serr,
+		)
+		return pout, nil
+	}
+	setDividendSource(sout.Dividends, s.opts.secondaryName)
+
+	return &divyield.DividendFetchOutput{
+		Dividends: s.reconcile(in.Symbol, pout.Dividends, sout.Dividends),
+	}, nil
+}
+
+// reuseStoredIDs sets the IDs of the stored dividends to the
+// dividends matching them by ex-date and amount. A dividend
+// filled in from a provider is stored with its ID, so it is
+// not stored again when the other provider reports it later
+// with an ex-date shifted within the tolerance.
+func (s *dividendService) reuseStoredIDs(
+	ctx context.Context,
+	in *divyield.DividendFetchInput,
+	dividends []*divyield.Dividend,
+) error {
+	if s.opts.db == nil || len(dividends) == 0 {
+		return nil
+	}
+
+	stored, err := s.opts.db.Dividends(
+		ctx,
+		in.Symbol,
+		&divyield.DividendFilter{
+			From: in.From.AddDate(0, 0, -s.opts.dateTolerance),
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("get stored dividends: %v", err)
+	}
+
+	for _, v := range matchStored(stored, dividends, s.opts) {
+		s.opts.logf(
+			"%v: dividend %v %v %v %v already stored",
+			in.Symbol,
+			v.Source,
+			v.ExDate.Format(divyield.DateFormat),
+			v.Amount,
+			v.Currency,
+		)
+	}
+	return nil
+}
+
+// matchStored sets the IDs of the stored dividends nearest
+// to the new dividends within the tolerances and returns
+// the matched new dividends.
+func matchStored(
+	stored []*divyield.Dividend,
+	dividends []*divyield.Dividend,
+	o options,
+) []*divyield.Dividend {
+	ids := make(map[int64]bool)
+	for _, v := range dividends {
+		ids[v.ID] = true
+	}
+	matched := make(map[int]bool)
+	storedIDs := make(map[int64]bool)
+	for i, v := range stored {
+		storedIDs[v.ID] = true
+		if ids[v.ID] {
+			matched[i] = true
+		}
+	}
+
+	res := make([]*divyield.Dividend, 0)
+	for _, d := range dividends {
+		if storedIDs[d.ID] {
+			continue
+		}
+		i := nearest(len(stored), func(i int) time.Time {
+			return stored[i].ExDate
+		}, d.ExDate, o.dateTolerance, matched)
+		if i < 0 || !o.amountEqual(stored[i].Amount, d.Amount) {
+			continue
+		}
+		matched[i] = true
+		d.ID = stored[i].ID
+		res = append(res, d)
+	}
+	return res
+}
+
+func (s *dividendService) reconcile(
+	symbol string,
+	primary []*divyield.Dividend,
+	secondary []*divyield.Dividend,
+) []*divyield.Dividend {
+	dividends := make([]*divyield.Dividend, 0, len(primary))
+	dividends = append(dividends, primary...)
+
+	matched := make(map[int]bool)
+	for _, sd := range secondary {
+		i := nearest(len(primary), func(i int) time.Time {
+			return primary[i].ExDate
+		}, sd.ExDate, s.opts.dateTolerance, matched)
+		if i < 0 {
+			s.opts.logf(
+				"%v: dividend %v %v %v missing from %v, use %v",
+				symbol,
+				sd.ExDate.Format(divyield.DateFormat),
+				sd.Amount,
+				sd.Currency,
+				s.opts.primaryName,
+				s.opts.secondaryName,
+			)
+			dividends = append(dividends, sd)
+			continue
+		}
+		matched[i] = true
+
+		pd := primary[i]
+		if !s.opts.amountEqual(pd.Amount, sd.Amount) ||
+			(pd.Currency != "" && sd.Currency != "" &&
+				pd.Currency != sd.Currency) {
+			s.opts.logf(
+				"%v: dividend mismatch: %v: %v %v %v, %v: %v %v %v",
+				symbol,
+				s.opts.primaryName,
+				pd.ExDate.Format(divyield.DateFormat),
+				pd.Amount,
+				pd.Currency,
+				s.opts.secondaryName,
+				sd.ExDate.Format(divyield.DateFormat),
+				sd.Amount,
+				sd.Currency,
+			)
+		}
+	}
+
+	if len(matched) < len(primary) {
+		for i, pd := range primary {
+			if matched[i] || !covers(len(secondary), func(i int) time.Time {
+				return secondary[i].ExDate
+			}, pd.ExDate) {
+				continue
+			}
+			s.opts.logf(
+				"%v: dividend %v %v %v missing from %v",
+				symbol,
+				pd.ExDate.Format(divyield.DateFormat),
+				pd.Amount,
+				pd.Currency,
+				s.opts.secondaryName,
+			)
+		}
+	}
+
+	sort.SliceStable(dividends, func(i, j int) bool {
+		return dividends[i].ExDate.After(dividends[j].ExDate)
+	})
+	return dividends
+}
+
+func setDividendSource(dividends []*divyield.Dividend, source string) {
+	for _, v := range dividends {
+		if v.Source == "" {
+			v.Source = source
+		}
+	}
+}
+
+// covers reports whether the date is not older than
+// the oldest date of a provider, so the missing row is
+// not just out of the history of the provider.
+func covers(
+	n int,
+	date func(i int) time.Time,
+	d time.Time,
+) bool {
+	for i := 0; i < n; i++ {
+		if !date(i).After(d) {
+			return true
+		}
+	}
+	return false
+}
+
+type splitService struct {
+	primary   divyield.SplitService
+	secondary divyield.SplitService
+	opts      options
+}
+
+// NewSplitService returns a split service that
+// reconciles the splits of the primary and
+// the secondary service.
+func NewSplitService(
+	primary divyield.SplitService,
+	secondary divyield.SplitService,
+	os ...Option,
+) divyield.SplitService {
+	opts := defaultOptions
+	for _, o := range os {
+		opts = o(opts)
+	}
+
+	return &splitService{
+		primary:   primary,
+		secondary: secondary,
+		opts:      opts,
+	}
+}
+
+func (s *splitService) Fetch(
+	ctx context.Context,
+	in *divyield.SplitFetchInput,
+) (*divyield.SplitFetchOutput, error) {
+	pout, perr := s.primary.Fetch(ctx, in)
+	sout, serr := s.secondary.Fetch(ctx, in)
+
+	if perr != nil && serr != nil {
+		return nil, perr
+	}
+	if perr != nil {
+		s.opts.logf(
+			"%v: splits: %v: %v, use %v",
+			in.Symbol,
+			s.opts.primaryName,
+			perr,
+			s.opts.secondaryName,
+		)
+		setSplitSource(sout.Splits, s.opts.secondaryName)
+		return sout, nil
+	}
+	setSplitSource(pout.Splits, s.opts.primaryName)
+	if serr != nil {
+		s.opts.logf(
+			"%v: splits: %v: %v, not reconciled",
+			in.Symbol,
+			s.opts.secondaryName,
+			serr,
+		)
+		return pout, nil
+	}
+	setSplitSource(sout.Splits, s.opts.secondaryName)
+
+	return &divyield.SplitFetchOutput{
+		Splits: s.reconcile(in.Symbol, pout.Splits, sout.Splits),
+	}, nil
+}
+
+func (s *splitService) reconcile(
+	symbol string,
+	primary []*divyield.Split,
+	secondary []*divyield.Split,
+) []*divyield.Split {
+	splits := make([]*divyield.Split, 0, len(primary))
+	splits = append(splits, primary...)
+
+	matched := make(map[int]bool)
+	for _, ss := range secondary {
+		i := nearest(len(primary), func(i int) time.Time {
+			return primary[i].ExDate
+		}, ss.ExDate, s.opts.dateTolerance, matched)
+		if i < 0 {
+			s.opts.logf(
+				"%v: split %v missing from %v, use %v",
+				symbol,
+				ss,
+				s.opts.primaryName,
+				s.opts.secondaryName,
+			)
+			splits = append(splits, ss)
+			continue
+		}
+		matched[i] = true
+
+		ps := primary[i]
+		if !s.opts.amountEqual(ratio(ps), ratio(ss)) {
+			s.opts.logf(
+				"%v: split mismatch: %v: %v, %v: %v",
+				symbol,
+				s.opts.primaryName,
+				ps,
+				s.opts.secondaryName,
+				ss,
+			)
+		}
+	}
+
+	for i, ps := range primary {
+		if matched[i] || !covers(len(secondary), func(i int) time.Time {
+			return secondary[i].ExDate
+		}, ps.ExDate) {
+			continue
+		}
+		s.opts.logf(
+			"%v: split %v missing from %v",
+			symbol,
+			ps,
+			s.opts.secondaryName,
+		)
+	}
+
+	sort.SliceStable(splits, func(i, j int) bool {
+		return splits[i].ExDate.After(splits[j].ExDate)
+	})
+	return splits
+}
+
+func setSplitSource(splits []*divyield.Split, source string) {
+	for _, v := range splits {
+		if v.Source == "" {
+			v.Source = source
+		}
+	}
+}
+
+func ratio(s *divyield.Split) float64 {
+	if s.FromFactor == 0 {
+		return 0
+	}
+	return s.ToFactor / s.FromFactor
+}
+
+// nearest returns the index of the unmatched date nearest
+// to the date within the tolerance or -1.
+func nearest(
+	n int,
+	date func(i int) time.Time,
+	d time.Time,
+	tolerance int,
+	matched map[int]bool,
+) int {
+	best := -1
+	bestDiff := time.Duration(tolerance) * 24 * time.Hour
+	for i := 0; i < n; i++ {
+		if matched[i] {
+			continue
+		}
+		diff := date(i).Sub(d)
+		if diff < 0 {
+			diff = -diff
+		}
+		if diff <= bestDiff {
+			best = i
+			bestDiff = diff
+		}
+	}
+	return best
+}
+
+func (o options) amountEqual(a, b float64) bool {
+	if a == b {
+		return true
+	}
+	base := math.Max(math.Abs(a), math.Abs(b))
+	return math.Abs(a-b)/base*100 <= o.amountTolerance
+}
+
+func (o options) logf(
+	format string,
+	v ...interface{},
+) {
+	w := o.logger
+	if w != nil {
+		w.Logf(format, v...)
+	}
+}
+
+var defaultOptions = options{
+	primaryName:     "primary",
+	secondaryName:   "secondary",
+	dateTolerance:   3,
+	amountTolerance: 1,
+	db:              nil,
+	logger:          nil,
+}
+
+type options struct {
+	primaryName     string
+	secondaryName   string
+	dateTolerance   int
+	amountTolerance float64
+	db              divyield.DB
+	logger          logger.Logger
+}
+
+type Option func(o options) options
+
+func PrimaryName(v string) Option {
+	return func(o options) options {
+		o.primaryName = v
+		return o
+	}
+}
+
+func SecondaryName(v string) Option {
+	return func(o options) options {
+		o.secondaryName = v
+		return o
+	}
+}
+
+// DateTolerance is the maximum difference of
+// the ex-dates of the matching rows in days.
+func DateTolerance(v int) Option {
+	return func(o options) options {
+		o.dateTolerance = v
+		return o
+	}
+}
+
+// AmountTolerance is the maximum difference of the
+// amounts or ratios of the matching rows in percent.
+func AmountTolerance(v float64) Option {
+	return func(o options) options {
+		o.amountTolerance = v
+		return o
+	}
+}
+
+// DB sets the database of the stored dividends
+// matched to the reconciled dividends.
+func DB(v divyield.DB) Option {
+	return func(o options) options {
+		o.db = v
+		return o
+	}
+}
+
+func Logger(v logger.Logger) Option {
+	return func(o options) options {
+		o.logger = v
+		return o
+	}
+}
@@ -0,0 +1,465 @@
+package reconcile
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"szakszon.com/divyield"
+)
+
+func date(s string) time.Time {
+	t, err := time.Parse(divyield.DateFormat, s)
+	if err != nil {
+		panic(err)
+	}
+	return t
+}
+
+func dividend(exDate string, amount float64) *divyield.Dividend {
+	return &divyield.Dividend{
+		ExDate:   date(exDate),
+		Amount:   amount,
+		Currency: "USD",
+	}
+}
+
+func split(exDate string, to, from float64) *divyield.Split {
+	return &divyield.Split{
+		ExDate:     date(exDate),
+		ToFactor:   to,
+		FromFactor: from,
+	}
+}
+
+type logs struct {
+	lines []string
+}
+
+func (l *logs) Logf(format string, v ...interface{}) {
+	l.lines = append(l.lines, fmt.Sprintf(format, v...))
+}
+
+func (l *logs) count(s string) int {
+	n := 0
+	for _, v := range l.lines {
+		if strings.Contains(v, s) {
+			n++
+		}
+	}
+	return n
+}
+
+type dividendFetcher struct {
+	dividends []*divyield.Dividend
+	err       error
+}
+
+func (f *dividendFetcher) Fetch(
+	ctx context.Context,
+	in *divyield.DividendFetchInput,
+) (*divyield.DividendFetchOutput, error) {
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &divyield.DividendFetchOutput{
+		Dividends: f.dividends,
+	}, nil
+}
+
+func exDates(dividends []*divyield.Dividend) []string {
+	res := make([]string, 0, len(dividends))
+	for _, v := range dividends {
+		res = append(res, v.ExDate.Format(divyield.DateFormat)+" "+v.Source)
+	}
+	return res
+}
+
+func TestNearest(t *testing.T) {
+	dates := []time.Time{
+		date("2021-03-01"),
+		date("2021-03-05"),
+		date("2021-03-06"),
+	}
+	at := func(i int) time.Time {
+		return dates[i]
+	}
+
+	tests := []struct {
+		name      string
+		date      string
+		tolerance int
+		matched   map[int]bool
+		want      int
+	}{
+		{"same date", "2021-03-01", 3, nil, 0},
+		{"within tolerance", "2021-03-03", 3, nil, 1},
+		{"nearest", "2021-03-07", 3, nil, 2},
+		{"on the tolerance", "2021-03-09", 3, nil, 2},
+		{"out of tolerance", "2021-03-10", 3, nil, -1},
+		{"zero tolerance", "2021-03-02", 0, nil, -1},
+		{"matched skipped", "2021-03-06", 3, map[int]bool{2: true}, 1},
+		{"all matched", "2021-03-01", 3, map[int]bool{0: true, 1: true, 2: true}, -1},
+	}
+
+	for _, tt := range tests {
+		got := nearest(len(dates), at, date(tt.date), tt.tolerance, tt.matched)
+		if got != tt.want {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAmountEqual(t *testing.T) {
+	tests := []struct {
+		a, b      float64
+		tolerance float64
+		want      bool
+	}{
+		{1, 1, 0, true},
+		{0, 0, 1, true},
+		{1, 1.01, 1, true},
+		{1.01, 1, 1, true},
+		{1, 1.02, 1, false},
+		{1, 0, 1, false},
+		{0.5, 0.55, 10, true},
+		{0.5, 0.56, 10, false},
+	}
+
+	for _, tt := range tests {
+		o := defaultOptions
+		o.amountTolerance = tt.tolerance
+		got := o.amountEqual(tt.a, tt.b)
+		if got != tt.want {
+			t.Errorf(
+				"%v, %v within %v%%: got %v, want %v",
+				tt.a,
+				tt.b,
+				tt.tolerance,
+				got,
+				tt.want,
+			)
+		}
+	}
+}
+
+func TestDividends(t *testing.T) {
+	tests := []struct {
+		name      string
+		primary   *dividendFetcher
+		secondary *dividendFetcher
+		want      []string
+		missing   int
+		mismatch  int
+	}{
+		{
+			name: "match",
+			primary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-01", 0.5),
+				dividend("2021-03-01", 0.5),
+			}},
+			secondary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-02", 0.5),
+				dividend("2021-03-01", 0.5),
+			}},
+			want: []string{"2021-06-01 p", "2021-03-01 p"},
+		},
+		{
+			name: "fill from secondary",
+			primary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-01", 0.5),
+			}},
+			secondary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-01", 0.5),
+				dividend("2021-03-01", 0.5),
+			}},
+			want:    []string{"2021-06-01 p", "2021-03-01 s"},
+			missing: 1,
+		},
+		{
+			name: "out of date tolerance",
+			primary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-01", 0.5),
+			}},
+			secondary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-05", 0.5),
+			}},
+			// 2021-06-01 is older than the history of s
+			want:    []string{"2021-06-05 s", "2021-06-01 p"},
+			missing: 1,
+		},
+		{
+			name: "amount mismatch",
+			primary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-01", 0.5),
+			}},
+			secondary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-01", 0.52),
+			}},
+			want:     []string{"2021-06-01 p"},
+			mismatch: 1,
+		},
+		{
+			name: "currency mismatch",
+			primary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-01", 0.5),
+			}},
+			secondary: &dividendFetcher{dividends: []*divyield.Dividend{
+				{
+					ExDate:   date("2021-06-01"),
+					Amount:   0.5,
+					Currency: "EUR",
+				},
+			}},
+			want:     []string{"2021-06-01 p"},
+			mismatch: 1,
+		},
+		{
+			name: "older than secondary history",
+			primary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-01", 0.5),
+				dividend("2001-06-01", 0.1),
+			}},
+			secondary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-01", 0.5),
+			}},
+			want: []string{"2021-06-01 p", "2001-06-01 p"},
+		},
+		{
+			name: "primary fails",
+			primary: &dividendFetcher{
+				err: fmt.Errorf("fail"),
+			},
+			secondary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-01", 0.5),
+			}},
+			want: []string{"2021-06-01 s"},
+		},
+		{
+			name: "secondary fails",
+			primary: &dividendFetcher{dividends: []*divyield.Dividend{
+				dividend("2021-06-01", 0.5),
+			}},
+			secondary: &dividendFetcher{
+				err: fmt.Errorf("fail"),
+			},
+			want: []string{"2021-06-01 p"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &logs{}
+			s := NewDividendService(
+				tt.primary,
+				tt.secondary,
+				PrimaryName("p"),
+				SecondaryName("s"),
+				DateTolerance(3),
+				AmountTolerance(1),
+				Logger(l),
+			)
+
+			out, err := s.Fetch(
+				context.Background(),
+				&divyield.DividendFetchInput{Symbol: "T"},
+			)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			got := exDates(out.Dividends)
+			if strings.Join(got, ", ") != strings.Join(tt.want, ", ") {
+				t.Errorf("got %v, want %v", got, tt.want)
+			}
+			if n := l.count("missing from"); n != tt.missing {
+				t.Errorf("missing: got %v, want %v: %v", n, tt.missing, l.lines)
+			}
+			if n := l.count("mismatch"); n != tt.mismatch {
+				t.Errorf("mismatch: got %v, want %v: %v", n, tt.mismatch, l.lines)
+			}
+		})
+	}
+}
+
+func TestDividendsFail(t *testing.T) {
+	s := NewDividendService(
+		&dividendFetcher{err: fmt.Errorf("primary")},
+		&dividendFetcher{err: fmt.Errorf("secondary")},
+	)
+	_, err := s.Fetch(
+		context.Background(),
+		&divyield.DividendFetchInput{Symbol: "T"},
+	)
+	if err == nil || err.Error() != "primary" {
+		t.Errorf("got %v, want the error of the primary", err)
+	}
+}
+
+func TestSplits(t *testing.T) {
+	tests := []struct {
+		name      string
+		primary   []*divyield.Split
+		secondary []*divyield.Split
+		want      int
+		missing   int
+		mismatch  int
+	}{
+		{
+			name:      "match",
+			primary:   []*divyield.Split{split("2020-08-31", 4, 1)},
+			secondary: []*divyield.Split{split("2020-08-28", 4, 1)},
+			want:      1,
+		},
+		{
+			name:      "ratio mismatch",
+			primary:   []*divyield.Split{split("2020-08-31", 4, 1)},
+			secondary: []*divyield.Split{split("2020-08-31", 2, 1)},
+			want:      1,
+			mismatch:  1,
+		},
+		{
+			name:    "fill from secondary",
+			primary: []*divyield.Split{split("2020-08-31", 4, 1)},
+			secondary: []*divyield.Split{
+				split("2020-08-31", 4, 1),
+				split("2014-06-09", 7, 1),
+			},
+			want:    2,
+			missing: 1,
+		},
+		{
+			name: "missing from secondary",
+			primary: []*divyield.Split{
+				split("2020-08-31", 4, 1),
+				split("2014-06-09", 7, 1),
+			},
+			secondary: []*divyield.Split{
+				split("2020-08-31", 4, 1),
+				split("2005-02-28", 2, 1),
+			},
+			want:    3,
+			missing: 2,
+		},
+		{
+			name: "older than secondary history",
+			primary: []*divyield.Split{
+				split("2020-08-31", 4, 1),
+				split("2005-02-28", 2, 1),
+			},
+			secondary: []*divyield.Split{split("2020-08-31", 4, 1)},
+			want:      2,
+		},
+		{
+			name:    "no secondary splits",
+			primary: []*divyield.Split{split("2020-08-31", 4, 1)},
+			want:    1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			l := &logs{}
+			s := &splitService{
+				opts: defaultOptions,
+			}
+			s.opts.logger = l
+
+			got := s.reconcile("T", tt.primary, tt.secondary)
+			if len(got) != tt.want {
+				t.Errorf("splits: got %v, want %v", len(got), tt.want)
+			}
+			for i := 1; i < len(got); i++ {
+				if got[i].ExDate.After(got[i-1].ExDate) {
+					t.Errorf("not ordered by ex-date descending: %v", got)
+				}
+			}
+			if n := l.count("missing from"); n != tt.missing {
+				t.Errorf("missing: got %v, want %v: %v", n, tt.missing, l.lines)
+			}
+			if n := l.count("mismatch"); n != tt.mismatch {
+				t.Errorf("mismatch: got %v, want %v: %v", n, tt.mismatch, l.lines)
+			}
+		})
+	}
+}
+
+func TestMatchStored(t *testing.T) {
+	withID := func(d *divyield.Dividend, id int64) *divyield.Dividend {
+		d.ID = id
+		return d
+	}
+
+	tests := []struct {
+		name      string
+		stored    []*divyield.Dividend
+		dividends []*divyield.Dividend
+		want      []int64
+	}{
+		{
+			name:      "nothing stored",
+			dividends: []*divyield.Dividend{withID(dividend("2021-06-01", 0.5), 2)},
+			want:      []int64{2},
+		},
+		{
+			name:      "shifted ex-date",
+			stored:    []*divyield.Dividend{withID(dividend("2021-06-01", 0.5), 1)},
+			dividends: []*divyield.Dividend{withID(dividend("2021-06-03", 0.5), 2)},
+			want:      []int64{1},
+		},
+		{
+			name:      "out of date tolerance",
+			stored:    []*divyield.Dividend{withID(dividend("2021-06-01", 0.5), 1)},
+			dividends: []*divyield.Dividend{withID(dividend("2021-06-05", 0.5), 2)},
+			want:      []int64{2},
+		},
+		{
+			name:      "other amount",
+			stored:    []*divyield.Dividend{withID(dividend("2021-06-01", 0.5), 1)},
+			dividends: []*divyield.Dividend{withID(dividend("2021-06-01", 1.5), 2)},
+			want:      []int64{2},
+		},
+		{
+			name:      "same ID",
+			stored:    []*divyield.Dividend{withID(dividend("2021-06-01", 0.5), 1)},
+			dividends: []*divyield.Dividend{withID(dividend("2021-06-01", 0.5), 1)},
+			want:      []int64{1},
+		},
+		{
+			name: "stored matched once",
+			stored: []*divyield.Dividend{
+				withID(dividend("2021-06-01", 0.5), 1),
+			},
+			dividends: []*divyield.Dividend{
+				withID(dividend("2021-06-02", 0.5), 2),
+				withID(dividend("2021-06-03", 0.5), 3),
+			},
+			want: []int64{1, 3},
+		},
+		{
+			name: "stored matched by ID",
+			stored: []*divyield.Dividend{
+				withID(dividend("2021-06-01", 0.5), 1),
+			},
+			dividends: []*divyield.Dividend{
+				withID(dividend("2021-06-01", 0.5), 1),
+				withID(dividend("2021-06-02", 0.5), 2),
+			},
+			want: []int64{1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		matchStored(tt.stored, tt.dividends, defaultOptions)
+
+		got := make([]int64, 0, len(tt.dividends))
+		for _, v := range tt.dividends {
+			got = append(got, v.ID)
+		}
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("%v: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
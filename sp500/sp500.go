@@ -0,0 +1,194 @@
+// Package sp500 stores the monthly S&P 500 dividend yield
+// series of an upstream service in the database.
+package sp500
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"szakszon.com/divyield"
+	"szakszon.com/divyield/logger"
+)
+
+type sp500Service struct {
+	db       divyield.DB
+	upstream divyield.SP500Service
+	opts     options
+}
+
+// NewSP500Service returns a service that provides the current
+// yield of the upstream service and the historical yields
+// stored in the database. The stored series is refreshed
+// from the upstream if it does not contain the current month.
+// If the upstream fails, the latest stored yield is used.
+func NewSP500Service(
+	db divyield.DB,
+	upstream divyield.SP500Service,
+	os ...Option,
+) divyield.SP500Service {
+	opts := defaultOptions
+	for _, o := range os {
+		opts = o(opts)
+	}
+
+	return &sp500Service{
+		db:       db,
+		upstream: upstream,
+		opts:     opts,
+	}
+}
+
+func (s *sp500Service) DividendYield(
+	ctx context.Context,
+	in *divyield.SP500DividendYieldInput,
+) (*divyield.SP500DividendYieldOutput, error) {
+	if in.Date.IsZero() {
+		out, err := s.upstream.DividendYield(ctx, in)
+		if err == nil {
+			return out, nil
+		}
+
+		stored, serr := s.stored(ctx, time.Now().UTC())
+		if serr != nil || stored == nil {
+			return nil, err
+		}
+		s.logf(
+			"S&P 500 dividend yield: %v, use the yield of %v",
+			err,
+			stored.Timestamp,
+		)
+		return &divyield.SP500DividendYieldOutput{
+			SP500DividendYield: *stored,
+		}, nil
+	}
+
+	_, err := s.DividendYields(ctx, &divyield.SP500DividendYieldsInput{
+		Until: in.Date,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	stored, err := s.stored(ctx, in.Date)
+	if err != nil {
+		return nil, err
+	}
+	if stored == nil {
+		return nil, fmt.Errorf(
+			"no S&P 500 dividend yield on %v",
+			in.Date.Format(divyield.DateFormat),
+		)
+	}
+	return &divyield.SP500DividendYieldOutput{
+		SP500DividendYield: *stored,
+	}, nil
+}
+
+// stored returns the latest stored yield not after the date.
+func (s *sp500Service) stored(
+	ctx context.Context,
+	date time.Time,
+) (*divyield.SP500DividendYield, error) {
+	out, err := s.db.SP500DividendYields(
+		ctx,
+		&divyield.DBSP500DividendYieldsInput{
+			Until: date,
+			Limit: 1,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get S&P 500 dividend yields: %v", err)
+	}
+	if len(out.DividendYields) == 0 {
+		return nil, nil
+	}
+	return out.DividendYields[0], nil
+}
+
+func (s *sp500Service) DividendYields(
+	ctx context.Context,
+	in *divyield.SP500DividendYieldsInput,
+) (*divyield.SP500DividendYieldsOutput, error) {
+	latest, err := s.stored(ctx, time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	month := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	if in.Refresh || latest == nil || latest.Date.Before(month) {
+		err = s.refresh(ctx)
+		if err != nil {
+			if in.Refresh || latest == nil {
+				return nil, err
+			}
+			s.logf(
+				"S&P 500 dividend yields: %v, use the stored series",
+				err,
+			)
+		}
+	}
+
+	out, err := s.db.SP500DividendYields(
+		ctx,
+		&divyield.DBSP500DividendYieldsInput{
+			Since: in.Since,
+			Until: in.Until,
+		},
+	)
+	if err != nil {
+		return nil, fmt.Errorf("get S&P 500 dividend yields: %v", err)
+	}
+	return &divyield.SP500DividendYieldsOutput{
+		DividendYields: out.DividendYields,
+	}, nil
+}
+
+func (s *sp500Service) refresh(ctx context.Context) error {
+	out, err := s.upstream.DividendYields(
+		ctx,
+		&divyield.SP500DividendYieldsInput{},
+	)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.SaveSP500DividendYields(
+		ctx,
+		&divyield.DBSaveSP500DividendYieldsInput{
+			DividendYields: out.DividendYields,
+		},
+	)
+	if err != nil {
+		return fmt.Errorf("save S&P 500 dividend yields: %v", err)
+	}
+	return nil
+}
+
+func (s *sp500Service) logf(
+	format string,
+	v ...interface{},
+) {
+	w := s.opts.logger
+	if w != nil {
+		w.Logf(format, v...)
+	}
+}
+
+var defaultOptions = options{
+	logger: nil,
+}
+
+type options struct {
+	logger logger.Logger
+}
+
+type Option func(o options) options
+
+func Logger(v logger.Logger) Option {
+	return func(o options) options {
+		o.logger = v
+		return o
+	}
+}
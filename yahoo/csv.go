@@ -0,0 +1,505 @@
+package yahoo
+
+// The CSV downloads of Yahoo Finance provide the daily prices,
+// the dividends and the splits of a symbol. The prices and the
+// dividends are adjusted for the splits, they are converted back
+// to the raw values because the adjustments are calculated
+// from the stored splits.
+
+import (
+	"context"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"golang.org/x/time/rate"
+
+	"szakszon.com/divyield"
+	"szakszon.com/divyield/httprate"
+	"szakszon.com/divyield/logger"
+)
+
+type CSV struct {
+	opts       csvOptions
+	httpClient *httprate.RLClient
+}
+
+func NewCSV(os ...CSVOption) *CSV {
+	opts := defaultCSVOptions
+	for _, o := range os {
+		opts = o(opts)
+	}
+
+	return &CSV{
+		opts: opts,
+		httpClient: &httprate.RLClient{
+			Client: &http.Client{
+				Timeout: opts.timeout,
+			},
+			Ratelimiter: opts.rateLimiter,
+		},
+	}
+}
+
+func (c *CSV) downloadURL(
+	symbol string,
+	from time.Time,
+	events string,
+) string {
+	return c.opts.baseURL +
+		"/v7/finance/download/" + Symbol(symbol) +
+		"?period1=" + strconv.FormatInt(from.Unix(), 10) +
+		"&period2=" + strconv.FormatInt(time.Now().Unix(), 10) +
+		"&interval=1d" +
+		"&events=" + events +
+		"&includeAdjustedClose=true"
+}
+
+// download returns the records of the CSV file without the header.
+func (c *CSV) download(
+	ctx context.Context,
+	symbol string,
+	from time.Time,
+	events string,
+) ([][]string, error) {
+	u := c.downloadURL(symbol, from, events)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", userAgent)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	c.logf("%v: %v %v", symbol, resp.StatusCode, u)
+
+	if resp.StatusCode < 200 || 299 < resp.StatusCode {
+		return nil, fmt.Errorf("http error: %d", resp.StatusCode)
+	}
+
+	return readCSV(resp.Body)
+}
+
+func readCSV(r io.Reader) ([][]string, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	records, err := cr.ReadAll()
+	if err != nil {
+		return nil, fmt.Errorf("read csv: %v", err)
+	}
+	if len(records) == 0 {
+		return records, nil
+	}
+	return records[1:], nil
+}
+
+func (c *CSV) logf(
+	format string,
+	v ...interface{},
+) {
+	w := c.opts.logger
+	if w != nil {
+		w.Logf(format, v...)
+	}
+}
+
+func (c *CSV) NewPriceService() divyield.PriceService {
+	return &priceService{
+		CSV: c,
+	}
+}
+
+type priceService struct {
+	*CSV
+}
+
+func (s *priceService) Fetch(
+	ctx context.Context,
+	in *divyield.PriceFetchInput,
+) (*divyield.PriceFetchOutput, error) {
+	records, err := s.download(ctx, in.Symbol, in.From, "history")
+	if err != nil {
+		return nil, err
+	}
+	prices, err := parsePrices(records)
+	if err != nil {
+		return nil, fmt.Errorf("parse prices: %v", err)
+	}
+
+	splits, err := s.splits(ctx, in.Symbol, in.From)
+	if err != nil {
+		return nil, err
+	}
+
+	ex := exchangeOf(in.Symbol)
+	for _, p := range prices {
+		f := splitFactor(splits, p.Date) * ex.scale
+		p.Symbol = in.Symbol
+		p.Close *= f
+		p.High *= f
+		p.Low *= f
+		p.Open *= f
+		p.Currency = ex.currency
+	}
+
+	return &divyield.PriceFetchOutput{
+		Prices: prices,
+	}, nil
+}
+
+// parsePrices parses the Date, Open, High, Low, Close,
+// Adj Close, Volume records. The days without trading
+// have null values.
+func parsePrices(records [][]string) ([]*divyield.Price, error) {
+	prices := make([]*divyield.Price, 0, len(records))
+	for _, rec := range records {
+		if len(rec) < 7 {
+			return nil, fmt.Errorf("invalid record: %v", rec)
+		}
+		if rec[4] == "null" {
+			continue
+		}
+
+		date, err := time.Parse(divyield.DateFormat, rec[0])
+		if err != nil {
+			return nil, err
+		}
+
+		values := make([]float64, 0, 5)
+		for _, i := range []int{1, 2, 3, 4, 6} {
+			v, err := strconv.ParseFloat(rec[i], 64)
+			if err != nil {
+				return nil, fmt.Errorf("%v: %v", rec[0], err)
+			}
+			values = append(values, v)
+		}
+
+		prices = append(prices, &divyield.Price{
+			Date:   date,
+			Open:   values[0],
+			High:   values[1],
+			Low:    values[2],
+			Close:  values[3],
+			Volume: values[4],
+		})
+	}
+
+	sort.SliceStable(prices, func(i, j int) bool {
+		return prices[i].Date.After(prices[j].Date)
+	})
+	return prices, nil
+}
+
+func (c *CSV) NewDividendService() divyield.DividendService {
+	return &dividendService{
+		CSV: c,
+	}
+}
+
+type dividendService struct {
+	*CSV
+}
+
+func (s *dividendService) Fetch(
+	ctx context.Context,
+	in *divyield.DividendFetchInput,
+) (*divyield.DividendFetchOutput, error) {
+	// the frequency of the first dividends
+	// is inferred from the previous ones
+	from := in.From
+	if !from.IsZero() {
+		from = from.AddDate(-frequencyLookbackYears, 0, 0)
+	}
+
+	records, err := s.download(ctx, in.Symbol, from, "div")
+	if err != nil {
+		return nil, err
+	}
+	dividends, err := parseDividends(records)
+	if err != nil {
+		return nil, fmt.Errorf("parse dividends: %v", err)
+	}
+	// the amounts of the download, the split
+	// adjustment depends on the pulled splits
+	divyield.SetDividendIDs(in.Symbol, dividends)
+
+	splits, err := s.splits(ctx, in.Symbol, from)
+	if err != nil {
+		return nil, err
+	}
+
+	ex := exchangeOf(in.Symbol)
+	frequencies := divyield.InferFrequencies(dividends)
+	out := &divyield.DividendFetchOutput{
+		Dividends: make([]*divyield.Dividend, 0, len(dividends)),
+	}
+	for i, d := range dividends {
+		if d.ExDate.Before(in.From) {
+			continue
+		}
+		d.Symbol = in.Symbol
+		d.Amount *= splitFactor(splits, d.ExDate) * ex.scale
+		d.Currency = ex.currency
+		d.Frequency = frequencies[i]
+		d.PaymentType = "Cash"
+		out.Dividends = append(out.Dividends, d)
+	}
+
+	return out, nil
+}
+
+// parseDividends parses the Date, Dividends records.
+func parseDividends(records [][]string) ([]*divyield.Dividend, error) {
+	dividends := make([]*divyield.Dividend, 0, len(records))
+	now := time.Now().UTC()
+	for _, rec := range records {
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("invalid record: %v", rec)
+		}
+
+		date, err := time.Parse(divyield.DateFormat, rec[0])
+		if err != nil {
+			return nil, err
+		}
+		amount, err := strconv.ParseFloat(rec[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", rec[0], err)
+		}
+
+		// skip future dividend dates
+		if date.After(now) || amount <= 0 {
+			continue
+		}
+
+		dividends = append(dividends, &divyield.Dividend{
+			ExDate: date,
+			Amount: amount,
+		})
+	}
+
+	sort.SliceStable(dividends, func(i, j int) bool {
+		return dividends[i].ExDate.After(dividends[j].ExDate)
+	})
+	return dividends, nil
+}
+
+const frequencyLookbackYears = 2
+
+func (c *CSV) NewSplitService() divyield.SplitService {
+	return &splitService{
+		CSV: c,
+	}
+}
+
+type splitService struct {
+	*CSV
+}
+
+func (s *splitService) Fetch(
+	ctx context.Context,
+	in *divyield.SplitFetchInput,
+) (*divyield.SplitFetchOutput, error) {
+	splits, err := s.splits(ctx, in.Symbol, in.From)
+	if err != nil {
+		return nil, err
+	}
+	return &divyield.SplitFetchOutput{
+		Splits: splits,
+	}, nil
+}
+
+func (c *CSV) splits(
+	ctx context.Context,
+	symbol string,
+	from time.Time,
+) ([]*divyield.Split, error) {
+	records, err := c.download(ctx, symbol, from, "split")
+	if err != nil {
+		return nil, err
+	}
+	splits, err := parseSplits(records)
+	if err != nil {
+		return nil, fmt.Errorf("parse splits: %v", err)
+	}
+	return splits, nil
+}
+
+// parseSplits parses the Date, Stock Splits records,
+// e.g. 2022-07-18,20:1 where 20 is the to factor.
+func parseSplits(records [][]string) ([]*divyield.Split, error) {
+	splits := make([]*divyield.Split, 0, len(records))
+	for _, rec := range records {
+		if len(rec) < 2 {
+			return nil, fmt.Errorf("invalid record: %v", rec)
+		}
+
+		date, err := time.Parse(divyield.DateFormat, rec[0])
+		if err != nil {
+			return nil, err
+		}
+
+		ratio := strings.FieldsFunc(rec[1], func(r rune) bool {
+			return r == ':' || r == '/'
+		})
+		if len(ratio) != 2 {
+			return nil, fmt.Errorf("%v: invalid ratio: %v", rec[0], rec[1])
+		}
+		to, err := strconv.ParseFloat(ratio[0], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", rec[0], err)
+		}
+		from, err := strconv.ParseFloat(ratio[1], 64)
+		if err != nil {
+			return nil, fmt.Errorf("%v: %v", rec[0], err)
+		}
+		if to <= 0 || from <= 0 {
+			return nil, fmt.Errorf("%v: invalid ratio: %v", rec[0], rec[1])
+		}
+
+		splits = append(splits, &divyield.Split{
+			ExDate:     date,
+			ToFactor:   to,
+			FromFactor: from,
+		})
+	}
+
+	sort.SliceStable(splits, func(i, j int) bool {
+		return splits[i].ExDate.After(splits[j].ExDate)
+	})
+	return splits, nil
+}
+
+// splitFactor returns the factor that converts a split
+// adjusted value of the date back to the raw value.
+func splitFactor(splits []*divyield.Split, date time.Time) float64 {
+	f := 1.0
+	for _, s := range splits {
+		if s.ExDate.After(date) {
+			f *= s.ToFactor / s.FromFactor
+		}
+	}
+	return f
+}
+
+type exchange struct {
+	iexSuffix   string
+	yahooSuffix string
+	currency    string
+
+	// scale converts the quoted prices to the currency,
+	// e.g. the London prices are in pence.
+	scale float64
+}
+
+var exchanges = []exchange{
+	{"", "", "USD", 1},
+	{"-CT", ".TO", "CAD", 1},
+	{"-CV", ".V", "CAD", 1},
+	{"-LN", ".L", "GBP", 0.01},
+	{"-GY", ".DE", "EUR", 1},
+	{"-GF", ".F", "EUR", 1},
+	{"-FP", ".PA", "EUR", 1},
+	{"-NA", ".AS", "EUR", 1},
+	{"-BB", ".BR", "EUR", 1},
+	{"-PL", ".LS", "EUR", 1},
+	{"-ID", ".IR", "EUR", 1},
+	{"-SM", ".MC", "EUR", 1},
+	{"-IM", ".MI", "EUR", 1},
+	{"-AV", ".VI", "EUR", 1},
+	{"-FH", ".HE", "EUR", 1},
+	{"-SE", ".SW", "CHF", 1},
+	{"-DC", ".CO", "DKK", 1},
+	{"-SS", ".ST", "SEK", 1},
+	{"-NO", ".OL", "NOK", 1},
+	{"-HB", ".BD", "HUF", 1},
+	{"-JT", ".T", "JPY", 1},
+	{"-AT", ".AX", "AUD", 1},
+}
+
+func exchangeOf(symbol string) exchange {
+	for _, e := range exchanges[1:] {
+		if strings.HasSuffix(strings.ToUpper(symbol), e.iexSuffix) {
+			return e
+		}
+	}
+	return exchanges[0]
+}
+
+// Symbol converts an IEX Cloud symbol to Yahoo Finance,
+// e.g. BF.B to BF-B and BAYN-GY to BAYN.DE.
+func Symbol(iexSymbol string) string {
+	s := strings.ToUpper(iexSymbol)
+	e := exchangeOf(s)
+	base := strings.TrimSuffix(s, e.iexSuffix)
+	return strings.ReplaceAll(base, ".", "-") + e.yahooSuffix
+}
+
+// IEXSymbol converts a Yahoo Finance symbol to IEX Cloud,
+// e.g. BF-B to BF.B and BAYN.DE to BAYN-GY.
+func IEXSymbol(yahooSymbol string) string {
+	s := strings.ToUpper(yahooSymbol)
+	e := exchanges[0]
+	for _, v := range exchanges[1:] {
+		if strings.HasSuffix(s, v.yahooSuffix) {
+			e = v
+			break
+		}
+	}
+	base := strings.TrimSuffix(s, e.yahooSuffix)
+	return strings.ReplaceAll(base, "-", ".") + e.iexSuffix
+}
+
+const userAgent = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/90.0.4430.93 Safari/537.36 OPR/76.0.4017.123"
+
+var defaultCSVOptions = csvOptions{
+	baseURL:     "https://query1.finance.yahoo.com",
+	timeout:     30 * time.Second,
+	rateLimiter: rate.NewLimiter(rate.Every(500*time.Millisecond), 1),
+	logger:      nil,
+}
+
+type csvOptions struct {
+	baseURL     string
+	timeout     time.Duration
+	rateLimiter *rate.Limiter
+	logger      logger.Logger
+}
+
+type CSVOption func(o csvOptions) csvOptions
+
+func BaseURL(v string) CSVOption {
+	return func(o csvOptions) csvOptions {
+		o.baseURL = v
+		return o
+	}
+}
+
+func CSVTimeout(v time.Duration) CSVOption {
+	return func(o csvOptions) csvOptions {
+		o.timeout = v
+		return o
+	}
+}
+
+func RateLimiter(v *rate.Limiter) CSVOption {
+	return func(o csvOptions) csvOptions {
+		o.rateLimiter = v
+		return o
+	}
+}
+
+func CSVLogger(v logger.Logger) CSVOption {
+	return func(o csvOptions) csvOptions {
+		o.logger = v
+		return o
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	//"github.com/chromedp/cdproto/browser"
@@ -14,10 +15,16 @@ import (
 	"github.com/chromedp/cdproto/network"
 	"github.com/chromedp/chromedp"
 	"szakszon.com/divyield"
+	"szakszon.com/divyield/logger"
 )
 
 type options struct {
-	timeout time.Duration
+	timeout      time.Duration
+	headless     bool
+	tabs         int
+	retries      int
+	retryBackoff time.Duration
+	logger       logger.Logger
 }
 
 type Option func(o options) options
@@ -29,8 +36,53 @@ func Timeout(d time.Duration) Option {
 	}
 }
 
+// Headless runs the browser without a window.
+func Headless(v bool) Option {
+	return func(o options) options {
+		o.headless = v
+		return o
+	}
+}
+
+// Tabs is the number of the browser tabs
+// pulling the statements concurrently.
+func Tabs(v int) Option {
+	return func(o options) options {
+		o.tabs = v
+		return o
+	}
+}
+
+// Retries is the number of the retries of a failed pull.
+func Retries(v int) Option {
+	return func(o options) options {
+		o.retries = v
+		return o
+	}
+}
+
+// RetryBackoff is the wait before the first retry,
+// it doubles after each retry.
+func RetryBackoff(v time.Duration) Option {
+	return func(o options) options {
+		o.retryBackoff = v
+		return o
+	}
+}
+
+func Logger(v logger.Logger) Option {
+	return func(o options) options {
+		o.logger = v
+		return o
+	}
+}
+
 var defaultOptions = options{
-	timeout: 0,
+	timeout:      0,
+	headless:     false,
+	tabs:         1,
+	retries:      0,
+	retryBackoff: 5 * time.Second,
 }
 
 func NewFinancialsService(
@@ -90,7 +142,7 @@ func (s *financialsService) cashFlow(
 ) ([][]string, error) {
 	opts := append(
 		chromedp.DefaultExecAllocatorOptions[:],
-		chromedp.Flag("headless", false),
+		chromedp.Flag("headless", s.opts.headless),
 	)
 	actx, cancel := chromedp.NewExecAllocator(
 		context.Background(),
@@ -179,15 +231,20 @@ func (s *financialsService) PullValuation(
 	jobCh := make(chan string)
 
 	go func() {
+		defer close(resCh)
+
 		opts := append(
 			chromedp.DefaultExecAllocatorOptions[:],
-			chromedp.Flag("headless", false),
+			chromedp.Flag("headless", s.opts.headless),
 		)
+		// the browser and the tabs stop
+		// when the context is cancelled
 		actx, cancel := chromedp.NewExecAllocator(
-			context.Background(),
+			ctx,
 			opts...,
 		)
-		ctx, cancel := chromedp.NewContext(
+		defer cancel()
+		bctx, cancel := chromedp.NewContext(
 			actx,
 			chromedp.WithLogf(log.Printf),
 			//chromedp.WithDebugf(log.Printf),
@@ -195,227 +252,356 @@ func (s *financialsService) PullValuation(
 		)
 		defer cancel()
 
-		responses := make(map[string]*response)
-		rtCh := make(chan *response, 1)
-		valCh := make(chan *response, 1)
-		isCh := make(chan *response, 1)
-		bsCh := make(chan *response, 1)
-		cfCh := make(chan *response, 1)
-
-		chromedp.ListenTarget(ctx, func(v interface{}) {
-			switch ev := v.(type) {
-			case *network.EventRequestWillBeSent:
-				reqID := ev.RequestID.String()
-				resp := &response{
-					URL: ev.Request.URL,
+		// the first run starts the browser,
+		// the tabs are opened in it
+		err := chromedp.Run(bctx)
+		if err != nil {
+			for u := range jobCh {
+				resCh <- &divyield.FinancialsPullValuationOutput{
+					URL: u,
+					Err: fmt.Errorf("start browser: %v", err),
 				}
+			}
+			return
+		}
 
-				if (resp.IsIS() || resp.IsBS() || resp.IsCF() || resp.IsValuation() || resp.IsRealtime()) &&
-					ev.Request.Method == "GET" {
-					//fmt.Println("reqID", reqID, ev.Request.URL)
-					responses[reqID] = resp
-				}
+		tabs := s.opts.tabs
+		if tabs < 1 {
+			tabs = 1
+		}
 
-			case *network.EventLoadingFinished:
-				for reqID, resp := range responses {
-					if reqID == ev.RequestID.String() {
-						//fmt.Println("del reqID", reqID)
-						delete(responses, reqID)
-						go func() {
-							c := chromedp.FromContext(ctx)
-							rbp := network.GetResponseBody(
-								ev.RequestID,
-							)
-							body, err := rbp.Do(
-								cdp.WithExecutor(
-									ctx,
-									c.Target,
-								),
-							)
-							if err != nil {
-								fmt.Println(err)
-							} else {
-								resp.Body = string(body)
-
-								if resp.IsRealtime() {
-									rtCh <- resp
-								} else if resp.IsValuation() {
-									valCh <- resp
-								} else if resp.IsIS() {
-									isCh <- resp
-								} else if resp.IsBS() {
-									bsCh <- resp
-								} else if resp.IsCF() {
-									cfCh <- resp
-								} else {
-									fmt.Println("unexpected response: ", resp)
-								}
-							}
-						}()
-						break
-					}
+		var wg sync.WaitGroup
+		for i := 0; i < tabs; i++ {
+			t := newTab(bctx, s.logf)
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				defer t.close()
+				for u := range jobCh {
+					resCh <- s.pullWithRetries(ctx, t, u)
 				}
-			}
-		})
+			}()
+		}
+		wg.Wait()
+	}()
 
-		for u := range jobCh {
-			var compID string
+	return jobCh, resCh
+}
 
-			res := &divyield.FinancialsPullValuationOutput{
-				URL: u,
-			}
+// pullWithRetries pulls the statements of the URL and
+// retries on failure, the backoff doubles after each try.
+func (s *financialsService) pullWithRetries(
+	ctx context.Context,
+	t *tab,
+	u string,
+) *divyield.FinancialsPullValuationOutput {
+	backoff := s.opts.retryBackoff
+	res := t.pull(u)
+	for i := 1; i <= s.opts.retries && res.Err != nil; i++ {
+		s.logf(
+			"%v: %v, retry %v/%v in %v",
+			u,
+			res.Err,
+			i,
+			s.opts.retries,
+			backoff,
+		)
+		select {
+		case <-ctx.Done():
+			return res
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		res = t.pull(u)
+	}
+	return res
+}
 
-			actions := make([]chromedp.Action, 0)
-			actions = append(
-				actions,
-				chromedp.Navigate(u+"/quote"),
-				chromedp.Evaluate(libJS, &[]byte{}),
-				chromedp.Evaluate(extractCompID, &compID),
-			)
-
-			err := chromedp.Run(ctx, actions...)
-			if err != nil {
-				res.Err = err
-				resCh <- res
-				continue
-			}
+func (s *financialsService) logf(
+	format string,
+	v ...interface{},
+) {
+	w := s.opts.logger
+	if w != nil {
+		w.Logf(format, v...)
+	}
+}
 
-			compID = strings.TrimSpace(compID)
-			if compID == "" {
-				res.Err = fmt.Errorf("CompID not found")
-				resCh <- res
-				continue
-			}
-			//fmt.Println("compID", compID)
+// tab is a browser tab that captures the responses
+// of the statements of the Morningstar pages.
+type tab struct {
+	ctx    context.Context
+	cancel context.CancelFunc
+	logf   func(format string, v ...interface{})
+
+	rtCh  chan *response
+	valCh chan *response
+	isCh  chan *response
+	bsCh  chan *response
+	cfCh  chan *response
+}
+
+func newTab(
+	bctx context.Context,
+	logf func(format string, v ...interface{}),
+) *tab {
+	ctx, cancel := chromedp.NewContext(bctx)
+	t := &tab{
+		ctx:    ctx,
+		cancel: cancel,
+		logf:   logf,
+		rtCh:   make(chan *response, 1),
+		valCh:  make(chan *response, 1),
+		isCh:   make(chan *response, 1),
+		bsCh:   make(chan *response, 1),
+		cfCh:   make(chan *response, 1),
+	}
 
-			rt, err := waitForResponse(rtCh, compID)
-			if err != nil {
-				res.Err = fmt.Errorf("realtime: %v", err)
-				resCh <- res
-				continue
+	var mu sync.Mutex
+	responses := make(map[string]*response)
+
+	chromedp.ListenTarget(ctx, func(v interface{}) {
+		switch ev := v.(type) {
+		case *network.EventRequestWillBeSent:
+			reqID := ev.RequestID.String()
+			resp := &response{
+				URL: ev.Request.URL,
 			}
 
-			actions = make([]chromedp.Action, 0)
-			actions = append(
-				actions,
-				chromedp.Navigate(u+"/valuation"),
-				chromedp.Evaluate(libJS, &[]byte{}),
-			)
-
-			err = chromedp.Run(ctx, actions...)
-			if err != nil {
-				res.Err = err
-				resCh <- res
-				continue
+			if (resp.IsIS() || resp.IsBS() || resp.IsCF() || resp.IsValuation() || resp.IsRealtime()) &&
+				ev.Request.Method == "GET" {
+				//fmt.Println("reqID", reqID, ev.Request.URL)
+				mu.Lock()
+				responses[reqID] = resp
+				mu.Unlock()
 			}
 
-			val, err := waitForResponse(valCh, compID)
-			if err != nil {
-				res.Err = fmt.Errorf("valuation: %v", err)
-				resCh <- res
-				continue
+		case *network.EventLoadingFinished:
+			reqID := ev.RequestID.String()
+			mu.Lock()
+			resp, ok := responses[reqID]
+			delete(responses, reqID)
+			mu.Unlock()
+			if !ok {
+				return
 			}
 
-			actions = make([]chromedp.Action, 0)
-			actions = append(
-				actions,
-				chromedp.Navigate(u+"/financials"),
-				runWithTimeOut(&ctx, 30, chromedp.Tasks{
-					chromedp.WaitVisible(
-						"//span[contains(text(),'Normalized Diluted EPS')]",
-						chromedp.BySearch,
+			go func() {
+				c := chromedp.FromContext(ctx)
+				rbp := network.GetResponseBody(
+					ev.RequestID,
+				)
+				body, err := rbp.Do(
+					cdp.WithExecutor(
+						ctx,
+						c.Target,
 					),
-				}),
-				chromedp.Evaluate(libJS, &[]byte{}),
-				chromedp.Evaluate(clickDetailsViewLink, &[]byte{}),
-			)
-
-			err = chromedp.Run(ctx, actions...)
-			if err != nil {
-				res.Err = err
-				resCh <- res
-				continue
-			}
+				)
+				if err != nil {
+					t.logf("%v: %v", resp.URL, err)
+					return
+				}
+				resp.Body = string(body)
+
+				if resp.IsRealtime() {
+					t.send(t.rtCh, resp)
+				} else if resp.IsValuation() {
+					t.send(t.valCh, resp)
+				} else if resp.IsIS() {
+					t.send(t.isCh, resp)
+				} else if resp.IsBS() {
+					t.send(t.bsCh, resp)
+				} else if resp.IsCF() {
+					t.send(t.cfCh, resp)
+				} else {
+					t.logf("%v: unexpected response", resp.URL)
+				}
+			}()
+		}
+	})
+	return t
+}
 
-			is, err := waitForResponse(isCh, compID)
-			if err != nil {
-				res.Err = fmt.Errorf("is: %v", err)
-				resCh <- res
-				continue
-			}
+// send replaces the response of the channel,
+// the response of a failed pull is dropped.
+func (t *tab) send(ch chan *response, resp *response) {
+	for {
+		select {
+		case ch <- resp:
+			return
+		default:
+		}
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
 
-			actions = make([]chromedp.Action, 0)
-			actions = append(
-				actions,
-				chromedp.Evaluate(libJS, &[]byte{}),
-				chromedp.Evaluate(clickBalSheRadio, &[]byte{}),
-			)
-
-			err = chromedp.Run(ctx, actions...)
-			if err != nil {
-				res.Err = err
-				resCh <- res
-				continue
-			}
+// drain drops the responses of the previous pull.
+func (t *tab) drain() {
+	for _, ch := range []chan *response{
+		t.rtCh,
+		t.valCh,
+		t.isCh,
+		t.bsCh,
+		t.cfCh,
+	} {
+		select {
+		case <-ch:
+		default:
+		}
+	}
+}
 
-			bs, err := waitForResponse(bsCh, compID)
-			if err != nil {
-				res.Err = fmt.Errorf("bs: %v", err)
-				resCh <- res
-				continue
-			}
+func (t *tab) close() {
+	t.cancel()
+}
 
-			actions = make([]chromedp.Action, 0)
-			actions = append(
-				actions,
-				chromedp.Evaluate(libJS, &[]byte{}),
-				chromedp.Evaluate(clickCasFloRadio, &[]byte{}),
-			)
-
-			err = chromedp.Run(ctx, actions...)
-			if err != nil {
-				res.Err = err
-				resCh <- res
-				continue
-			}
+func (t *tab) pull(u string) *divyield.FinancialsPullValuationOutput {
+	t.drain()
+	ctx := t.ctx
 
-			cf, err := waitForResponse(cfCh, compID)
-			if err != nil {
-				res.Err = fmt.Errorf("cf: %v", err)
-				resCh <- res
-				continue
-			}
+	var compID string
 
-			res.Realtime = rt.Body
-			res.Valuation = val.Body
-			res.IncomeStatement = is.Body
-			res.BalanceSheet = bs.Body
-			res.CashFlow = cf.Body
-			resCh <- res
-		}
-		close(resCh)
-	}()
+	res := &divyield.FinancialsPullValuationOutput{
+		URL: u,
+	}
 
-	return jobCh, resCh
+	actions := make([]chromedp.Action, 0)
+	actions = append(
+		actions,
+		chromedp.Navigate(u+"/quote"),
+		chromedp.Evaluate(libJS, &[]byte{}),
+		chromedp.Evaluate(extractCompID, &compID),
+	)
+
+	err := chromedp.Run(ctx, actions...)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	compID = strings.TrimSpace(compID)
+	if compID == "" {
+		res.Err = fmt.Errorf("CompID not found")
+		return res
+	}
+	//fmt.Println("compID", compID)
+
+	rt, err := waitForResponse(t.rtCh, compID)
+	if err != nil {
+		res.Err = fmt.Errorf("realtime: %v", err)
+		return res
+	}
+
+	actions = make([]chromedp.Action, 0)
+	actions = append(
+		actions,
+		chromedp.Navigate(u+"/valuation"),
+		chromedp.Evaluate(libJS, &[]byte{}),
+	)
+
+	err = chromedp.Run(ctx, actions...)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	val, err := waitForResponse(t.valCh, compID)
+	if err != nil {
+		res.Err = fmt.Errorf("valuation: %v", err)
+		return res
+	}
+
+	actions = make([]chromedp.Action, 0)
+	actions = append(
+		actions,
+		chromedp.Navigate(u+"/financials"),
+		runWithTimeOut(&ctx, 30, chromedp.Tasks{
+			chromedp.WaitVisible(
+				"//span[contains(text(),'Normalized Diluted EPS')]",
+				chromedp.BySearch,
+			),
+		}),
+		chromedp.Evaluate(libJS, &[]byte{}),
+		chromedp.Evaluate(clickDetailsViewLink, &[]byte{}),
+	)
+
+	err = chromedp.Run(ctx, actions...)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	is, err := waitForResponse(t.isCh, compID)
+	if err != nil {
+		res.Err = fmt.Errorf("is: %v", err)
+		return res
+	}
+
+	actions = make([]chromedp.Action, 0)
+	actions = append(
+		actions,
+		chromedp.Evaluate(libJS, &[]byte{}),
+		chromedp.Evaluate(clickBalSheRadio, &[]byte{}),
+	)
+
+	err = chromedp.Run(ctx, actions...)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	bs, err := waitForResponse(t.bsCh, compID)
+	if err != nil {
+		res.Err = fmt.Errorf("bs: %v", err)
+		return res
+	}
+
+	actions = make([]chromedp.Action, 0)
+	actions = append(
+		actions,
+		chromedp.Evaluate(libJS, &[]byte{}),
+		chromedp.Evaluate(clickCasFloRadio, &[]byte{}),
+	)
+
+	err = chromedp.Run(ctx, actions...)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+
+	cf, err := waitForResponse(t.cfCh, compID)
+	if err != nil {
+		res.Err = fmt.Errorf("cf: %v", err)
+		return res
+	}
+
+	res.Realtime = rt.Body
+	res.Valuation = val.Body
+	res.IncomeStatement = is.Body
+	res.BalanceSheet = bs.Body
+	res.CashFlow = cf.Body
+	return res
 }
 
+// waitForResponse returns the response of the compID.
+// The late responses of the earlier pulls of the tab
+// are discarded.
 func waitForResponse(ch chan *response, compID string) (*response, error) {
-	var resp *response
+	timeout := time.NewTimer(30 * time.Second)
+	defer timeout.Stop()
+
 	for {
 		select {
-		case resp = <-ch:
-		case <-time.After(30 * time.Second):
+		case resp := <-ch:
+			if resp.CompID() == compID {
+				return resp, nil
+			}
+		case <-timeout.C:
 			return nil, fmt.Errorf("timeout")
 		}
-
-		if resp.CompID() != compID {
-			return nil, fmt.Errorf(
-				"unexpected compID: %v",
-				resp.CompID(),
-			)
-		}
-
-		return resp, nil
 	}
 }
 